@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -13,6 +17,12 @@ import (
 	"github.com/Abraham12611/veritas/internal/handlers"
 	"github.com/Abraham12611/veritas/internal/logger"
 	"github.com/Abraham12611/veritas/internal/middleware"
+	"github.com/Abraham12611/veritas/internal/services"
+)
+
+const (
+	syncCheckInterval      = time.Minute // How often the scheduler looks for due data sources
+	defaultSyncConcurrency = 4           // Sync workers per process
 )
 
 func main() {
@@ -54,6 +64,32 @@ func main() {
 	// Setup routes
 	setupRoutes(app)
 
+	// Stop the server and scheduler on SIGINT/SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		app.Shutdown()
+	}()
+
+	// Start the sync scheduler, the workers running queued syncs and the
+	// worker syncing items queued by webhooks
+	dataSourceService := services.NewDataSourceService()
+	scheduler := services.NewSyncScheduler(dataSourceService, syncCheckInterval)
+	workers := services.NewSyncWorkerPool(dataSourceService, syncConcurrency())
+	itemWorker := services.NewSyncItemWorker(dataSourceService)
+	workersDone := make(chan struct{})
+	itemWorkerDone := make(chan struct{})
+	go scheduler.Run(ctx)
+	go func() {
+		workers.Run(ctx)
+		close(workersDone)
+	}()
+	go func() {
+		itemWorker.Run(ctx)
+		close(itemWorkerDone)
+	}()
+
 	// Start server
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -66,12 +102,27 @@ func main() {
 	if err := app.Listen(":" + port); err != nil {
 		logger.Fatal("Server failed to start", err)
 	}
+
+	// Wait for interrupted syncs to be requeued before closing the database
+	stop()
+	<-workersDone
+	<-itemWorkerDone
+}
+
+// syncConcurrency returns the number of sync workers to run
+func syncConcurrency() int {
+	if n, err := strconv.Atoi(os.Getenv("SYNC_CONCURRENCY")); err == nil && n > 0 {
+		return n
+	}
+	return defaultSyncConcurrency
 }
 
 func setupRoutes(app *fiber.App) {
 	// Initialize handlers
 	instanceHandler := handlers.NewInstanceHandler()
 	dataSourceHandler := handlers.NewDataSourceHandler()
+	syncJobHandler := handlers.NewSyncJobHandler()
+	webhookHandler := handlers.NewWebhookHandler()
 
 	// API routes
 	api := app.Group("/api/v1")
@@ -83,6 +134,13 @@ func setupRoutes(app *fiber.App) {
 		})
 	})
 
+	// Webhook routes (public, verified by signature)
+	webhooks := api.Group("/webhooks")
+	webhooks.Post("/github/:id", webhookHandler.Receive("github"))
+	webhooks.Post("/confluence/:id", webhookHandler.Receive("confluence"))
+	webhooks.Post("/notion/:id", webhookHandler.Receive("notion"))
+	webhooks.Post("/slack/:id", webhookHandler.Receive("slack"))
+
 	// Protected routes
 	protected := api.Use(middleware.AuthMiddleware())
 
@@ -98,10 +156,20 @@ func setupRoutes(app *fiber.App) {
 	dataSources := protected.Group("/data-sources")
 	dataSources.Get("/", dataSourceHandler.ListDataSources)
 	dataSources.Post("/", dataSourceHandler.CreateDataSource)
+	dataSources.Post("/test", dataSourceHandler.TestConnection)
 	dataSources.Get("/:id", dataSourceHandler.GetDataSource)
 	dataSources.Put("/:id", dataSourceHandler.UpdateDataSource)
 	dataSources.Delete("/:id", dataSourceHandler.DeleteDataSource)
 	dataSources.Post("/:id/sync", dataSourceHandler.SyncDataSource)
+	dataSources.Post("/:id/sync/cancel", dataSourceHandler.CancelSync)
+	dataSources.Post("/:id/test", dataSourceHandler.TestDataSourceConnection)
+	dataSources.Post("/:id/preview", dataSourceHandler.PreviewSync)
+	dataSources.Get("/:id/syncs", syncJobHandler.ListSyncJobs)
+
+	// Sync job routes (protected)
+	syncs := protected.Group("/syncs")
+	syncs.Get("/", syncJobHandler.ListActiveSyncJobs)
+	syncs.Get("/:jobId", syncJobHandler.GetSyncJob)
 
 	// Query routes (protected)
 	queries := protected.Group("/queries")
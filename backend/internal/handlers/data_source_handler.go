@@ -1,8 +1,12 @@
 package handlers
 
 import (
+	"errors"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/Abraham12611/veritas/internal/logger"
 	"github.com/Abraham12611/veritas/internal/models"
 	"github.com/Abraham12611/veritas/internal/services"
 )
@@ -82,18 +86,90 @@ func (h *DataSourceHandler) CreateDataSource(c *fiber.Ctx) error {
 	}
 
 	dataSource, err := h.service.CreateDataSource(c.Context(), input)
+	if errors.Is(err, services.ErrInvalidConfig) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to create data source",
 		})
 	}
 
-	// Trigger initial sync in background
-	go h.service.TriggerSync(c.Context(), dataSource.ID)
+	// Queue the initial sync. The data source exists either way, so a
+	// failure here only means the first sync has to be triggered later.
+	if _, err := h.service.TriggerSync(c.Context(), dataSource.ID, models.SyncTriggerInitial); err != nil {
+		logger.Error("Failed to queue initial sync", err, logger.Fields{
+			"dataSourceId": dataSource.ID,
+		})
+	}
 
 	return c.Status(fiber.StatusCreated).JSON(dataSource)
 }
 
+// TestConnection tests the connection of a data source config before it is
+// saved. The result says whether it worked and, if not, why.
+func (h *DataSourceHandler) TestConnection(c *fiber.Ctx) error {
+	var input models.TestConnectionInput
+
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	result, err := h.service.TestConnection(c.Context(), input)
+	if errors.Is(err, services.ErrInvalidConfig) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to test connection",
+		})
+	}
+
+	return c.JSON(result)
+}
+
+// TestDataSourceConnection tests the connection of a saved data source
+func (h *DataSourceHandler) TestDataSourceConnection(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Data source ID is required",
+		})
+	}
+
+	dataSourceID, err := uuid.Parse(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid data source ID format",
+		})
+	}
+
+	result, err := h.service.TestDataSourceConnection(c.Context(), dataSourceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Data source not found",
+		})
+	}
+	if errors.Is(err, services.ErrInvalidConfig) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to test connection",
+		})
+	}
+
+	return c.JSON(result)
+}
+
 // UpdateDataSource updates an existing data source
 func (h *DataSourceHandler) UpdateDataSource(c *fiber.Ctx) error {
 	id := c.Params("id")
@@ -118,6 +194,11 @@ func (h *DataSourceHandler) UpdateDataSource(c *fiber.Ctx) error {
 	}
 
 	dataSource, err := h.service.UpdateDataSource(c.Context(), dataSourceID, input)
+	if errors.Is(err, services.ErrInvalidConfig) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update data source",
@@ -168,11 +249,89 @@ func (h *DataSourceHandler) SyncDataSource(c *fiber.Ctx) error {
 		})
 	}
 
-	// Start sync in background
-	go h.service.TriggerSync(c.Context(), dataSourceID)
+	// Queue the sync for the sync workers
+	job, err := h.service.TriggerSync(c.Context(), dataSourceID, models.SyncTriggerManual)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Data source not found",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to queue sync",
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// PreviewSync runs a data source's connector without ingesting anything and
+// returns what a sync would ingest. The limit query parameter sets how many
+// documents are listed; totals=true runs the whole sync to count the rest.
+func (h *DataSourceHandler) PreviewSync(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Data source ID is required",
+		})
+	}
+
+	dataSourceID, err := uuid.Parse(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid data source ID format",
+		})
+	}
+
+	preview, err := h.service.PreviewSync(c.Context(), dataSourceID, c.QueryInt("limit"), c.QueryBool("totals"))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Data source not found",
+		})
+	}
+	if errors.Is(err, services.ErrInvalidConfig) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		// Most failures come from the source, e.g. bad credentials
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(preview)
+}
+
+// CancelSync cancels the queued or running sync of a data source
+func (h *DataSourceHandler) CancelSync(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Data source ID is required",
+		})
+	}
+
+	dataSourceID, err := uuid.Parse(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid data source ID format",
+		})
+	}
+
+	job, err := h.service.CancelSync(c.Context(), dataSourceID)
+	if errors.Is(err, services.ErrNoActiveSync) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "No sync in progress",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to cancel sync",
+		})
+	}
 
-	return c.JSON(fiber.Map{
-		"message": "Sync started",
-		"id":      dataSourceID,
-	})
-} 
\ No newline at end of file
+	// A running job reports cancelled once its worker has stopped
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
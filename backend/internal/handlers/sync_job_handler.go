@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/Abraham12611/veritas/internal/services"
+)
+
+// SyncJobHandler handles sync history requests
+type SyncJobHandler struct {
+	service *services.SyncJobService
+}
+
+// NewSyncJobHandler creates a new sync job handler
+func NewSyncJobHandler() *SyncJobHandler {
+	return &SyncJobHandler{
+		service: services.NewSyncJobService(),
+	}
+}
+
+// ListSyncJobs returns the sync history of a data source
+func (h *SyncJobHandler) ListSyncJobs(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Data source ID is required",
+		})
+	}
+
+	dataSourceID, err := uuid.Parse(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid data source ID format",
+		})
+	}
+
+	jobs, err := h.service.ListSyncJobs(c.Context(), dataSourceID, c.QueryInt("limit"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve sync jobs",
+		})
+	}
+
+	return c.JSON(jobs)
+}
+
+// ListActiveSyncJobs returns the queued and running sync jobs
+func (h *SyncJobHandler) ListActiveSyncJobs(c *fiber.Ctx) error {
+	jobs, err := h.service.ListActiveSyncJobs(c.Context(), c.QueryInt("limit"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to retrieve sync jobs",
+		})
+	}
+
+	return c.JSON(jobs)
+}
+
+// GetSyncJob returns a specific sync job, including the live counts of a
+// running sync
+func (h *SyncJobHandler) GetSyncJob(c *fiber.Ctx) error {
+	id := c.Params("jobId")
+	if id == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Sync job ID is required",
+		})
+	}
+
+	jobID, err := uuid.Parse(id)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid sync job ID format",
+		})
+	}
+
+	job, err := h.service.GetSyncJob(c.Context(), jobID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Sync job not found",
+		})
+	}
+
+	return c.JSON(job)
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/Abraham12611/veritas/internal/logger"
+	"github.com/Abraham12611/veritas/internal/services"
+	"github.com/Abraham12611/veritas/internal/services/sync"
+)
+
+// WebhookHandler handles webhook deliveries from data sources. Deliveries are
+// authenticated by their signature, not by the API's auth middleware.
+type WebhookHandler struct {
+	service *services.DataSourceService
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler() *WebhookHandler {
+	return &WebhookHandler{
+		service: services.NewDataSourceService(),
+	}
+}
+
+// Receive returns a handler for webhook deliveries to data sources of type
+// dsType. The changes a delivery describes are queued, so the source gets its
+// response quickly.
+func (h *WebhookHandler) Receive(dsType string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		dataSourceID, err := uuid.Parse(c.Params("id"))
+		if err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Data source not found",
+			})
+		}
+
+		header := make(http.Header)
+		c.Request().Header.VisitAll(func(key, value []byte) {
+			header.Add(string(key), string(value))
+		})
+
+		reply, err := h.service.ReceiveWebhook(c.Context(), dsType, dataSourceID, sync.WebhookRequest{
+			Header: header,
+			Body:   c.Body(),
+		})
+		if errors.Is(err, services.ErrInvalidSignature) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid signature",
+			})
+		}
+		if errors.Is(err, pgx.ErrNoRows) || errors.Is(err, services.ErrWebhookNotSupported) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Data source not found",
+			})
+		}
+		if err != nil {
+			logger.Error("Failed to receive webhook", err, logger.Fields{
+				"dataSourceId": dataSourceID,
+				"type":         dsType,
+			})
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to receive webhook",
+			})
+		}
+
+		if reply == nil {
+			return c.SendStatus(fiber.StatusOK)
+		}
+		return c.JSON(reply)
+	}
+}
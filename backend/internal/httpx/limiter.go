@@ -0,0 +1,33 @@
+package httpx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[string]*rate.Limiter)
+)
+
+// SharedLimiter returns the rate limiter for a service and credential,
+// creating it with limit and burst on first use. APIs rate limit per
+// credential, so every sync using the same credential shares one limiter.
+func SharedLimiter(service, credential string, limit rate.Limit, burst int) *rate.Limiter {
+	// Don't keep credentials around as map keys
+	sum := sha256.Sum256([]byte(credential))
+	key := service + ":" + hex.EncodeToString(sum[:])
+
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	limiter, ok := limiters[key]
+	if !ok {
+		limiter = rate.NewLimiter(limit, burst)
+		limiters[key] = limiter
+	}
+	return limiter
+}
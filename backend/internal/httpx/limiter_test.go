@@ -0,0 +1,20 @@
+package httpx
+
+import (
+	"testing"
+
+	"golang.org/x/time/rate"
+)
+
+func TestSharedLimiter(t *testing.T) {
+	a := SharedLimiter("test", "token-a", rate.Inf, 1)
+	if SharedLimiter("test", "token-a", rate.Inf, 1) != a {
+		t.Error("Expected the same limiter for the same credential")
+	}
+	if SharedLimiter("test", "token-b", rate.Inf, 1) == a {
+		t.Error("Expected a different limiter for a different credential")
+	}
+	if SharedLimiter("other", "token-a", rate.Inf, 1) == a {
+		t.Error("Expected a different limiter for a different service")
+	}
+}
@@ -0,0 +1,142 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RetryPolicy configures Retry. Zero fields use the defaults.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+
+	// BaseDelay is the backoff before the first retry; it doubles for each
+	// retry up to MaxDelay. Defaults to 1s and 30s.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// MaxRetryAfter is the longest server-requested wait Retry will honour.
+	// A longer one fails the operation, leaving it to the sync job queue to
+	// retry later. Defaults to 2 minutes.
+	MaxRetryAfter time.Duration
+}
+
+const (
+	defaultBaseDelay     = time.Second
+	defaultMaxDelay      = 30 * time.Second
+	defaultMaxRetryAfter = 2 * time.Minute
+)
+
+// Retry calls operation until it succeeds, returns an error that isn't
+// retryable, or runs out of retries. Each attempt first waits for limiter,
+// which may be nil.
+func Retry(ctx context.Context, limiter *rate.Limiter, policy RetryPolicy, operation func() error) error {
+	var lastErr error
+	for attempt := 0; attempt <= policy.MaxRetries; attempt++ {
+		if limiter != nil {
+			if err := limiter.Wait(ctx); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				// Wait fails straight away if the next token comes after
+				// ctx's deadline
+				return fmt.Errorf("rate limiter error: %v: %w", err, context.DeadlineExceeded)
+			}
+		}
+
+		err := operation()
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil || !IsRetryable(err) {
+			return err
+		}
+		lastErr = err
+
+		if attempt == policy.MaxRetries {
+			break
+		}
+
+		delay, ok := policy.delay(attempt, err)
+		if !ok {
+			return fmt.Errorf("server asked to retry after %s: %w", delay, err)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+	return fmt.Errorf("max retries exceeded: %w", lastErr)
+}
+
+// delay returns how long to wait before retrying after attempt failed with
+// err. It returns false if the server asked for a wait above MaxRetryAfter.
+func (p RetryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		maxRetryAfter := p.MaxRetryAfter
+		if maxRetryAfter == 0 {
+			maxRetryAfter = defaultMaxRetryAfter
+		}
+		if statusErr.RetryAfter > maxRetryAfter {
+			return statusErr.RetryAfter, false
+		}
+		return statusErr.RetryAfter, true
+	}
+
+	baseDelay, maxDelay := p.BaseDelay, p.MaxDelay
+	if baseDelay == 0 {
+		baseDelay = defaultBaseDelay
+	}
+	if maxDelay == 0 {
+		maxDelay = defaultMaxDelay
+	}
+
+	backoff := baseDelay
+	for i := 0; i < attempt && backoff < maxDelay; i++ {
+		backoff *= 2
+	}
+	if backoff > maxDelay {
+		backoff = maxDelay
+	}
+
+	// Wait between half and all of the backoff, so concurrent workers that
+	// failed together don't retry together
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1)), true
+}
+
+// IsRetryable reports whether err is a temporary failure worth retrying: a
+// temporary HTTP status (see StatusError.Temporary) or a network error.
+// Context cancellation is never retryable.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Temporary()
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,140 @@
+package httpx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+func TestRetry(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond}
+
+	t.Run("Retries temporary errors", func(t *testing.T) {
+		attempts := 0
+		err := Retry(context.Background(), nil, policy, func() error {
+			attempts++
+			if attempts < 3 {
+				return &StatusError{StatusCode: http.StatusServiceUnavailable}
+			}
+			return nil
+		})
+		if err != nil || attempts != 3 {
+			t.Errorf("Retry() error = %v after %d attempts", err, attempts)
+		}
+	})
+
+	t.Run("Stops on permanent errors", func(t *testing.T) {
+		attempts := 0
+		err := Retry(context.Background(), nil, policy, func() error {
+			attempts++
+			return &StatusError{StatusCode: http.StatusNotFound, Body: "page 500"}
+		})
+		if err == nil || attempts != 1 {
+			t.Errorf("Retry() error = %v after %d attempts", err, attempts)
+		}
+	})
+
+	t.Run("Gives up after max retries", func(t *testing.T) {
+		attempts := 0
+		err := Retry(context.Background(), nil, policy, func() error {
+			attempts++
+			return &StatusError{StatusCode: http.StatusTooManyRequests}
+		})
+		if attempts != 3 || !strings.Contains(err.Error(), "max retries exceeded") {
+			t.Errorf("Retry() error = %v after %d attempts", err, attempts)
+		}
+	})
+
+	t.Run("Honours Retry-After", func(t *testing.T) {
+		attempts := 0
+		start := time.Now()
+		err := Retry(context.Background(), nil, policy, func() error {
+			attempts++
+			if attempts == 1 {
+				return &StatusError{StatusCode: http.StatusTooManyRequests, RetryAfter: 50 * time.Millisecond}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Retry() error = %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Errorf("Expected to wait for Retry-After, waited %v", elapsed)
+		}
+	})
+
+	t.Run("Fails on long Retry-After", func(t *testing.T) {
+		attempts := 0
+		err := Retry(context.Background(), nil, policy, func() error {
+			attempts++
+			return &StatusError{StatusCode: http.StatusForbidden, RetryAfter: time.Hour}
+		})
+		var statusErr *StatusError
+		if attempts != 1 || !errors.As(err, &statusErr) {
+			t.Errorf("Retry() error = %v after %d attempts", err, attempts)
+		}
+	})
+
+	t.Run("Stops when cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		err := Retry(ctx, rate.NewLimiter(rate.Inf, 1), RetryPolicy{MaxRetries: 2, BaseDelay: time.Hour}, func() error {
+			cancel()
+			return fmt.Errorf("request failed: %w", context.Canceled)
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got: %v", err)
+		}
+	})
+
+	t.Run("Deadline before next token", func(t *testing.T) {
+		limiter := rate.NewLimiter(rate.Every(time.Hour), 1)
+		limiter.Allow()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		err := Retry(ctx, limiter, policy, func() error { return nil })
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, got: %v", err)
+		}
+	})
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second, MaxDelay: 4 * time.Second}
+
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		delay, ok := policy.delay(attempt, errors.New("connection reset"))
+		if !ok || delay < want/2 || delay > want {
+			t.Errorf("delay(%d) = %v, want between %v and %v", attempt, delay, want/2, want)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"Rate limited", &StatusError{StatusCode: http.StatusTooManyRequests}, true},
+		{"Wrapped server error", fmt.Errorf("failed to get pages: %w", &StatusError{StatusCode: http.StatusInternalServerError}), true},
+		{"Not found mentioning 500", errors.New("page 500 not found"), false},
+		{"Unauthorized", &StatusError{StatusCode: http.StatusUnauthorized}, false},
+		{"Cancelled", context.Canceled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
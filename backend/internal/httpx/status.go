@@ -0,0 +1,138 @@
+// Package httpx contains the HTTP plumbing shared by the sync connectors:
+// typed status errors, retries that honour the server's rate limit headers,
+// and rate limiters shared by everything using the same credential.
+package httpx
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxErrorBody is how much of an error response body is kept in a StatusError
+const maxErrorBody = 4 << 10
+
+// StatusError is returned for an HTTP response with a non-2xx status
+type StatusError struct {
+	StatusCode int
+	Body       string
+
+	// RetryAfter is how long the server asked us to wait before retrying,
+	// from the Retry-After or X-RateLimit-Reset headers. Zero if it didn't say.
+	RetryAfter time.Duration
+
+	// Err is the error reported by a client library that parsed the
+	// response, if any
+	Err error
+}
+
+func (e *StatusError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	if e.Body == "" {
+		return fmt.Sprintf("status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("status %d: %s", e.StatusCode, e.Body)
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// Temporary reports whether the request may succeed if retried: the server
+// is rate limiting us, asked us to come back later, or had a transient failure
+func (e *StatusError) Temporary() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	// GitHub signals rate limits with a 403 and rate limit headers
+	return e.RetryAfter > 0
+}
+
+// NewStatusError creates a StatusError for a response whose body has already
+// been read, e.g. by a client library that returned err
+func NewStatusError(resp *http.Response, err error) *StatusError {
+	return &StatusError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: retryAfter(resp, time.Now()),
+		Err:        err,
+	}
+}
+
+// CheckResponse returns a *StatusError if resp doesn't have a 2xx status.
+// The caller still has to close the body.
+func CheckResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	err := NewStatusError(resp, nil)
+	err.Body = strings.TrimSpace(string(body))
+	return err
+}
+
+// DoJSON sends req and decodes a successful JSON response into out. Since a
+// request can only be sent once, retries must build a new request.
+func DoJSON(client *http.Client, req *http.Request, out interface{}) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := CheckResponse(resp); err != nil {
+		return err
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// retryAfter returns how long the server asked us to wait. Retry-After is
+// either a number of seconds or an HTTP date. X-RateLimit-Reset is sent on
+// every response by some APIs, so it is only used once the limit is hit;
+// it is a Unix time (GitHub, Slack) or an RFC 3339 timestamp (Atlassian).
+func retryAfter(resp *http.Response, now time.Time) time.Duration {
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return positive(time.Duration(secs) * time.Second)
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return positive(t.Sub(now))
+		}
+	}
+
+	limited := resp.StatusCode == http.StatusTooManyRequests ||
+		resp.Header.Get("X-RateLimit-Remaining") == "0"
+	if v := resp.Header.Get("X-RateLimit-Reset"); v != "" && limited {
+		if unix, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return positive(time.Unix(unix, 0).Sub(now))
+		}
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return positive(t.Sub(now))
+		}
+	}
+
+	return 0
+}
+
+// positive clamps negative durations, e.g. a reset time in the past, to zero
+func positive(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
@@ -0,0 +1,138 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCheckResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte(`{}`))
+		case "/limited":
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"message": "Rate limit exceeded"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if err := CheckResponse(resp); err != nil {
+		t.Errorf("CheckResponse() error = %v", err)
+	}
+
+	resp, err = http.Get(server.URL + "/limited")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var statusErr *StatusError
+	if !errors.As(CheckResponse(resp), &statusErr) {
+		t.Fatal("Expected StatusError")
+	}
+	if statusErr.StatusCode != http.StatusTooManyRequests || statusErr.RetryAfter != 7*time.Second {
+		t.Errorf("Unexpected status error: %+v", statusErr)
+	}
+	if statusErr.Error() != `status 429: {"message": "Rate limit exceeded"}` {
+		t.Errorf("Unexpected message: %s", statusErr.Error())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+
+	tests := []struct {
+		name    string
+		status  int
+		headers map[string]string
+		want    time.Duration
+	}{
+		{
+			name:    "Seconds",
+			status:  http.StatusTooManyRequests,
+			headers: map[string]string{"Retry-After": "30"},
+			want:    30 * time.Second,
+		},
+		{
+			name:    "HTTP date",
+			status:  http.StatusServiceUnavailable,
+			headers: map[string]string{"Retry-After": now.Add(time.Minute).UTC().Format(http.TimeFormat)},
+			want:    time.Minute,
+		},
+		{
+			name:   "Unix reset when exhausted",
+			status: http.StatusForbidden,
+			headers: map[string]string{
+				"X-RateLimit-Remaining": "0",
+				"X-RateLimit-Reset":     strconv.FormatInt(now.Add(90*time.Second).Unix(), 10),
+			},
+			want: 90 * time.Second,
+		},
+		{
+			name:    "RFC 3339 reset on 429",
+			status:  http.StatusTooManyRequests,
+			headers: map[string]string{"X-RateLimit-Reset": now.Add(time.Hour).Format(time.RFC3339)},
+			want:    time.Hour,
+		},
+		{
+			name:   "Reset ignored while requests remain",
+			status: http.StatusNotFound,
+			headers: map[string]string{
+				"X-RateLimit-Remaining": "4999",
+				"X-RateLimit-Reset":     strconv.FormatInt(now.Add(time.Hour).Unix(), 10),
+			},
+			want: 0,
+		},
+		{
+			name:    "Reset in the past",
+			status:  http.StatusTooManyRequests,
+			headers: map[string]string{"X-RateLimit-Reset": strconv.FormatInt(now.Add(-time.Minute).Unix(), 10)},
+			want:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			for k, v := range tt.headers {
+				resp.Header.Set(k, v)
+			}
+
+			if got := retryAfter(resp, now); got != tt.want {
+				t.Errorf("retryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusError_Temporary(t *testing.T) {
+	tests := []struct {
+		err  StatusError
+		want bool
+	}{
+		{StatusError{StatusCode: http.StatusTooManyRequests}, true},
+		{StatusError{StatusCode: http.StatusBadGateway}, true},
+		{StatusError{StatusCode: http.StatusForbidden, RetryAfter: time.Minute}, true},
+		{StatusError{StatusCode: http.StatusForbidden}, false},
+		{StatusError{StatusCode: http.StatusNotFound, Body: "page 500 not found"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.Temporary(); got != tt.want {
+			t.Errorf("Temporary() for %d %q = %v, want %v", tt.err.StatusCode, tt.err.Body, got, tt.want)
+		}
+	}
+}
@@ -13,8 +13,8 @@ type DataSource struct {
 	Name       string    `json:"name"`
 	Type       string    `json:"type"` // github, confluence, notion, slack, etc.
 	Config     Config    `json:"config"`
-	Status     string    `json:"status"` // active, inactive, syncing, error
-	LastSync   time.Time `json:"last_sync"`
+	Status     string    `json:"status"` // active, inactive (never synced), paused, syncing, error
+	LastSync   *time.Time `json:"last_sync"` // nil until the first successful sync
 	CreatedAt  time.Time `json:"created_at"`
 	UpdatedAt  time.Time `json:"updated_at"`
 }
@@ -31,9 +31,27 @@ type Config struct {
 	Workspace     string `json:"workspace,omitempty"`
 	Organization  string `json:"organization,omitempty"`
 	Repository    string `json:"repository,omitempty"`
+	APIToken      string `json:"api_token,omitempty"` // Confluence, Notion and Slack
+	WebhookSecret string `json:"webhook_secret,omitempty"` // verifies webhook deliveries
+
+	// WebhookVerificationToken is the unconfirmed token a source sent while
+	// setting up a webhook. It isn't used to verify deliveries until an admin
+	// sets it as the webhook secret.
+	WebhookVerificationToken string `json:"webhook_verification_token,omitempty"`
+
+	// Confluence
+	BaseURL  string `json:"base_url,omitempty"`
+	Username string `json:"username,omitempty"`
+	SpaceKey string `json:"space_key,omitempty"`
+
+	// Notion
+	DatabaseID string `json:"database_id,omitempty"`
+
+	// Slack
+	Channels []string `json:"channels,omitempty"` // channel IDs
 	
 	// Source-specific settings
-	SyncFrequency string                 `json:"sync_frequency"` // hourly, daily, weekly
+	SyncFrequency string                 `json:"sync_frequency" validate:"omitempty,oneof=hourly daily weekly"` // empty for manual syncs only
 	Filters       map[string]interface{} `json:"filters"`        // source-specific filters
 	ExtraSettings map[string]interface{} `json:"extra_settings"` // additional configuration
 }
@@ -42,7 +60,7 @@ type Config struct {
 type CreateDataSourceInput struct {
 	InstanceID uuid.UUID `json:"instance_id" validate:"required"`
 	Name       string    `json:"name" validate:"required"`
-	Type       string    `json:"type" validate:"required,oneof=github confluence notion slack zendesk"`
+	Type       string    `json:"type" validate:"required"` // Any type with a registered connector
 	Config     Config    `json:"config" validate:"required"`
 }
 
@@ -50,5 +68,30 @@ type CreateDataSourceInput struct {
 type UpdateDataSourceInput struct {
 	Name   *string `json:"name"`
 	Config *Config `json:"config"`
-	Status *string `json:"status" validate:"omitempty,oneof=active inactive"`
-} 
\ No newline at end of file
+	Status *string `json:"status" validate:"omitempty,oneof=active paused"` // paused stops scheduled syncs
+} 
+
+// Connection test statuses
+const (
+	ConnectionOK            = "ok"
+	ConnectionInvalidConfig = "invalid_config" // required fields are missing
+	ConnectionUnauthorized  = "unauthorized"   // credentials were rejected
+	ConnectionScopeMissing  = "scope_missing"  // credentials lack a permission
+	ConnectionNotFound      = "not_found"      // the repository, space, database or channel doesn't exist or isn't shared
+	ConnectionRateLimited   = "rate_limited"   // try again later
+	ConnectionUnreachable   = "unreachable"    // network error or timeout
+	ConnectionError         = "error"          // any other failure
+)
+
+// TestConnectionInput represents the input for testing an unsaved data source
+type TestConnectionInput struct {
+	Type   string `json:"type" validate:"required"`
+	Config Config `json:"config" validate:"required"`
+}
+
+// ConnectionTestResult is the outcome of testing a data source's connection
+type ConnectionTestResult struct {
+	OK      bool   `json:"ok"`
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+}
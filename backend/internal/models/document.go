@@ -14,10 +14,11 @@ type Document struct {
 	Title        string     `json:"title"`
 	Content      string     `json:"content"`
 	URL          string     `json:"url"`
-	Type         string     `json:"type"`           // markdown, text, html, pdf
+	Type         string     `json:"type"`           // markdown, text, html, pdf, confluence, notion, slack, github_issue, github_pull_request
 	Metadata     Metadata   `json:"metadata"`
 	Chunks       []Chunk    `json:"chunks"`
 	Embedding    []float64  `json:"embedding"`      // document-level embedding
+	ContentHash  string     `json:"content_hash"`   // used to skip unchanged documents on re-sync
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
 	DeletedAt    *time.Time `json:"deleted_at"`
@@ -44,6 +45,20 @@ type Chunk struct {
 	Metadata  Metadata  `json:"metadata"`
 }
 
+// DocumentChunk represents a chunk returned by vector search, together with
+// the document it belongs to
+type DocumentChunk struct {
+	ID         uuid.UUID `json:"id"`          // chunk ID
+	DocumentID uuid.UUID `json:"document_id"`
+	Title      string    `json:"title"`       // document title
+	URL        string    `json:"url"`         // URL of the chunk's part of the document, or the document URL
+	Content    string    `json:"content"`     // chunk text
+	StartChar  int       `json:"start_char"`  // offset of the chunk in the document
+	EndChar    int       `json:"end_char"`
+	Metadata   Metadata  `json:"metadata"`    // document metadata
+	Score      float64   `json:"score"`       // similarity score
+}
+
 // CreateDocumentInput represents the input for creating a new document
 type CreateDocumentInput struct {
 	InstanceID   uuid.UUID `json:"instance_id" validate:"required"`
@@ -51,8 +66,17 @@ type CreateDocumentInput struct {
 	Title        string    `json:"title" validate:"required"`
 	Content      string    `json:"content" validate:"required"`
 	URL          string    `json:"url"`
-	Type         string    `json:"type" validate:"required,oneof=markdown text html pdf"`
+	Type         string    `json:"type" validate:"required,oneof=markdown text html pdf confluence notion slack github_issue github_pull_request"`
 	Metadata     Metadata  `json:"metadata"`
+	Anchors      []Anchor  `json:"anchors,omitempty"` // parts of Content with their own URL
+}
+
+// Anchor marks where a part of a document with its own URL starts, e.g. a
+// message in a Slack thread. Chunks list the URLs of the parts they contain,
+// so citations can link to the part rather than the whole document.
+type Anchor struct {
+	Offset int    `json:"offset"` // byte offset in the content
+	URL    string `json:"url"`
 }
 
 // UpdateDocumentInput represents the input for updating a document
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sync job triggers
+const (
+	SyncTriggerInitial   = "initial"   // first sync after the data source was created
+	SyncTriggerManual    = "manual"    // requested through the API
+	SyncTriggerScheduled = "scheduled" // started by the sync scheduler
+	SyncTriggerWebhook   = "webhook"   // requested by a change pushed from the source
+)
+
+// Sync job statuses
+const (
+	SyncJobQueued    = "queued"
+	SyncJobRunning   = "running"
+	SyncJobSucceeded = "succeeded"
+	SyncJobFailed    = "failed"
+	SyncJobCancelled = "cancelled"
+)
+
+// SyncJob records a single sync of a data source. Jobs are queued and then
+// picked up by a sync worker; failed attempts are retried until MaxAttempts.
+type SyncJob struct {
+	ID           uuid.UUID  `json:"id"`
+	DataSourceID uuid.UUID  `json:"data_source_id"`
+	Trigger      string     `json:"trigger"` // initial, manual, scheduled, webhook
+	Status       string     `json:"status"`  // queued, running, succeeded, failed, cancelled
+	SyncJobCounts
+	Error        string     `json:"error,omitempty"` // error of the last failed attempt
+	Attempts     int        `json:"attempts"`
+	MaxAttempts  int        `json:"max_attempts"`
+	RunAfter     time.Time  `json:"run_after"` // earliest time a worker may pick the job up
+	CreatedAt    time.Time  `json:"created_at"`
+	StartedAt    *time.Time `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	CancelRequestedAt *time.Time `json:"cancel_requested_at"` // set while a running job is being cancelled
+}
+
+// SyncJobCounts records what a sync did with the documents it processed
+type SyncJobCounts struct {
+	Added     int `json:"documents_added"`
+	Updated   int `json:"documents_updated"`
+	Unchanged int `json:"documents_unchanged"`
+	Deleted   int `json:"documents_deleted"`
+	Failed    int `json:"documents_failed"`
+}
+
+// SyncItem is a single item of a data source queued for re-ingestion or
+// deletion, e.g. because a webhook reported it changed
+type SyncItem struct {
+	DataSourceID uuid.UUID `json:"data_source_id"`
+	ExternalID   string    `json:"external_id"`
+	Deleted      bool      `json:"deleted"` // the source reported the item deleted
+	Attempts     int       `json:"attempts"`
+}
+
+// SyncPreview is the result of a dry run of a data source's connector: what
+// a sync would ingest, without ingesting it
+type SyncPreview struct {
+	Documents    []PreviewDocument `json:"documents"` // the first documents produced, up to the requested limit
+	Total        int               `json:"total"`
+	TotalsByType map[string]int    `json:"totals_by_type"`
+	TotalSize    int               `json:"total_size"` // content bytes
+	TotalChunks  int               `json:"total_chunks"`
+	Complete     bool              `json:"complete"` // false if the preview timed out or stopped at the limit before the connector finished
+}
+
+// PreviewDocument summarises a document produced during a sync preview
+type PreviewDocument struct {
+	Title      string `json:"title"`
+	URL        string `json:"url"`
+	Type       string `json:"type"`
+	ExternalID string `json:"external_id"`
+	Size       int    `json:"size"` // content bytes
+	Chunks     int    `json:"chunks"`
+}
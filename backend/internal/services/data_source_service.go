@@ -4,23 +4,47 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
+	"github.com/cenkalti/backoff/v4"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/Abraham12611/veritas/config"
+	"github.com/Abraham12611/veritas/internal/logger"
 	"github.com/Abraham12611/veritas/internal/models"
+	"github.com/Abraham12611/veritas/internal/services/rag"
 	"github.com/Abraham12611/veritas/internal/services/sync"
 )
 
+// ErrInvalidConfig is returned when a data source config is missing fields
+// required by its type, or the type has no connector
+var ErrInvalidConfig = sync.ErrInvalidConfig
+
+// syncProgressInterval is how often a running sync records its counts and
+// heartbeats its job. Must be well below syncJobVisibilityTimeout.
+const syncProgressInterval = 5 * time.Second
+
+// syncPreviewTimeout bounds a sync preview, which runs during the request.
+// Previews of larger sources are cut short and marked incomplete.
+const syncPreviewTimeout = time.Minute
+
 // DataSourceService handles business logic for data sources
 type DataSourceService struct {
 	ingestionService *IngestionService
+	syncJobService   *SyncJobService
+	syncItemService  *SyncItemService
 }
 
 // NewDataSourceService creates a new data source service
 func NewDataSourceService() *DataSourceService {
+	llm := rag.NewOpenAIClient(os.Getenv("OPENAI_API_KEY"), os.Getenv("OPENAI_MODEL"))
+
 	return &DataSourceService{
-		ingestionService: NewIngestionService(),
+		ingestionService: NewIngestionService(llm),
+		syncJobService:   NewSyncJobService(),
+		syncItemService:  NewSyncItemService(),
 	}
 }
 
@@ -91,6 +115,10 @@ func (s *DataSourceService) GetDataSource(ctx context.Context, id uuid.UUID) (*m
 
 // CreateDataSource creates a new data source
 func (s *DataSourceService) CreateDataSource(ctx context.Context, input models.CreateDataSourceInput) (*models.DataSource, error) {
+	if err := validateConfig(input.Type, input.Config); err != nil {
+		return nil, err
+	}
+
 	query := `
 		INSERT INTO data_sources (id, instance_id, name, type, config, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $6)
@@ -128,11 +156,25 @@ func (s *DataSourceService) CreateDataSource(ctx context.Context, input models.C
 
 // UpdateDataSource updates an existing data source
 func (s *DataSourceService) UpdateDataSource(ctx context.Context, id uuid.UUID, input models.UpdateDataSourceInput) (*models.DataSource, error) {
+	// A new config replaces the old one, so it must be complete. It also
+	// invalidates the sync checkpoint, which is cleared so the next sync
+	// starts from scratch.
+	if input.Config != nil {
+		existing, err := s.GetDataSource(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := validateConfig(existing.Type, *input.Config); err != nil {
+			return nil, err
+		}
+	}
+
 	query := `
 		UPDATE data_sources
 		SET name = COALESCE($1, name),
 			config = COALESCE($2, config),
 			status = COALESCE($3, status),
+			sync_checkpoint = CASE WHEN $2 IS NULL THEN sync_checkpoint END,
 			updated_at = $4
 		WHERE id = $5 AND deleted_at IS NULL
 		RETURNING id, instance_id, name, type, config, status, last_sync, created_at, updated_at
@@ -183,11 +225,13 @@ func (s *DataSourceService) DeleteDataSource(ctx context.Context, id uuid.UUID)
 	return nil
 }
 
-// UpdateSyncStatus updates the sync status and last_sync timestamp
+// UpdateSyncStatus updates the sync status and last_sync timestamp. A paused
+// data source stays paused through manual syncs.
 func (s *DataSourceService) UpdateSyncStatus(ctx context.Context, id uuid.UUID, status string) error {
 	query := `
 		UPDATE data_sources
-		SET status = $1, last_sync = CASE WHEN $1 = 'active' THEN CURRENT_TIMESTAMP ELSE last_sync END
+		SET status = CASE WHEN status = 'paused' THEN status ELSE $1 END,
+			last_sync = CASE WHEN $1 = 'active' THEN CURRENT_TIMESTAMP ELSE last_sync END
 		WHERE id = $2 AND deleted_at IS NULL
 	`
 
@@ -203,87 +247,389 @@ func (s *DataSourceService) UpdateSyncStatus(ctx context.Context, id uuid.UUID,
 	return nil
 }
 
-// TriggerSync initiates a sync operation for a data source
-func (s *DataSourceService) TriggerSync(ctx context.Context, id uuid.UUID) error {
-	// Get data source details
-	ds, err := s.GetDataSource(ctx, id)
+// TriggerSync queues a sync of a data source for the sync workers and returns
+// the job. trigger says what requested the sync (see models.SyncTrigger*).
+// If a sync is already queued or running, that job is returned.
+func (s *DataSourceService) TriggerSync(ctx context.Context, id uuid.UUID, trigger string) (*models.SyncJob, error) {
+	// Make sure the data source exists
+	if _, err := s.GetDataSource(ctx, id); err != nil {
+		return nil, err
+	}
+
+	job, err := s.syncJobService.EnqueueSyncJob(ctx, id, trigger)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to enqueue sync job: %w", err)
 	}
 
-	// Update status to syncing
-	if err := s.UpdateSyncStatus(ctx, id, "syncing"); err != nil {
+	return job, nil
+}
+
+// RunSyncJob performs a sync job claimed by a sync worker and records its
+// outcome. If ctx is cancelled mid-sync the job is put back in the queue.
+func (s *DataSourceService) RunSyncJob(ctx context.Context, job *models.SyncJob) error {
+	// Updates after the sync must happen even if ctx was cancelled, or the
+	// job would stay running until its visibility timeout expires
+	statusCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	fields := logger.Fields{
+		"dataSourceId": job.DataSourceID,
+		"jobId":        job.ID,
+		"attempt":      job.Attempts,
+	}
+
+	// A job can be reclaimed after its worker died with a cancellation pending
+	if job.CancelRequestedAt != nil {
+		err := s.syncJobService.FinishCancelledSyncJob(statusCtx, job, job.SyncJobCounts)
+		if errors.Is(err, ErrSyncJobLost) {
+			logger.Info("Sync job was claimed by another worker", fields)
+			return err
+		}
+		if err != nil {
+			logger.Error("Failed to finish sync job", err, fields)
+		}
+		return s.resetSyncStatus(statusCtx, job.DataSourceID)
+	}
+
+	// Get data source details. A job whose data source was deleted, or that
+	// was abandoned by crashed workers too often, can't succeed by retrying.
+	ds, err := s.GetDataSource(ctx, job.DataSourceID)
+	switch {
+	case job.Attempts > job.MaxAttempts:
+		err = backoff.Permanent(fmt.Errorf("sync abandoned after %d attempts", job.MaxAttempts))
+	case errors.Is(err, pgx.ErrNoRows):
+		err = backoff.Permanent(errors.New("data source not found"))
+	case err == nil:
+		err = s.UpdateSyncStatus(ctx, ds.ID, "syncing")
+	}
+
+	// Run the sync, reporting progress while the connector works. The
+	// progress reporter cancels syncCtx when a cancellation is requested.
+	run := newSyncRun(s.ingestionService, job.DataSourceID)
+	syncErr := err
+	if syncErr == nil {
+		syncCtx, cancelSync := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go s.reportProgress(syncCtx, job, run, done, cancelSync)
+		syncErr = s.runSync(syncCtx, ds, run)
+		close(done)
+		cancelSync()
+	}
+
+	// Cancelled on request. Documents ingested so far are kept; each one was
+	// written in its own transaction and nothing was deleted.
+	if syncErr != nil && run.wasCancelled() {
+		logger.Info("Sync cancelled", fields)
+		err := s.syncJobService.FinishCancelledSyncJob(statusCtx, job, run.counts())
+		if errors.Is(err, ErrSyncJobLost) {
+			logger.Info("Sync job was claimed by another worker", fields)
+			return err
+		}
+		if err != nil {
+			logger.Error("Failed to finish sync job", err, fields)
+		}
+		return s.resetSyncStatus(statusCtx, job.DataSourceID)
+	}
+
+	// Interrupted by shutdown: let another worker pick the job up again
+	if syncErr != nil && ctx.Err() != nil {
+		logger.Info("Sync interrupted, requeueing job", fields)
+		if err := s.syncJobService.RequeueSyncJob(statusCtx, job); err != nil {
+			logger.Error("Failed to requeue sync job", err, fields)
+		}
+		return syncErr
+	}
+
+	// A worker whose attempt was superseded leaves the job and the data
+	// source to the worker that claimed it again
+	status, err := s.syncJobService.FinishSyncJob(statusCtx, job, run.counts(), syncErr)
+	if errors.Is(err, ErrSyncJobLost) {
+		logger.Info("Sync job was claimed by another worker", fields)
 		return err
 	}
+	if err != nil {
+		logger.Error("Failed to finish sync job", err, fields)
+	}
 
-	// Perform sync based on data source type
-	var syncErr error
-	switch ds.Type {
-	case "github":
-		syncErr = s.syncGitHub(ctx, ds)
-	case "confluence":
-		syncErr = s.syncConfluence(ctx, ds)
-	case "notion":
-		syncErr = s.syncNotion(ctx, ds)
-	case "slack":
-		syncErr = s.syncSlack(ctx, ds)
+	// Update final status based on sync result. A job queued for retry keeps
+	// the data source in the syncing state.
+	switch {
+	case syncErr == nil:
+		return s.UpdateSyncStatus(statusCtx, job.DataSourceID, "active")
+	case status == models.SyncJobQueued:
+		logger.Error("Sync failed, retry queued", syncErr, fields)
 	default:
-		syncErr = fmt.Errorf("unsupported data source type: %s", ds.Type)
+		s.UpdateSyncStatus(statusCtx, job.DataSourceID, "error")
+	}
+
+	return fmt.Errorf("sync failed: %w", syncErr)
+}
+
+// runSync runs the connector for the data source type, then removes the
+// documents that no longer exist in the source and stores the connector's
+// checkpoint for the next sync
+func (s *DataSourceService) runSync(ctx context.Context, ds *models.DataSource, run *syncRun) error {
+	connector, ok := sync.Lookup(ds.Type)
+	if !ok {
+		return backoff.Permanent(fmt.Errorf("unsupported data source type: %s", ds.Type))
 	}
 
-	// Update final status based on sync result
-	if syncErr != nil {
-		s.UpdateSyncStatus(ctx, id, "error")
-		return fmt.Errorf("sync failed: %w", syncErr)
+	checkpoint, err := s.getCheckpoint(ctx, ds.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get sync checkpoint: %w", err)
 	}
 
-	return s.UpdateSyncStatus(ctx, id, "active")
+	result, err := connector.Sync(ctx, ds, run, checkpoint)
+	if err != nil {
+		return err
+	}
+
+	// Only a complete sync tells us what is missing
+	if result.Complete {
+		deleted, err := run.deleteMissing(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to delete missing documents: %w", err)
+		}
+		if deleted > 0 {
+			logger.Info("Deleted documents missing from source", logger.Fields{
+				"dataSourceId": ds.ID,
+				"deleted":      deleted,
+			})
+		}
+	}
+
+	if err := s.saveCheckpoint(ctx, ds.ID, result.Checkpoint); err != nil {
+		return fmt.Errorf("failed to save sync checkpoint: %w", err)
+	}
+
+	return nil
 }
 
-// syncGitHub syncs content from a GitHub repository
-func (s *DataSourceService) syncGitHub(ctx context.Context, ds *models.DataSource) error {
-	// Get GitHub access token from config
-	accessToken := ds.Config.AccessToken
-	if accessToken == "" {
-		return errors.New("GitHub access token not found in config")
+// getCheckpoint returns the checkpoint stored by the last successful sync of
+// a data source, or nil
+func (s *DataSourceService) getCheckpoint(ctx context.Context, id uuid.UUID) (sync.Checkpoint, error) {
+	var checkpoint sync.Checkpoint
+	err := config.DB.QueryRow(ctx, `SELECT sync_checkpoint FROM data_sources WHERE id = $1`, id).Scan(&checkpoint)
+	if err != nil {
+		return nil, err
 	}
+	return checkpoint, nil
+}
 
-	// Create GitHub service
-	githubService := sync.NewGitHubService(accessToken)
+// saveCheckpoint stores the checkpoint for the next sync of a data source
+func (s *DataSourceService) saveCheckpoint(ctx context.Context, id uuid.UUID, checkpoint sync.Checkpoint) error {
+	_, err := config.DB.Exec(ctx, `UPDATE data_sources SET sync_checkpoint = $2 WHERE id = $1`, id, checkpoint)
+	return err
+}
 
-	// Sync repository
-	return githubService.SyncRepository(ctx, ds)
+// reportProgress periodically records the counts of a running sync until
+// done is closed. If cancellation of the job has been requested it marks the
+// run cancelled and calls cancel. It also calls cancel if another worker has
+// claimed the job.
+func (s *DataSourceService) reportProgress(ctx context.Context, job *models.SyncJob, run *syncRun, done <-chan struct{}, cancel context.CancelFunc) {
+	ticker := time.NewTicker(syncProgressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			cancelRequested, err := s.syncJobService.UpdateSyncJobProgress(ctx, job, run.counts())
+			if errors.Is(err, ErrSyncJobLost) {
+				// Another worker is running the job, so stop this attempt
+				cancel()
+				return
+			}
+			if err != nil {
+				logger.Error("Failed to update sync progress", err, logger.Fields{
+					"jobId": job.ID,
+				})
+				continue
+			}
+			if cancelRequested {
+				run.markCancelled()
+				cancel()
+				return
+			}
+		}
+	}
 }
 
-// syncConfluence syncs content from a Confluence space
-func (s *DataSourceService) syncConfluence(ctx context.Context, ds *models.DataSource) error {
-	// Get Confluence credentials from config
-	baseURL := ds.Config.BaseURL
-	if baseURL == "" {
-		return errors.New("Confluence base URL not found in config")
+// CancelSync cancels the queued or running sync of a data source and returns
+// the job. A running sync stops within syncProgressInterval. It returns
+// ErrNoActiveSync if nothing is syncing.
+func (s *DataSourceService) CancelSync(ctx context.Context, id uuid.UUID) (*models.SyncJob, error) {
+	job, err := s.syncJobService.CancelSyncJob(ctx, id)
+	if err != nil {
+		return nil, err
 	}
 
-	username := ds.Config.Username
-	if username == "" {
-		return errors.New("Confluence username not found in config")
+	// A queued job may be a retry, which leaves the data source syncing
+	if job.Status == models.SyncJobCancelled {
+		if err := s.resetSyncStatus(ctx, id); err != nil {
+			return nil, err
+		}
 	}
 
-	apiToken := ds.Config.APIToken
-	if apiToken == "" {
-		return errors.New("Confluence API token not found in config")
+	return job, nil
+}
+
+// resetSyncStatus moves a data source out of the syncing state without
+// recording a sync: back to active if it has synced before, otherwise to
+// inactive as when it was created. A paused data source stays paused.
+func (s *DataSourceService) resetSyncStatus(ctx context.Context, id uuid.UUID) error {
+	query := `
+		UPDATE data_sources
+		SET status = CASE WHEN last_sync IS NULL THEN 'inactive' ELSE 'active' END
+		WHERE id = $1 AND deleted_at IS NULL AND status <> 'paused'
+	`
+
+	_, err := config.DB.Exec(ctx, query, id)
+	return err
+}
+
+// TestConnection checks that an unsaved data source config can reach its
+// source. It returns ErrInvalidConfig only for unsupported types; a config
+// missing fields is reported in the result.
+func (s *DataSourceService) TestConnection(ctx context.Context, input models.TestConnectionInput) (*models.ConnectionTestResult, error) {
+	connector, err := lookupConnector(input.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	ds := &models.DataSource{Type: input.Type, Config: input.Config}
+	result := sync.CheckConnection(ctx, connector, ds)
+	return &result, nil
+}
+
+// TestDataSourceConnection checks that a saved data source can reach its source
+func (s *DataSourceService) TestDataSourceConnection(ctx context.Context, id uuid.UUID) (*models.ConnectionTestResult, error) {
+	ds, err := s.GetDataSource(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	connector, err := lookupConnector(ds.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	result := sync.CheckConnection(ctx, connector, ds)
+	return &result, nil
+}
+
+// PreviewSync runs the connector of a data source without ingesting anything
+// and returns the first limit documents it would ingest. The connector is
+// stopped once they're listed, unless totals is set, in which case it runs to
+// the end to count every document. The preview ignores the sync checkpoint,
+// so it covers the whole source.
+func (s *DataSourceService) PreviewSync(ctx context.Context, id uuid.UUID, limit int, totals bool) (*models.SyncPreview, error) {
+	ds, err := s.GetDataSource(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	connector, err := lookupConnector(ds.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	previewCtx, cancel := context.WithTimeout(ctx, syncPreviewTimeout)
+	defer cancel()
+
+	stop := cancel
+	if totals {
+		stop = nil
+	}
+	sink := newPreviewSink(s.ingestionService, limit, stop)
+	_, err = connector.Sync(previewCtx, ds, sink, nil)
+
+	// Running out of time, or stopping once enough documents are listed,
+	// still leaves a useful partial preview
+	timedOut := previewCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil
+	if err != nil && !timedOut && !sink.hasStopped() {
+		return nil, fmt.Errorf("failed to preview sync: %w", err)
+	}
+
+	preview := sink.result()
+	preview.Complete = err == nil && !sink.hasStopped()
+	return preview, nil
+}
+
+// ListScheduledDataSources returns the data sources across all instances
+// that have a sync frequency and aren't paused, least recently synced first.
+// Inactive sources are included, since that's the status a source has until
+// its first sync completes.
+func (s *DataSourceService) ListScheduledDataSources(ctx context.Context) ([]models.DataSource, error) {
+	query := `
+		SELECT id, instance_id, name, type, config, status, last_sync, created_at, updated_at
+		FROM data_sources
+		WHERE deleted_at IS NULL
+		  AND status IN ('active', 'error', 'inactive')
+		  AND COALESCE(config->>'sync_frequency', '') <> ''
+		ORDER BY last_sync ASC NULLS FIRST
+	`
+
+	rows, err := config.DB.Query(ctx, query)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	// Create Confluence service
-	confluenceService := sync.NewConfluenceService(baseURL, username, apiToken)
+	var dataSources []models.DataSource
+	for rows.Next() {
+		var ds models.DataSource
+		err := rows.Scan(
+			&ds.ID,
+			&ds.InstanceID,
+			&ds.Name,
+			&ds.Type,
+			&ds.Config,
+			&ds.Status,
+			&ds.LastSync,
+			&ds.CreatedAt,
+			&ds.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		dataSources = append(dataSources, ds)
+	}
 
-	// Sync space
-	return confluenceService.SyncSpace(ctx, ds)
+	return dataSources, rows.Err()
 }
 
-func (s *DataSourceService) syncNotion(ctx context.Context, ds *models.DataSource) error {
-	return errors.New("Notion sync not implemented")
+// validateConfig checks that a config has the fields its data source type
+// needs to sync. Failures wrap ErrInvalidConfig.
+func validateConfig(dsType string, cfg models.Config) error {
+	connector, err := lookupConnector(dsType)
+	if err != nil {
+		return err
+	}
+
+	if err := connector.Validate(cfg); err != nil {
+		return err
+	}
+
+	if cfg.SyncFrequency != "" {
+		if _, ok := syncFrequencies[cfg.SyncFrequency]; !ok {
+			return fmt.Errorf("%w: sync_frequency must be hourly, daily or weekly", ErrInvalidConfig)
+		}
+	}
+
+	return nil
 }
 
-func (s *DataSourceService) syncSlack(ctx context.Context, ds *models.DataSource) error {
-	return errors.New("Slack sync not implemented")
-} 
\ No newline at end of file
+// lookupConnector returns the connector for a data source type, or an
+// ErrInvalidConfig if there is none
+func lookupConnector(dsType string) (sync.Connector, error) {
+	connector, ok := sync.Lookup(dsType)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported data source type %q, must be one of %s",
+			ErrInvalidConfig, dsType, strings.Join(sync.Types(), ", "))
+	}
+	return connector, nil
+}
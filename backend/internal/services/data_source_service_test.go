@@ -0,0 +1,117 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		dsType  string
+		config  models.Config
+		wantErr string
+	}{
+		{
+			name:   "Valid GitHub",
+			dsType: "github",
+			config: models.Config{AccessToken: "token", Repository: "org/repo"},
+		},
+		{
+			name:    "GitHub without repository",
+			dsType:  "github",
+			config:  models.Config{AccessToken: "token"},
+			wantErr: "github requires repository",
+		},
+		{
+			name:   "Valid Confluence",
+			dsType: "confluence",
+			config: models.Config{BaseURL: "https://example.atlassian.net", Username: "user", APIToken: "token", SpaceKey: "DOCS"},
+		},
+		{
+			name:    "Confluence missing fields",
+			dsType:  "confluence",
+			config:  models.Config{BaseURL: "https://example.atlassian.net"},
+			wantErr: "confluence requires username, api_token, space_key",
+		},
+		{
+			name:   "Valid Notion",
+			dsType: "notion",
+			config: models.Config{APIToken: "token", DatabaseID: "db"},
+		},
+		{
+			name:    "Notion without database",
+			dsType:  "notion",
+			config:  models.Config{APIToken: "token"},
+			wantErr: "notion requires database_id",
+		},
+		{
+			name:   "Valid Slack",
+			dsType: "slack",
+			config: models.Config{APIToken: "token", Channels: []string{"C123456"}, SyncFrequency: "hourly"},
+		},
+		{
+			name:    "Slack without channels",
+			dsType:  "slack",
+			config:  models.Config{APIToken: "token"},
+			wantErr: "slack requires channels",
+		},
+		{
+			name:   "Slack discovering channels",
+			dsType: "slack",
+			config: models.Config{APIToken: "token", Filters: map[string]interface{}{"include": []interface{}{"help-*", "!help-internal"}}},
+		},
+		{
+			name:    "Slack with unknown membership",
+			dsType:  "slack",
+			config:  models.Config{APIToken: "token", Filters: map[string]interface{}{"membership": "everyone"}},
+			wantErr: "membership must be member or public",
+		},
+		{
+			name:    "GitHub with malformed repository",
+			dsType:  "github",
+			config:  models.Config{AccessToken: "token", Repository: "repo"},
+			wantErr: "invalid repository format",
+		},
+		{
+			name:    "GitHub with branch and tag",
+			dsType:  "github",
+			config:  models.Config{AccessToken: "token", Repository: "org/repo", Filters: map[string]interface{}{"branch": "main", "tag": "v1.0"}},
+			wantErr: "branch and tag",
+		},
+		{
+			name:    "Unsupported type",
+			dsType:  "zendesk",
+			config:  models.Config{APIToken: "token"},
+			wantErr: "unsupported data source type",
+		},
+		{
+			name:    "Invalid sync frequency",
+			dsType:  "notion",
+			config:  models.Config{APIToken: "token", DatabaseID: "db", SyncFrequency: "monthly"},
+			wantErr: "sync_frequency",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateConfig(tt.dsType, tt.config)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("validateConfig() error = %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrInvalidConfig) {
+				t.Fatalf("Expected ErrInvalidConfig, got: %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
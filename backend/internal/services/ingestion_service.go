@@ -2,64 +2,208 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/Abraham12611/veritas/config"
 	"github.com/Abraham12611/veritas/internal/models"
+	"github.com/Abraham12611/veritas/internal/services/rag"
 )
 
-// IngestionService handles document ingestion and processing
-type IngestionService struct{}
+const (
+	embeddingDimensions = 1536 // Must match the vector(1536) columns in the schema
+	embeddingBatchSize  = 100  // Chunks embedded per batch
+)
+
+// Embedder generates vector embeddings for chunk content.
+// rag.LLMClient satisfies this interface.
+type Embedder interface {
+	CreateEmbeddings(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// IngestionError reports a document that could not be ingested
+type IngestionError struct {
+	Title      string
+	ExternalID string
+	Err        error
+}
+
+func (e *IngestionError) Error() string {
+	return fmt.Sprintf("failed to ingest document %q (%s): %v", e.Title, e.ExternalID, e.Err)
+}
+
+func (e *IngestionError) Unwrap() error {
+	return e.Err
+}
+
+// IngestionService handles document ingestion and processing. Syncs reach it
+// through syncRun, the sync.DocumentSink passed to the connectors.
+type IngestionService struct {
+	embedder Embedder
+}
 
 // NewIngestionService creates a new ingestion service
-func NewIngestionService() *IngestionService {
-	return &IngestionService{}
+func NewIngestionService(embedder Embedder) *IngestionService {
+	return &IngestionService{
+		embedder: embedder,
+	}
 }
 
-// IngestDocument processes and stores a document
+// IngestOutcome describes what Ingest did with a document
+type IngestOutcome string
+
+const (
+	IngestCreated   IngestOutcome = "created"   // New document
+	IngestUpdated   IngestOutcome = "updated"   // Content changed, chunks replaced
+	IngestUnchanged IngestOutcome = "unchanged" // Content hash matched, nothing written
+)
+
+// IngestDocument processes and stores a document. Failures are reported as
+// *IngestionError so callers can tell which document was affected.
 func (s *IngestionService) IngestDocument(ctx context.Context, input models.CreateDocumentInput) (*models.Document, error) {
-	// Create the document record
-	doc, err := s.createDocument(ctx, input)
+	doc, _, err := s.Ingest(ctx, input)
+	return doc, err
+}
+
+// Ingest upserts a document keyed by its data source and external ID.
+// Documents whose content hash is unchanged are skipped; changed documents
+// have their chunks replaced in the same transaction as the document update.
+func (s *IngestionService) Ingest(ctx context.Context, input models.CreateDocumentInput) (*models.Document, IngestOutcome, error) {
+	hash := contentHash(input)
+
+	// Look up the stored version, including soft-deleted documents so an
+	// item that reappears in the source is restored rather than duplicated
+	existing, err := s.findDocument(ctx, input.DataSourceID, input.Metadata.ExternalID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create document: %w", err)
+		return nil, "", s.ingestionError(input, fmt.Errorf("failed to look up document: %w", err))
+	}
+	if existing != nil && existing.DeletedAt == nil && existing.ContentHash == hash {
+		return existing, IngestUnchanged, nil
 	}
 
-	// Process the document content into chunks
-	chunks, err := s.processContent(ctx, doc.ID, input.Content)
+	// Process the document content into embedded chunks before writing
+	// anything, so a failed embedding leaves the stored version untouched
+	chunks, err := s.processContent(ctx, input.Content, input.Anchors)
 	if err != nil {
-		return nil, fmt.Errorf("failed to process content: %w", err)
+		return nil, "", s.ingestionError(input, fmt.Errorf("failed to process content: %w", err))
 	}
 
-	// Store the chunks
-	if err := s.storeChunks(ctx, doc.ID, chunks); err != nil {
-		return nil, fmt.Errorf("failed to store chunks: %w", err)
+	doc, err := s.saveDocument(ctx, input, hash, chunks)
+	if err != nil {
+		return nil, "", s.ingestionError(input, fmt.Errorf("failed to save document: %w", err))
 	}
 
-	return doc, nil
+	if existing != nil {
+		return doc, IngestUpdated, nil
+	}
+	return doc, IngestCreated, nil
 }
 
-// createDocument creates a new document record
-func (s *IngestionService) createDocument(ctx context.Context, input models.CreateDocumentInput) (*models.Document, error) {
+// ingestionError wraps err with the identity of the document being ingested
+func (s *IngestionService) ingestionError(input models.CreateDocumentInput, err error) error {
+	return &IngestionError{
+		Title:      input.Title,
+		ExternalID: input.Metadata.ExternalID,
+		Err:        err,
+	}
+}
+
+// contentHash returns a SHA-256 digest of the fields that end up in the
+// stored document and its chunks
+func contentHash(input models.CreateDocumentInput) string {
+	h := sha256.New()
+	for _, field := range []string{input.Type, input.Title, input.URL, input.Content} {
+		h.Write([]byte(field))
+		h.Write([]byte{0})
+	}
+	for _, anchor := range input.Anchors {
+		fmt.Fprintf(h, "%d %s", anchor.Offset, anchor.URL)
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// findDocument returns the document with the given external ID in a data
+// source, or nil if there is none. Documents without an external ID can't be
+// matched and are always created.
+func (s *IngestionService) findDocument(ctx context.Context, dataSourceID uuid.UUID, externalID string) (*models.Document, error) {
+	if externalID == "" {
+		return nil, nil
+	}
+
+	query := `
+		SELECT id, instance_id, data_source_id, title, content, COALESCE(url, ''), type, metadata,
+			   COALESCE(content_hash, ''), created_at, updated_at, deleted_at
+		FROM documents
+		WHERE data_source_id = $1 AND metadata->>'external_id' = $2
+	`
+
+	var doc models.Document
+	err := config.DB.QueryRow(ctx, query, dataSourceID, externalID).Scan(
+		&doc.ID,
+		&doc.InstanceID,
+		&doc.DataSourceID,
+		&doc.Title,
+		&doc.Content,
+		&doc.URL,
+		&doc.Type,
+		&doc.Metadata,
+		&doc.ContentHash,
+		&doc.CreatedAt,
+		&doc.UpdatedAt,
+		&doc.DeletedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+// saveDocument upserts the document record and replaces its chunks in a
+// single transaction
+func (s *IngestionService) saveDocument(ctx context.Context, input models.CreateDocumentInput, hash string, chunks []models.Chunk) (*models.Document, error) {
+	// Begin transaction
+	tx, err := config.DB.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Insert the document, or update it in place if the external ID is
+	// already known. Restores soft-deleted documents.
 	query := `
 		INSERT INTO documents (
 			id, instance_id, data_source_id, title, content, url, type, metadata,
-			created_at, updated_at
+			content_hash, created_at, updated_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+		ON CONFLICT (data_source_id, (metadata->>'external_id')) DO UPDATE
+		SET title = EXCLUDED.title,
+			content = EXCLUDED.content,
+			url = EXCLUDED.url,
+			type = EXCLUDED.type,
+			metadata = EXCLUDED.metadata,
+			content_hash = EXCLUDED.content_hash,
+			deleted_at = NULL
 		RETURNING id, instance_id, data_source_id, title, content, url, type, metadata,
-				  created_at, updated_at
+				  content_hash, created_at, updated_at
 	`
 
-	id := uuid.New()
-	now := time.Now()
-
 	var doc models.Document
-	err := config.DB.QueryRow(ctx, query,
-		id,
+	err = tx.QueryRow(ctx, query,
+		uuid.New(),
 		input.InstanceID,
 		input.DataSourceID,
 		input.Title,
@@ -67,7 +211,8 @@ func (s *IngestionService) createDocument(ctx context.Context, input models.Crea
 		input.URL,
 		input.Type,
 		input.Metadata,
-		now,
+		hash,
+		time.Now(),
 	).Scan(
 		&doc.ID,
 		&doc.InstanceID,
@@ -77,59 +222,127 @@ func (s *IngestionService) createDocument(ctx context.Context, input models.Crea
 		&doc.URL,
 		&doc.Type,
 		&doc.Metadata,
+		&doc.ContentHash,
 		&doc.CreatedAt,
 		&doc.UpdatedAt,
 	)
 	if err != nil {
+		return nil, fmt.Errorf("failed to upsert document: %w", err)
+	}
+
+	// Replace the previous chunks, if any
+	if _, err := tx.Exec(ctx, `DELETE FROM chunks WHERE document_id = $1`, doc.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete old chunks: %w", err)
+	}
+	if err := s.storeChunks(ctx, tx, doc.ID, chunks); err != nil {
+		return nil, fmt.Errorf("failed to store chunks: %w", err)
+	}
+
+	// Commit transaction
+	if err := tx.Commit(ctx); err != nil {
 		return nil, err
 	}
 
 	return &doc, nil
 }
 
-// processContent splits content into chunks and generates embeddings
-func (s *IngestionService) processContent(ctx context.Context, docID uuid.UUID, content string) ([]models.Chunk, error) {
+// processContent splits content into chunks and generates embeddings. Each
+// chunk lists the URLs of the anchored parts of the content it overlaps.
+func (s *IngestionService) processContent(ctx context.Context, content string, anchors []models.Anchor) ([]models.Chunk, error) {
 	// Split content into chunks
 	chunks := s.splitIntoChunks(content)
 
 	// Process each chunk
-	var processedChunks []models.Chunk
-	for _, chunk := range chunks {
-		// TODO: Generate embedding for chunk using OpenAI or other provider
-		// For now, we'll use a placeholder
-		embedding := []float64{0.0} // Placeholder
-
-		processedChunk := models.Chunk{
+	processedChunks := make([]models.Chunk, len(chunks))
+	for i, chunk := range chunks {
+		processedChunks[i] = models.Chunk{
 			ID:        uuid.New(),
 			Content:   chunk.content,
 			StartChar: chunk.start,
 			EndChar:   chunk.end,
-			Embedding: embedding,
 			Metadata: models.Metadata{
 				Extra: map[string]interface{}{
-					"position": len(processedChunks),
+					"position": i,
 				},
 			},
 		}
-		processedChunks = append(processedChunks, processedChunk)
+		if urls := anchorURLs(anchors, chunk.start, chunk.end); len(urls) > 0 {
+			processedChunks[i].Metadata.Extra["urls"] = urls
+		}
+	}
+
+	// Generate embeddings batch by batch
+	for start := 0; start < len(processedChunks); start += embeddingBatchSize {
+		end := start + embeddingBatchSize
+		if end > len(processedChunks) {
+			end = len(processedChunks)
+		}
+		if err := s.embedBatch(ctx, processedChunks[start:end], start); err != nil {
+			return nil, err
+		}
 	}
 
 	return processedChunks, nil
 }
 
-// storeChunks stores processed chunks in the database
-func (s *IngestionService) storeChunks(ctx context.Context, docID uuid.UUID, chunks []models.Chunk) error {
-	// Begin transaction
-	tx, err := config.DB.Begin(ctx)
+// anchorURLs returns the URLs of the anchored parts overlapping content[start:end].
+// Anchors must be sorted by offset; each part runs until the next anchor.
+func anchorURLs(anchors []models.Anchor, start, end int) []string {
+	var urls []string
+	for i, anchor := range anchors {
+		partEnd := end
+		if i+1 < len(anchors) {
+			partEnd = anchors[i+1].Offset
+		}
+		if anchor.Offset < end && partEnd > start {
+			urls = append(urls, anchor.URL)
+		}
+	}
+	return urls
+}
+
+// embedBatch generates embeddings for a batch of chunks in place. offset is
+// the position of the first chunk in the document, used for error reporting.
+func (s *IngestionService) embedBatch(ctx context.Context, batch []models.Chunk, offset int) error {
+	texts := make([]string, len(batch))
+	for i, chunk := range batch {
+		texts[i] = chunk.Content
+	}
+
+	embeddings, err := s.embedder.CreateEmbeddings(ctx, texts)
 	if err != nil {
-		return err
+		var batchErr *rag.EmbeddingBatchError
+		if errors.As(err, &batchErr) {
+			first := batchErr.Failed[0]
+			return fmt.Errorf("failed to embed chunk %d: %w", offset+first, batchErr.Errors[first])
+		}
+		return fmt.Errorf("failed to embed chunks %d-%d: %w", offset, offset+len(batch)-1, err)
 	}
-	defer tx.Rollback(ctx)
+	if len(embeddings) != len(batch) {
+		return fmt.Errorf("expected %d embeddings, got %d", len(batch), len(embeddings))
+	}
+
+	for i, embedding := range embeddings {
+		if len(embedding) != embeddingDimensions {
+			return fmt.Errorf("embedding for chunk %d has %d dimensions, expected %d",
+				offset+i, len(embedding), embeddingDimensions)
+		}
 
+		batch[i].Embedding = make([]float64, len(embedding))
+		for j, v := range embedding {
+			batch[i].Embedding[j] = float64(v)
+		}
+	}
+
+	return nil
+}
+
+// storeChunks stores processed chunks within the given transaction
+func (s *IngestionService) storeChunks(ctx context.Context, tx pgx.Tx, docID uuid.UUID, chunks []models.Chunk) error {
 	// Prepare the insert statement
 	stmt, err := tx.Prepare(ctx, "insert_chunks", `
 		INSERT INTO chunks (id, document_id, content, embedding, start_char, end_char, metadata, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		VALUES ($1, $2, $3, $4::vector, $5, $6, $7, $8)
 	`)
 	if err != nil {
 		return err
@@ -142,7 +355,7 @@ func (s *IngestionService) storeChunks(ctx context.Context, docID uuid.UUID, chu
 			chunk.ID,
 			docID,
 			chunk.Content,
-			chunk.Embedding,
+			formatVector(chunk.Embedding),
 			chunk.StartChar,
 			chunk.EndChar,
 			chunk.Metadata,
@@ -153,8 +366,21 @@ func (s *IngestionService) storeChunks(ctx context.Context, docID uuid.UUID, chu
 		}
 	}
 
-	// Commit transaction
-	return tx.Commit(ctx)
+	return nil
+}
+
+// formatVector converts an embedding to a pgvector literal
+func formatVector(v []float64) string {
+	var b strings.Builder
+	b.WriteString("[")
+	for i, f := range v {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString(strconv.FormatFloat(f, 'f', -1, 32))
+	}
+	b.WriteString("]")
+	return b.String()
 }
 
 // chunkInfo represents a content chunk with position information
@@ -164,7 +390,8 @@ type chunkInfo struct {
 	end     int
 }
 
-// splitIntoChunks splits content into overlapping chunks
+// splitIntoChunks splits content into overlapping chunks. Chunks start and
+// end on rune boundaries, so multi-byte characters are never split.
 func (s *IngestionService) splitIntoChunks(content string) []chunkInfo {
 	const (
 		maxChunkSize    = 1000 // Maximum characters per chunk
@@ -203,6 +430,11 @@ func (s *IngestionService) splitIntoChunks(content string) []chunkInfo {
 				// Look for sentence break
 				end = start + idx + 1 // Include the period
 			}
+
+			// Don't cut a multi-byte character in two
+			for end > start+1 && !utf8.RuneStart(content[end]) {
+				end--
+			}
 		}
 
 		// Create the chunk
@@ -212,10 +444,20 @@ func (s *IngestionService) splitIntoChunks(content string) []chunkInfo {
 			end:     end,
 		})
 
-		// Move start position for next chunk, accounting for overlap
-		start = end - overlapSize
-		if start < 0 {
-			start = 0
+		// Stop once the end of the content has been chunked
+		if end >= contentLength {
+			break
+		}
+
+		// Move start position for next chunk, accounting for overlap,
+		// but always make forward progress
+		if next := end - overlapSize; next > start {
+			for next < end && !utf8.RuneStart(content[next]) {
+				next++
+			}
+			start = next
+		} else {
+			start = end
 		}
 	}
 
@@ -256,4 +498,67 @@ func (s *IngestionService) DeleteDocument(ctx context.Context, id uuid.UUID) err
 
 	// Commit transaction
 	return tx.Commit(ctx)
-} 
\ No newline at end of file
+}
+
+// DeleteMissingDocuments soft deletes the documents of a data source whose
+// external ID is not in keep, and removes their chunks. It is run after a
+// complete sync to drop items that no longer exist in the source. Documents
+// without an external ID are never deleted here.
+func (s *IngestionService) DeleteMissingDocuments(ctx context.Context, dataSourceID uuid.UUID, keep []string) (int64, error) {
+	return s.deleteDocuments(ctx, dataSourceID, keep, false)
+}
+
+// DeleteDocuments soft deletes the documents of a data source with the given
+// external IDs, and removes their chunks. It is used for items the source
+// reported deleted.
+func (s *IngestionService) DeleteDocuments(ctx context.Context, dataSourceID uuid.UUID, externalIDs []string) (int64, error) {
+	return s.deleteDocuments(ctx, dataSourceID, externalIDs, true)
+}
+
+// deleteDocuments soft deletes the documents of a data source whose external
+// ID is in externalIDs if matching is true, or is not in them otherwise
+func (s *IngestionService) deleteDocuments(ctx context.Context, dataSourceID uuid.UUID, externalIDs []string, matching bool) (int64, error) {
+	// Begin transaction
+	tx, err := config.DB.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Soft delete the matching documents
+	rows, err := tx.Query(ctx, `
+		UPDATE documents
+		SET deleted_at = $1
+		WHERE data_source_id = $2
+		  AND deleted_at IS NULL
+		  AND metadata->>'external_id' IS NOT NULL
+		  AND (metadata->>'external_id' = ANY($3)) = $4
+		RETURNING id
+	`, time.Now(), dataSourceID, externalIDs, matching)
+	if err != nil {
+		return 0, err
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	// Hard delete associated chunks (they don't need soft delete)
+	_, err = tx.Exec(ctx, `
+		DELETE FROM chunks
+		WHERE document_id = ANY($1)
+	`, ids)
+	if err != nil {
+		return 0, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(ctx); err != nil {
+		return 0, err
+	}
+
+	return int64(len(ids)), nil
+}
@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/Abraham12611/veritas/internal/models"
+	"github.com/Abraham12611/veritas/internal/services/rag"
+)
+
+// mockEmbedder implements Embedder for testing
+type mockEmbedder struct {
+	dimensions int
+	failOn     string
+	calls      int32
+}
+
+func (m *mockEmbedder) CreateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	atomic.AddInt32(&m.calls, 1)
+
+	embeddings := make([][]float32, len(texts))
+	batchErr := &rag.EmbeddingBatchError{Errors: make(map[int]error)}
+	for i, text := range texts {
+		if m.failOn != "" && strings.Contains(text, m.failOn) {
+			batchErr.Failed = append(batchErr.Failed, i)
+			batchErr.Errors[i] = errors.New("mock embedding error")
+			continue
+		}
+		embeddings[i] = make([]float32, m.dimensions)
+	}
+	if len(batchErr.Failed) > 0 {
+		return embeddings, batchErr
+	}
+	return embeddings, nil
+}
+
+func TestIngestionService_ProcessContent(t *testing.T) {
+	longContent := strings.Repeat("This is a sentence about the product. ", 300)
+
+	tests := []struct {
+		name     string
+		content  string
+		embedder *mockEmbedder
+		wantErr  string
+	}{
+		{
+			name:     "Single chunk",
+			content:  "Short document",
+			embedder: &mockEmbedder{dimensions: embeddingDimensions},
+		},
+		{
+			name:     "Multiple chunks",
+			content:  longContent,
+			embedder: &mockEmbedder{dimensions: embeddingDimensions},
+		},
+		{
+			name:     "Wrong dimensions",
+			content:  "Short document",
+			embedder: &mockEmbedder{dimensions: 3},
+			wantErr:  "has 3 dimensions, expected 1536",
+		},
+		{
+			name:     "Embedding failure",
+			content:  "Short document",
+			embedder: &mockEmbedder{dimensions: embeddingDimensions, failOn: "Short"},
+			wantErr:  "failed to embed chunk 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewIngestionService(tt.embedder)
+
+			chunks, err := service.processContent(context.Background(), tt.content, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("processContent() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("processContent() error = %v", err)
+			}
+
+			wantCalls := (len(chunks) + embeddingBatchSize - 1) / embeddingBatchSize
+			if int(atomic.LoadInt32(&tt.embedder.calls)) != wantCalls {
+				t.Errorf("Expected %d embedding calls, got %d", wantCalls, tt.embedder.calls)
+			}
+			for i, chunk := range chunks {
+				if len(chunk.Embedding) != embeddingDimensions {
+					t.Errorf("Chunk %d has %d dimensions", i, len(chunk.Embedding))
+				}
+				if chunk.Metadata.Extra["position"] != i {
+					t.Errorf("Chunk %d has position %v", i, chunk.Metadata.Extra["position"])
+				}
+			}
+		})
+	}
+}
+
+func TestIngestionError(t *testing.T) {
+	cause := errors.New("boom")
+	err := error(&IngestionError{Title: "README.md", ExternalID: "abc123", Err: cause})
+
+	if !errors.Is(err, cause) {
+		t.Error("Expected IngestionError to unwrap to its cause")
+	}
+	if !strings.Contains(err.Error(), "README.md") || !strings.Contains(err.Error(), "abc123") {
+		t.Errorf("Expected document identity in error, got: %v", err)
+	}
+}
+
+func TestSplitIntoChunks_MultiByte(t *testing.T) {
+	s := &IngestionService{}
+
+	// Three-byte characters, offset by one byte so chunk sizes fall mid-rune
+	content := "a" + strings.Repeat("日本語のテキスト", 300)
+
+	chunks := s.splitIntoChunks(content)
+	if len(chunks) < 2 {
+		t.Fatalf("Expected several chunks, got %d", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if !utf8.ValidString(chunk.content) {
+			t.Errorf("Chunk %d isn't valid UTF-8", i)
+		}
+		if chunk.content != content[chunk.start:chunk.end] {
+			t.Errorf("Chunk %d doesn't match its offsets", i)
+		}
+	}
+	if last := chunks[len(chunks)-1]; last.end != len(content) {
+		t.Errorf("Expected the last chunk to end the content, got end %d of %d", last.end, len(content))
+	}
+}
+
+func TestFormatVector(t *testing.T) {
+	got := formatVector([]float64{0.5, -1, 0.25})
+	if got != "[0.5,-1,0.25]" {
+		t.Errorf("formatVector() = %s, want [0.5,-1,0.25]", got)
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	input := models.CreateDocumentInput{
+		Title:   "README.md",
+		Content: "Hello",
+		URL:     "https://github.com/org/repo/blob/main/README.md",
+		Type:    "markdown",
+		Metadata: models.Metadata{
+			ExternalID: "README.md",
+		},
+	}
+
+	hash := contentHash(input)
+	if len(hash) != 64 {
+		t.Errorf("Expected a hex SHA-256 digest, got %q", hash)
+	}
+
+	// Metadata doesn't affect the hash
+	same := input
+	same.Metadata.Extra = map[string]interface{}{"sha": "abc123"}
+	if contentHash(same) != hash {
+		t.Error("Expected metadata changes to keep the hash")
+	}
+
+	// Content and title changes do
+	changed := input
+	changed.Content = "Hello, world"
+	if contentHash(changed) == hash {
+		t.Error("Expected content change to change the hash")
+	}
+	changed = input
+	changed.Title = "README"
+	if contentHash(changed) == hash {
+		t.Error("Expected title change to change the hash")
+	}
+	changed = input
+	changed.Anchors = []models.Anchor{{Offset: 0, URL: "https://example.com/#hello"}}
+	if contentHash(changed) == hash {
+		t.Error("Expected anchors to change the hash")
+	}
+
+	// Field boundaries are preserved
+	shifted := input
+	shifted.Title = "README.mdH"
+	shifted.Content = "ello"
+	if contentHash(shifted) == hash {
+		t.Error("Expected fields to be hashed separately")
+	}
+}
+
+func TestAnchorURLs(t *testing.T) {
+	anchors := []models.Anchor{
+		{Offset: 0, URL: "a"},
+		{Offset: 10, URL: "b"},
+		{Offset: 20, URL: "c"},
+	}
+
+	tests := []struct {
+		start, end int
+		want       []string
+	}{
+		{0, 5, []string{"a"}},
+		{5, 15, []string{"a", "b"}},
+		{10, 20, []string{"b"}},
+		{15, 40, []string{"b", "c"}},
+	}
+
+	for _, tt := range tests {
+		got := anchorURLs(anchors, tt.start, tt.end)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("anchorURLs(%d, %d) = %v, want %v", tt.start, tt.end, got, tt.want)
+		}
+	}
+
+	if urls := anchorURLs(nil, 0, 10); urls != nil {
+		t.Errorf("Expected no URLs without anchors, got %v", urls)
+	}
+}
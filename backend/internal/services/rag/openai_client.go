@@ -1,21 +1,59 @@
 package rag
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/cenkalti/backoff/v4"
 )
 
+const (
+	embeddingModel            = "text-embedding-ada-002"
+	maxEmbeddingInputs        = 2048   // Maximum inputs per embeddings request
+	maxEmbeddingInputTokens   = 8191   // Maximum tokens per input
+	maxEmbeddingRequestTokens = 300000 // Maximum tokens across all inputs of a request
+
+	// embeddingRequestTokenBudget leaves headroom under the request limit
+	// because token counts are only estimated
+	embeddingRequestTokenBudget = maxEmbeddingRequestTokens * 3 / 4
+)
+
 // OpenAIClient implements LLMClient using OpenAI's API
 type OpenAIClient struct {
 	apiKey     string
 	httpClient *http.Client
 	model      string
+	baseURL    string
+}
+
+// EmbeddingBatchError reports the inputs that CreateEmbeddings could not embed.
+// Embeddings for all other inputs are still returned alongside it.
+type EmbeddingBatchError struct {
+	// Failed holds the indexes of the failed inputs, in ascending order
+	Failed []int
+
+	// Errors maps each failed index to its cause
+	Errors map[int]error
+}
+
+func (e *EmbeddingBatchError) add(index int, err error) {
+	if e.Errors == nil {
+		e.Errors = make(map[int]error)
+	}
+	e.Failed = append(e.Failed, index)
+	e.Errors[index] = err
+}
+
+func (e *EmbeddingBatchError) Error() string {
+	first := e.Failed[0]
+	return fmt.Sprintf("failed to embed %d input(s), first at index %d: %v", len(e.Failed), first, e.Errors[first])
 }
 
 // OpenAIEmbeddingRequest represents a request to create embeddings
@@ -27,6 +65,7 @@ type OpenAIEmbeddingRequest struct {
 // OpenAIEmbeddingResponse represents the response from the embeddings API
 type OpenAIEmbeddingResponse struct {
 	Data []struct {
+		Index     int       `json:"index"`
 		Embedding []float32 `json:"embedding"`
 	} `json:"data"`
 }
@@ -59,16 +98,117 @@ func NewOpenAIClient(apiKey string, model string) *OpenAIClient {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		model: model,
+		model:   model,
+		baseURL: "https://api.openai.com/v1",
 	}
 }
 
 // CreateEmbedding generates embeddings for the given text
 func (c *OpenAIClient) CreateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := c.CreateEmbeddings(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// CreateEmbeddings generates embeddings for many texts, splitting them into
+// as few requests as the provider's input and token limits allow. The result
+// has one entry per input, in input order. If some inputs fail, the others are
+// still returned and the error is an *EmbeddingBatchError listing the failures.
+func (c *OpenAIClient) CreateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	batchErr := &EmbeddingBatchError{}
+
+	for _, batch := range splitEmbeddingInputs(texts, batchErr) {
+		inputs := make([]string, len(batch))
+		for i, idx := range batch {
+			inputs[i] = texts[idx]
+		}
+
+		results, err := c.requestEmbeddings(ctx, inputs)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			for _, idx := range batch {
+				batchErr.add(idx, err)
+			}
+			continue
+		}
+
+		for i, idx := range batch {
+			embeddings[idx] = results[i]
+		}
+	}
+
+	if len(batchErr.Failed) > 0 {
+		sort.Ints(batchErr.Failed)
+		return embeddings, batchErr
+	}
+
+	return embeddings, nil
+}
+
+// splitEmbeddingInputs groups input indexes into batches that respect the
+// per-request input count and token budget. Inputs that can never be sent
+// are recorded in batchErr instead.
+func splitEmbeddingInputs(texts []string, batchErr *EmbeddingBatchError) [][]int {
+	var batches [][]int
+	var current []int
+	currentTokens := 0
+
+	for i, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			batchErr.add(i, fmt.Errorf("input is empty"))
+			continue
+		}
+
+		tokens := estimateTokens(text)
+		if tokens > maxEmbeddingInputTokens {
+			batchErr.add(i, fmt.Errorf("input exceeds %d token limit", maxEmbeddingInputTokens))
+			continue
+		}
+
+		if len(current) == maxEmbeddingInputs || currentTokens+tokens > embeddingRequestTokenBudget {
+			batches = append(batches, current)
+			current = nil
+			currentTokens = 0
+		}
+
+		current = append(current, i)
+		currentTokens += tokens
+	}
+
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+
+	return batches
+}
+
+// estimateTokens approximates the token count of text. It errs high: ASCII
+// is counted at 3 characters per token, which covers code and punctuation,
+// and every other character (CJK, emoji, accented letters) as a whole token.
+func estimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return ascii/3 + other + 1
+}
+
+// requestEmbeddings sends a single embeddings request and returns the
+// embeddings in input order
+func (c *OpenAIClient) requestEmbeddings(ctx context.Context, inputs []string) ([][]float32, error) {
 	// Create request body
 	reqBody := OpenAIEmbeddingRequest{
-		Model: "text-embedding-ada-002",
-		Input: []string{text},
+		Model: embeddingModel,
+		Input: inputs,
 	}
 
 	// Marshal request body
@@ -77,26 +217,30 @@ func (c *OpenAIClient) CreateEmbedding(ctx context.Context, text string) ([]floa
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/embeddings", strings.NewReader(string(jsonBody)))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
-
-	// Execute request with retry
+	// Execute request with retry, building a fresh request for each attempt
+	// since the body is consumed by the previous one
 	var resp *http.Response
 	operation := func() error {
-		var err error
+		req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/embeddings", bytes.NewReader(jsonBody))
+		if err != nil {
+			return backoff.Permanent(fmt.Errorf("failed to create request: %w", err))
+		}
+
+		// Set headers
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.apiKey))
+
 		resp, err = c.httpClient.Do(req)
 		if err != nil {
 			return fmt.Errorf("request failed: %w", err)
 		}
 		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+			resp.Body.Close()
+			err := fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+			if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+				return err
+			}
+			return backoff.Permanent(err)
 		}
 		return nil
 	}
@@ -104,7 +248,7 @@ func (c *OpenAIClient) CreateEmbedding(ctx context.Context, text string) ([]floa
 	b := backoff.NewExponentialBackOff()
 	b.MaxElapsedTime = 30 * time.Second
 
-	if err := backoff.Retry(operation, b); err != nil {
+	if err := backoff.Retry(operation, backoff.WithContext(b, ctx)); err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -115,11 +259,20 @@ func (c *OpenAIClient) CreateEmbedding(ctx context.Context, text string) ([]floa
 		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	if len(embeddingResp.Data) == 0 {
-		return nil, fmt.Errorf("no embeddings returned")
+	if len(embeddingResp.Data) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(embeddingResp.Data))
+	}
+
+	// Order results by the index the provider reports for each input
+	embeddings := make([][]float32, len(inputs))
+	for _, d := range embeddingResp.Data {
+		if d.Index < 0 || d.Index >= len(inputs) || embeddings[d.Index] != nil {
+			return nil, fmt.Errorf("invalid embedding index %d in response", d.Index)
+		}
+		embeddings[d.Index] = d.Embedding
 	}
 
-	return embeddingResp.Data[0].Embedding, nil
+	return embeddings, nil
 }
 
 // Complete generates a completion for the given prompt
@@ -146,7 +299,7 @@ func (c *OpenAIClient) Complete(ctx context.Context, req CompletionRequest) (str
 	}
 
 	// Create request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", strings.NewReader(string(jsonBody)))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", strings.NewReader(string(jsonBody)))
 	if err != nil {
 		return "", fmt.Errorf("failed to create request: %w", err)
 	}
@@ -2,7 +2,15 @@ package rag
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 )
 
@@ -33,7 +41,7 @@ func TestOpenAIClient_CreateEmbedding(t *testing.T) {
 		},
 		{
 			name:    "Long text",
-			text:    "This is a very long text that should still work but will be truncated if it exceeds the model's maximum token limit. " + "Lorem ipsum dolor sit amet. ".repeat(100),
+			text:    "This is a very long text that should still work but will be truncated if it exceeds the model's maximum token limit. " + strings.Repeat("Lorem ipsum dolor sit amet. ", 100),
 			wantErr: false,
 		},
 	}
@@ -159,7 +167,7 @@ func TestOpenAIClient_ConcurrentRequests(t *testing.T) {
 	// Make concurrent requests
 	for i := 0; i < 3; i++ {
 		go func(i int) {
-			embedding, err := client.CreateEmbedding(ctx, "Test text "+string(i))
+			embedding, err := client.CreateEmbedding(ctx, "Test text "+strconv.Itoa(i))
 			results <- result{embedding, err}
 		}(i)
 	}
@@ -174,4 +182,140 @@ func TestOpenAIClient_ConcurrentRequests(t *testing.T) {
 			t.Errorf("Concurrent request %d returned empty embedding", i)
 		}
 	}
-} 
\ No newline at end of file
+} 
+func TestOpenAIClient_CreateEmbeddings(t *testing.T) {
+	// Create a mock embeddings API that returns results in reverse order
+	var requestCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requestCount, 1)
+
+		var req OpenAIEmbeddingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var resp OpenAIEmbeddingResponse
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			resp.Data = append(resp.Data, struct {
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			}{Index: i, Embedding: []float32{float32(len(req.Input[i]))}})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewOpenAIClient("test-key", "gpt-4-turbo-preview")
+	client.baseURL = server.URL
+
+	texts := []string{"a", "bb", "", "dddd"}
+	embeddings, err := client.CreateEmbeddings(context.Background(), texts)
+
+	// The empty input should be reported without failing the others
+	var batchErr *EmbeddingBatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("Expected EmbeddingBatchError, got: %v", err)
+	}
+	if len(batchErr.Failed) != 1 || batchErr.Failed[0] != 2 {
+		t.Errorf("Expected input 2 to fail, got %v", batchErr.Failed)
+	}
+
+	// Results should be in input order
+	for i, text := range texts {
+		if i == 2 {
+			if embeddings[i] != nil {
+				t.Errorf("Expected nil embedding for failed input, got %v", embeddings[i])
+			}
+			continue
+		}
+		if len(embeddings[i]) != 1 || int(embeddings[i][0]) != len(text) {
+			t.Errorf("Embedding %d = %v, want [%d]", i, embeddings[i], len(text))
+		}
+	}
+
+	if count := atomic.LoadInt32(&requestCount); count != 1 {
+		t.Errorf("Expected 1 request, got %d", count)
+	}
+}
+
+func TestSplitEmbeddingInputs(t *testing.T) {
+	repeat := func(text string, n int) []string {
+		texts := make([]string, n)
+		for i := range texts {
+			texts[i] = text
+		}
+		return texts
+	}
+
+	large := strings.Repeat("x", 3*5000) // ~5000 tokens each
+	largeCJK := strings.Repeat("漢", 5000)
+	tooLarge := strings.Repeat("x", 3*(maxEmbeddingInputTokens+1))
+
+	tests := []struct {
+		name        string
+		texts       []string
+		wantBatches int
+		wantFailed  []int
+	}{
+		{
+			name:        "Single batch",
+			texts:       []string{"one", "two", "three"},
+			wantBatches: 1,
+		},
+		{
+			name:        "Split by input count",
+			texts:       repeat("text", maxEmbeddingInputs+1),
+			wantBatches: 2,
+		},
+		{
+			name:        "Split by token budget",
+			texts:       repeat(large, 64),
+			wantBatches: 2,
+		},
+		{
+			name:        "Split CJK by token budget",
+			texts:       repeat(largeCJK, 64),
+			wantBatches: 2,
+		},
+		{
+			name:        "Input over token limit",
+			texts:       []string{"ok", tooLarge},
+			wantBatches: 1,
+			wantFailed:  []int{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batchErr := &EmbeddingBatchError{}
+			batches := splitEmbeddingInputs(tt.texts, batchErr)
+
+			if len(batches) != tt.wantBatches {
+				t.Errorf("Expected %d batches, got %d", tt.wantBatches, len(batches))
+			}
+			if fmt.Sprint(batchErr.Failed) != fmt.Sprint(tt.wantFailed) {
+				t.Errorf("Expected failed inputs %v, got %v", tt.wantFailed, batchErr.Failed)
+			}
+
+			// Every input is either batched once, in order, or failed
+			seen := len(batchErr.Failed)
+			last := -1
+			for _, batch := range batches {
+				if len(batch) > maxEmbeddingInputs {
+					t.Errorf("Batch has %d inputs, limit is %d", len(batch), maxEmbeddingInputs)
+				}
+				for _, idx := range batch {
+					if idx <= last {
+						t.Errorf("Batch indexes out of order: %d after %d", idx, last)
+					}
+					last = idx
+					seen++
+				}
+			}
+			if seen != len(tt.texts) {
+				t.Errorf("Expected %d inputs accounted for, got %d", len(tt.texts), seen)
+			}
+		})
+	}
+}
@@ -26,6 +26,11 @@ type LLMClient interface {
 	// The embedding can be used for similarity search or other vector operations.
 	CreateEmbedding(ctx context.Context, text string) ([]float32, error)
 
+	// CreateEmbeddings generates embeddings for many texts in as few provider
+	// requests as possible. Results are returned in input order; inputs that
+	// fail are left nil and reported through an *EmbeddingBatchError.
+	CreateEmbeddings(ctx context.Context, texts []string) ([][]float32, error)
+
 	// Complete generates text based on the given completion request.
 	// It should handle prompt construction and response parsing.
 	Complete(ctx context.Context, req CompletionRequest) (string, error)
@@ -35,7 +40,8 @@ type LLMClient interface {
 // Implementations should handle efficient similarity search over document embeddings.
 type VectorStore interface {
 	// SearchSimilar finds document chunks similar to the given embedding.
-	// It returns up to 'limit' chunks, ordered by similarity score.
+	// It returns up to 'limit' chunks, ordered by similarity score, each with
+	// its offsets and the title and URL of the document it came from.
 	SearchSimilar(ctx context.Context, instanceID uuid.UUID, embedding []float32, limit int) ([]models.DocumentChunk, error)
 }
 
@@ -144,7 +150,7 @@ func (s *RAGService) AnswerQuestion(ctx context.Context, instanceID uuid.UUID, q
 			ID:        uuid.New(),
 			QueryID:   query.ID,
 			Content:   completion,
-			Sources:   s.formatSources(chunks),
+			Citations: s.formatCitations(chunks),
 			CreatedAt: time.Now(),
 		}
 	}
@@ -176,7 +182,7 @@ func (s *RAGService) buildPrompt(question string, chunks []models.DocumentChunk)
 
 	sb.WriteString("Context:\n")
 	for i, chunk := range chunks {
-		sb.WriteString(fmt.Sprintf("\n[Document %d: %s]\n%s\n", i+1, chunk.Title, chunk.Content))
+		sb.WriteString(fmt.Sprintf("\n[Source %d: %s]\n%s\n", i+1, chunk.Title, chunk.Content))
 	}
 
 	sb.WriteString("\nQuestion: ")
@@ -186,17 +192,20 @@ func (s *RAGService) buildPrompt(question string, chunks []models.DocumentChunk)
 	return sb.String()
 }
 
-// formatSources formats document chunks as sources
-func (s *RAGService) formatSources(chunks []models.DocumentChunk) []models.Source {
-	sources := make([]models.Source, len(chunks))
+// formatCitations formats the retrieved chunks as answer citations
+func (s *RAGService) formatCitations(chunks []models.DocumentChunk) []models.Citation {
+	citations := make([]models.Citation, len(chunks))
 	for i, chunk := range chunks {
-		sources[i] = models.Source{
-			Title: chunk.Title,
-			URL:   chunk.URL,
-			Score: chunk.Score,
+		citations[i] = models.Citation{
+			DocumentID: chunk.DocumentID,
+			ChunkID:    chunk.ID,
+			Content:    chunk.Content,
+			URL:        chunk.URL,
+			Title:      chunk.Title,
+			Relevance:  chunk.Score,
 		}
 	}
-	return sources
+	return citations
 }
 
 // createNoContextAnswer creates an answer when no relevant context is found
@@ -205,7 +214,7 @@ func (s *RAGService) createNoContextAnswer(queryID uuid.UUID) *models.Answer {
 		ID:        uuid.New(),
 		QueryID:   queryID,
 		Content:   "No relevant information found in the knowledge base.",
-		Sources:   []models.Source{},
+		Citations: []models.Citation{},
 		CreatedAt: time.Now(),
 	}
 } 
\ No newline at end of file
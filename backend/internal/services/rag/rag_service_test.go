@@ -27,6 +27,9 @@ func NewMockLLMClient() *MockLLMClient {
 }
 
 func (m *MockLLMClient) CreateEmbedding(ctx context.Context, text string) ([]float32, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if m.shouldFail {
 		return nil, errors.New("mock embedding error")
 	}
@@ -36,6 +39,18 @@ func (m *MockLLMClient) CreateEmbedding(ctx context.Context, text string) ([]flo
 	return []float32{0.1, 0.2, 0.3}, nil
 }
 
+func (m *MockLLMClient) CreateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embedding, err := m.CreateEmbedding(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		embeddings[i] = embedding
+	}
+	return embeddings, nil
+}
+
 func (m *MockLLMClient) Complete(ctx context.Context, req CompletionRequest) (string, error) {
 	if m.shouldFail {
 		return "", errors.New("mock completion error")
@@ -246,6 +261,44 @@ func TestRAGService_AnswerQuestion(t *testing.T) {
 	}
 }
 
+func TestRAGService_Citations(t *testing.T) {
+	mockDB := NewMockDB()
+	mockLLM := NewMockLLMClient()
+	mockVector := NewMockVectorStore()
+	for i := range mockVector.chunks {
+		mockVector.chunks[i].DocumentID = uuid.New()
+		mockVector.chunks[i].StartChar = i * 100
+		mockVector.chunks[i].EndChar = i*100 + 36
+	}
+
+	service := NewRAGService(mockDB, mockLLM, mockVector)
+
+	answer, err := service.AnswerQuestion(context.Background(), uuid.New(), "What is in document 1?")
+	if err != nil {
+		t.Fatalf("AnswerQuestion() error = %v", err)
+	}
+
+	// Each retrieved chunk should be cited with its chunk and document IDs
+	if len(answer.Citations) != len(mockVector.chunks) {
+		t.Fatalf("Expected %d citations, got %d", len(mockVector.chunks), len(answer.Citations))
+	}
+	for i, citation := range answer.Citations {
+		chunk := mockVector.chunks[i]
+		if citation.ChunkID != chunk.ID || citation.DocumentID != chunk.DocumentID {
+			t.Errorf("Citation %d does not reference chunk %s of document %s", i, chunk.ID, chunk.DocumentID)
+		}
+		if citation.Content != chunk.Content || citation.Relevance != chunk.Score {
+			t.Errorf("Citation %d = %+v, want content and score of chunk", i, citation)
+		}
+	}
+
+	// The prompt should contain the chunk passages, not whole documents
+	prompt := service.buildPrompt("question", mockVector.chunks)
+	if !strings.Contains(prompt, "[Source 1: Test Document 1]") || !strings.Contains(prompt, mockVector.chunks[1].Content) {
+		t.Errorf("Unexpected prompt: %s", prompt)
+	}
+}
+
 func TestRAGService_Concurrency(t *testing.T) {
 	// Create dependencies
 	mockDB := NewMockDB()
@@ -2,20 +2,22 @@ package rag
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/Abraham12611/veritas/internal/models"
 )
 
 // SupabaseVectorStore implements the VectorStore interface using Supabase's pgvector
 type SupabaseVectorStore struct {
-	db *models.DB
+	db *pgxpool.Pool
 }
 
 // NewSupabaseVectorStore creates a new Supabase vector store
-func NewSupabaseVectorStore(db *models.DB) *SupabaseVectorStore {
+func NewSupabaseVectorStore(db *pgxpool.Pool) *SupabaseVectorStore {
 	return &SupabaseVectorStore{
 		db: db,
 	}
@@ -26,55 +28,72 @@ func (s *SupabaseVectorStore) SearchSimilar(ctx context.Context, instanceID uuid
 	// Convert embedding to PostgreSQL vector format
 	vectorStr := formatVector(embedding)
 
-	// Query similar documents using cosine similarity
+	// Query similar chunks using cosine distance, ordering by the distance
+	// itself so the ivfflat index on chunks.embedding can be used
 	query := `
-		WITH document_scores AS (
+		WITH chunk_scores AS (
 			SELECT 
-				d.id,
+				c.id,
+				c.document_id,
 				d.title,
-				d.content,
-				d.url,
+				COALESCE(c.metadata->'extra'->'urls'->>0, d.url) AS url,
+				c.content,
+				c.start_char,
+				c.end_char,
 				d.metadata,
-				1 - (d.embedding <=> $1::vector) as similarity_score
-			FROM documents d
+				1 - (c.embedding <=> $1::vector) as similarity_score
+			FROM chunks c
+			JOIN documents d ON d.id = c.document_id
 			WHERE d.instance_id = $2
 				AND d.deleted_at IS NULL
-			ORDER BY similarity_score DESC
+				AND c.embedding IS NOT NULL
+			ORDER BY c.embedding <=> $1::vector
 			LIMIT $3
 		)
 		SELECT 
 			id,
+			document_id,
 			title,
-			content,
 			url,
+			content,
+			start_char,
+			end_char,
 			metadata,
 			similarity_score
-		FROM document_scores
+		FROM chunk_scores
 		WHERE similarity_score > 0.7 -- Minimum similarity threshold
+		ORDER BY similarity_score DESC
 	`
 
 	rows, err := s.db.Query(ctx, query, vectorStr, instanceID, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query similar documents: %w", err)
+		return nil, fmt.Errorf("failed to query similar chunks: %w", err)
 	}
 	defer rows.Close()
 
 	var chunks []models.DocumentChunk
 	for rows.Next() {
 		var chunk models.DocumentChunk
+		var url *string
 		var metadata []byte
 
 		err := rows.Scan(
 			&chunk.ID,
+			&chunk.DocumentID,
 			&chunk.Title,
+			&url,
 			&chunk.Content,
-			&chunk.URL,
+			&chunk.StartChar,
+			&chunk.EndChar,
 			&metadata,
 			&chunk.Score,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan row: %w", err)
 		}
+		if url != nil {
+			chunk.URL = *url
+		}
 
 		// Parse metadata JSON
 		if len(metadata) > 0 {
@@ -105,4 +124,4 @@ func formatVector(v []float32) string {
 	}
 	b.WriteString("]")
 	return b.String()
-} 
\ No newline at end of file
+}
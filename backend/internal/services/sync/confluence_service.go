@@ -2,21 +2,40 @@ package sync
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/Abraham12611/veritas/internal/httpx"
 	"github.com/Abraham12611/veritas/internal/logger"
 	"github.com/Abraham12611/veritas/internal/models"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/time/rate"
 )
 
+const (
+	// confluenceModifiedKey is the checkpoint entry holding when the last
+	// sync started; the next one searches for pages modified since. Page
+	// entries are keyed by page ID and hold the synced version number.
+	confluenceModifiedKey = "#lastmodified"
+
+	// confluenceReconciledKey is the checkpoint entry holding when every
+	// page ID in the space was last listed
+	confluenceReconciledKey = "#reconciled"
+
+	// confluenceReconcileInterval is how often a sync lists every page, to
+	// find pages that were deleted or trashed since
+	confluenceReconcileInterval = 24 * time.Hour
+
+	// confluenceModifiedLookback is how far before the last sync the search
+	// for modified pages starts, since CQL only compares minutes
+	confluenceModifiedLookback = 10 * time.Minute
+)
+
 // ConfluenceService handles syncing content from Confluence
 type ConfluenceService struct {
 	client      *http.Client
@@ -26,17 +45,19 @@ type ConfluenceService struct {
 	username    string
 	apiToken    string
 	concurrency int
+	sink        DocumentSink
 }
 
 // NewConfluenceService creates a new Confluence sync service
-func NewConfluenceService(baseURL, username, apiToken string) *ConfluenceService {
+func NewConfluenceService(baseURL, username, apiToken string, sink DocumentSink) *ConfluenceService {
 	// Create HTTP client with reasonable timeouts
 	client := &http.Client{
 		Timeout: 30 * time.Second,
 	}
 
-	// Create rate limiter: 200 requests per minute (Confluence Cloud API limit)
-	limiter := rate.NewLimiter(rate.Every(time.Minute/200), 1)
+	// Rate limiter shared by all syncs with the same credentials: 200
+	// requests per minute (Confluence Cloud API limit)
+	limiter := httpx.SharedLimiter("confluence", baseURL+"|"+username+"|"+apiToken, rate.Every(time.Minute/200), 1)
 
 	return &ConfluenceService{
 		client:      client,
@@ -46,84 +67,130 @@ func NewConfluenceService(baseURL, username, apiToken string) *ConfluenceService
 		username:    username,
 		apiToken:    apiToken,
 		concurrency: 5, // Process 5 pages concurrently
+		sink:        sink,
 	}
 }
 
 // withRetry executes a function with retries and rate limiting
 func (s *ConfluenceService) withRetry(ctx context.Context, operation func() error) error {
-	var lastErr error
-	for attempt := 0; attempt <= s.maxRetries; attempt++ {
-		// Wait for rate limiter
-		if err := s.limiter.Wait(ctx); err != nil {
-			return fmt.Errorf("rate limiter error: %w", err)
-		}
+	return httpx.Retry(ctx, s.limiter, httpx.RetryPolicy{MaxRetries: s.maxRetries}, operation)
+}
 
-		// Execute operation
-		if err := operation(); err != nil {
-			lastErr = err
-			// Check if error is retryable
-			if isRetryableError(err) {
-				// Exponential backoff
-				backoff := time.Duration(attempt*attempt) * time.Second
-				select {
-				case <-time.After(backoff):
-					continue
-				case <-ctx.Done():
-					return ctx.Err()
-				}
-			}
-			return err // Non-retryable error
+// getJSON sends an authenticated GET request and decodes the JSON response
+func (s *ConfluenceService) getJSON(ctx context.Context, endpoint string, out interface{}) error {
+	return s.withRetry(ctx, func() error {
+		// A request can only be sent once, so build one per attempt
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return err
 		}
-		return nil // Success
-	}
-	return fmt.Errorf("max retries exceeded: %w", lastErr)
+
+		req.SetBasicAuth(s.username, s.apiToken)
+		req.Header.Set("Accept", "application/json")
+
+		return httpx.DoJSON(s.client, req, out)
+	})
 }
 
-// isRetryableError determines if an error should trigger a retry
-func isRetryableError(err error) bool {
-	if err == nil {
-		return false
-	}
+// confluenceConnector adapts ConfluenceService to the Connector interface
+type confluenceConnector struct{}
+
+func init() {
+	Register(confluenceConnector{})
+}
 
-	// Check for rate limit errors (HTTP 429)
-	if strings.Contains(err.Error(), "429") {
-		return true
+func (confluenceConnector) Type() string {
+	return "confluence"
+}
+
+func (confluenceConnector) Validate(cfg models.Config) error {
+	r := requiredFields{dsType: "confluence"}
+	r.require("base_url", cfg.BaseURL)
+	r.require("username", cfg.Username)
+	r.require("api_token", cfg.APIToken)
+	r.require("space_key", cfg.SpaceKey)
+	return r.err()
+}
+
+func (confluenceConnector) TestConnection(ctx context.Context, ds *models.DataSource) error {
+	s := newConfluenceServiceFor(ds, nil)
+	s.maxRetries = 0
+	return s.TestConnection(ctx, ds)
+}
+
+func (confluenceConnector) Sync(ctx context.Context, ds *models.DataSource, sink DocumentSink, checkpoint Checkpoint) (*SyncResult, error) {
+	next, err := newConfluenceServiceFor(ds, sink).SyncSpace(ctx, ds, checkpoint)
+	if err != nil {
+		return nil, err
 	}
 
-	// Check for network-related errors
-	if strings.Contains(err.Error(), "connection refused") ||
-		strings.Contains(err.Error(), "timeout") ||
-		strings.Contains(err.Error(), "temporary failure") {
-		return true
+	// Pages that aren't searched for are kept, so every page is accounted for
+	return &SyncResult{Checkpoint: next, Complete: true}, nil
+}
+
+// newConfluenceServiceFor creates a Confluence service from a data source's config
+func newConfluenceServiceFor(ds *models.DataSource, sink DocumentSink) *ConfluenceService {
+	return NewConfluenceService(ds.Config.BaseURL, ds.Config.Username, ds.Config.APIToken, sink)
+}
+
+// TestConnection checks that the space can be read with the credentials
+func (s *ConfluenceService) TestConnection(ctx context.Context, ds *models.DataSource) error {
+	if _, err := s.getSpace(ctx, ds.Config.SpaceKey); err != nil {
+		return fmt.Errorf("failed to get space details: %w", err)
 	}
+	return nil
+}
 
-	// Check for server errors (HTTP 5xx)
-	if strings.Contains(err.Error(), "500") ||
-		strings.Contains(err.Error(), "502") ||
-		strings.Contains(err.Error(), "503") ||
-		strings.Contains(err.Error(), "504") {
-		return true
+// SyncSpace syncs content from a Confluence space. With a checkpoint, only
+// pages modified since the last sync are searched for and the others are
+// kept, except once a day when every page is listed again, so pages deleted
+// or trashed since are dropped. Pages whose version is unchanged aren't
+// ingested again. The returned checkpoint maps every page synced or kept to
+// its version.
+func (s *ConfluenceService) SyncSpace(ctx context.Context, ds *models.DataSource, checkpoint Checkpoint) (Checkpoint, error) {
+	started := time.Now().UTC()
+
+	var modifiedSince, reconciled time.Time
+	if v, ok := checkpoint[confluenceModifiedKey]; ok {
+		modifiedSince, _ = time.Parse(time.RFC3339, v)
+	}
+	if v, ok := checkpoint[confluenceReconciledKey]; ok {
+		reconciled, _ = time.Parse(time.RFC3339, v)
 	}
 
-	return false
-}
+	// Without a cursor every page is listed with its content; once a day
+	// every page is listed by version only, to find those that are gone.
+	// Otherwise modified pages are searched for with their content.
+	listAll := modifiedSince.IsZero() || started.Sub(reconciled) >= confluenceReconcileInterval
+	withBody := modifiedSince.IsZero() || !listAll
 
-// SyncSpace syncs content from a Confluence space
-func (s *ConfluenceService) SyncSpace(ctx context.Context, ds *models.DataSource) error {
 	logger.Info("Starting Confluence space sync", logger.Fields{
 		"dataSourceId": ds.ID,
 		"spaceKey":    ds.Config.SpaceKey,
+		"incremental":  !listAll,
 	})
 
-	// Get space details first
-	space, err := s.getSpace(ctx, ds.Config.SpaceKey)
-	if err != nil {
-		return fmt.Errorf("failed to get space details: %w", err)
+	// Check the space exists first
+	if _, err := s.getSpace(ctx, ds.Config.SpaceKey); err != nil {
+		return nil, fmt.Errorf("failed to get space details: %w", err)
+	}
+
+	var mu sync.Mutex
+	next := make(Checkpoint)
+	listed := make(map[string]bool)
+	failed := false
+
+	// keep marks a page as unchanged since its synced version
+	keep := func(pageID, version string) {
+		s.sink.KeepDocument(pageID)
+		mu.Lock()
+		next[pageID] = version
+		mu.Unlock()
 	}
 
 	// Create error group for concurrent processing
 	g, ctx := errgroup.WithContext(ctx)
-	g.SetLimit(s.concurrency) // Limit concurrent goroutines
+	g.SetLimit(s.concurrency + 1) // Page workers plus the fetcher
 
 	// Channel for pages to process
 	pagesChan := make(chan ConfluencePage, s.concurrency*2)
@@ -144,9 +211,22 @@ func (s *ConfluenceService) SyncSpace(ctx context.Context, ds *models.DataSource
 							"pageId":    page.ID,
 							"pageTitle": page.Title,
 						})
+
+						// Keep the previous version until it is synced again.
+						// A failed ingest has already marked the page as seen.
+						mu.Lock()
+						failed = true
+						if previous, ok := checkpoint[page.ID]; ok {
+							next[page.ID] = previous
+						}
+						mu.Unlock()
 						// Continue processing other pages
 						continue
 					}
+
+					mu.Lock()
+					next[page.ID] = strconv.Itoa(page.Version.Number)
+					mu.Unlock()
 				}
 			}
 			return nil
@@ -156,30 +236,53 @@ func (s *ConfluenceService) SyncSpace(ctx context.Context, ds *models.DataSource
 	// Start page fetcher
 	g.Go(func() error {
 		defer close(pagesChan)
-		start := 0
-		limit := 25
 
-		for {
-			var pages []ConfluencePage
-			err := s.withRetry(ctx, func() error {
-				p, err := s.getPages(ctx, ds.Config.SpaceKey, start, limit)
+		// send queues a page for processing unless its version was synced
+		send := func(page ConfluencePage) error {
+			listed[page.ID] = true
+			version := strconv.Itoa(page.Version.Number)
+			if checkpoint[page.ID] == version {
+				keep(page.ID, version)
+				return nil
+			}
+
+			if !withBody {
+				full, err := s.getPage(ctx, page.ID)
 				if err != nil {
-					return err
+					return fmt.Errorf("failed to get page %s: %w", page.ID, err)
 				}
-				pages = p
+				if full == nil {
+					// Deleted since it was listed
+					return nil
+				}
+				page = *full
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case pagesChan <- page:
 				return nil
-			})
+			}
+		}
 
+		if !listAll {
+			return s.searchModifiedPages(ctx, ds.Config.SpaceKey, modifiedSince.Add(-confluenceModifiedLookback), send)
+		}
+
+		start := 0
+		limit := 25
+
+		for {
+			pages, err := s.getPages(ctx, ds.Config.SpaceKey, start, limit, withBody)
 			if err != nil {
 				return fmt.Errorf("failed to get pages: %w", err)
 			}
 
 			// Send pages to processor
 			for _, page := range pages {
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				case pagesChan <- page:
+				if err := send(page); err != nil {
+					return err
 				}
 			}
 
@@ -197,7 +300,27 @@ func (s *ConfluenceService) SyncSpace(ctx context.Context, ds *models.DataSource
 
 	// Wait for all goroutines to complete
 	if err := g.Wait(); err != nil {
-		return fmt.Errorf("error during sync: %w", err)
+		return nil, fmt.Errorf("error during sync: %w", err)
+	}
+
+	// Pages not modified since the last sync are unchanged
+	if !listAll {
+		for pageID, version := range checkpoint {
+			if strings.HasPrefix(pageID, "#") || listed[pageID] {
+				continue
+			}
+			keep(pageID, version)
+		}
+	}
+
+	// Search from the same point again rather than skip what failed
+	next[confluenceModifiedKey] = started.Format(time.RFC3339)
+	if failed && !modifiedSince.IsZero() {
+		next[confluenceModifiedKey] = checkpoint[confluenceModifiedKey]
+	}
+	next[confluenceReconciledKey] = checkpoint[confluenceReconciledKey]
+	if listAll {
+		next[confluenceReconciledKey] = started.Format(time.RFC3339)
 	}
 
 	logger.Info("Completed Confluence space sync", logger.Fields{
@@ -205,81 +328,79 @@ func (s *ConfluenceService) SyncSpace(ctx context.Context, ds *models.DataSource
 		"spaceKey":    ds.Config.SpaceKey,
 	})
 
-	return nil
+	return next, nil
 }
 
 // getSpace retrieves space details
 func (s *ConfluenceService) getSpace(ctx context.Context, spaceKey string) (*ConfluenceSpace, error) {
 	endpoint := fmt.Sprintf("%s/wiki/rest/api/space/%s", s.baseURL, url.PathEscape(spaceKey))
 	
-	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.SetBasicAuth(s.username, s.apiToken)
-	req.Header.Set("Accept", "application/json")
-
 	var space ConfluenceSpace
-	err = s.withRetry(ctx, func() error {
-		resp, err := s.client.Do(req)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("failed to get space: status %d: %s", resp.StatusCode, string(body))
-		}
-
-		return json.NewDecoder(resp.Body).Decode(&space)
-	})
-
-	if err != nil {
-		return nil, err
+	if err := s.getJSON(ctx, endpoint, &space); err != nil {
+		return nil, fmt.Errorf("failed to get space: %w", err)
 	}
 
 	return &space, nil
 }
 
-// getPages retrieves a batch of pages from a space
-func (s *ConfluenceService) getPages(ctx context.Context, spaceKey string, start, limit int) ([]ConfluencePage, error) {
-	endpoint := fmt.Sprintf("%s/wiki/rest/api/space/%s/content/page?start=%d&limit=%d&expand=body.storage",
-		s.baseURL, url.PathEscape(spaceKey), start, limit)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
-	if err != nil {
-		return nil, err
+// getPages retrieves a batch of pages from a space, with their content if
+// withBody is set
+func (s *ConfluenceService) getPages(ctx context.Context, spaceKey string, start, limit int, withBody bool) ([]ConfluencePage, error) {
+	expand := "version"
+	if withBody {
+		expand = "body.storage,version"
 	}
-
-	req.SetBasicAuth(s.username, s.apiToken)
-	req.Header.Set("Accept", "application/json")
+	endpoint := fmt.Sprintf("%s/wiki/rest/api/space/%s/content/page?start=%d&limit=%d&expand=%s",
+		s.baseURL, url.PathEscape(spaceKey), start, limit, expand)
 
 	var response struct {
 		Results []ConfluencePage `json:"results"`
 	}
+	if err := s.getJSON(ctx, endpoint, &response); err != nil {
+		return nil, err
+	}
 
-	err = s.withRetry(ctx, func() error {
-		resp, err := s.client.Do(req)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
+	return response.Results, nil
+}
 
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("failed to get pages: status %d: %s", resp.StatusCode, string(body))
+// searchModifiedPages passes each page of a space modified since the given
+// time to fn, with its content, oldest first. The time is given to CQL
+// relative to now, since CQL reads absolute times in the user's timezone.
+func (s *ConfluenceService) searchModifiedPages(ctx context.Context, spaceKey string, since time.Time, fn func(ConfluencePage) error) error {
+	minutes := int(time.Since(since)/time.Minute) + 1
+	cql := fmt.Sprintf(`space = %q and type = page and lastmodified >= now("-%dm") order by lastmodified asc`, spaceKey, minutes)
+
+	params := url.Values{}
+	params.Set("cql", cql)
+	params.Set("limit", "25")
+	params.Set("expand", "body.storage,version")
+	endpoint := fmt.Sprintf("%s/wiki/rest/api/content/search?%s", s.baseURL, params.Encode())
+
+	for endpoint != "" {
+		var response struct {
+			Results []ConfluencePage `json:"results"`
+			Links   struct {
+				Next string `json:"next"`
+			} `json:"_links"`
+		}
+		if err := s.getJSON(ctx, endpoint, &response); err != nil {
+			return fmt.Errorf("failed to search pages: %w", err)
 		}
 
-		return json.NewDecoder(resp.Body).Decode(&response)
-	})
+		for _, page := range response.Results {
+			if err := fn(page); err != nil {
+				return err
+			}
+		}
 
-	if err != nil {
-		return nil, err
+		// The next link is relative to the wiki, and carries a cursor
+		endpoint = ""
+		if response.Links.Next != "" {
+			endpoint = s.baseURL + "/wiki" + response.Links.Next
+		}
 	}
 
-	return response.Results, nil
+	return nil
 }
 
 // processPage processes a single Confluence page
@@ -314,11 +435,9 @@ func (s *ConfluenceService) processPage(ctx context.Context, ds *models.DataSour
 		},
 	}
 
-	// TODO: Call ingestion service to process the document
-	logger.Info("Would process document", logger.Fields{
-		"title": input.Title,
-		"url":   input.URL,
-	})
+	if _, err := s.sink.IngestDocument(ctx, input); err != nil {
+		return fmt.Errorf("failed to ingest document: %w", err)
+	}
 
 	return nil
 }
@@ -340,6 +459,9 @@ type ConfluencePage struct {
 	Type    string `json:"type"`
 	Status  string `json:"status"`
 	Title   string `json:"title"`
+	Space   struct {
+		Key string `json:"key"`
+	} `json:"space"`
 	Version struct {
 		Number int    `json:"number"`
 		When   string `json:"when"`
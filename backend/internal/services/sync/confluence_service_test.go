@@ -2,15 +2,16 @@ package sync
 
 import (
 	"context"
-	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/Abraham12611/veritas/internal/httpx"
 	"github.com/Abraham12611/veritas/internal/models"
 )
 
@@ -107,7 +108,7 @@ func TestConfluenceService_SyncSpace(t *testing.T) {
 	defer server.Close()
 
 	// Create service with mock server URL
-	service := NewConfluenceService(server.URL, "test-user", "test-token")
+	service := NewConfluenceService(server.URL, "test-user", "test-token", newMockSink())
 	service.concurrency = 2 // Use 2 workers for testing
 
 	// Create test data source
@@ -115,7 +116,7 @@ func TestConfluenceService_SyncSpace(t *testing.T) {
 		ID:         uuid.New(),
 		InstanceID: uuid.New(),
 		Type:       "confluence",
-		Config: models.DataSourceConfig{
+		Config: models.Config{
 			BaseURL:   server.URL,
 			Username:  "test-user",
 			APIToken:  "test-token",
@@ -124,7 +125,7 @@ func TestConfluenceService_SyncSpace(t *testing.T) {
 	}
 
 	// Test sync
-	err := service.SyncSpace(context.Background(), ds)
+	_, err := service.SyncSpace(context.Background(), ds, nil)
 	if err != nil {
 		t.Errorf("SyncSpace() error = %v", err)
 	}
@@ -145,7 +146,7 @@ func TestConfluenceService_RateLimiting(t *testing.T) {
 	defer server.Close()
 
 	// Create service with mock server URL
-	service := NewConfluenceService(server.URL, "test-user", "test-token")
+	service := NewConfluenceService(server.URL, "test-user", "test-token", newMockSink())
 	service.maxRetries = 2 // Reduce retries for faster test
 
 	// Create test data source
@@ -153,7 +154,7 @@ func TestConfluenceService_RateLimiting(t *testing.T) {
 		ID:         uuid.New(),
 		InstanceID: uuid.New(),
 		Type:       "confluence",
-		Config: models.DataSourceConfig{
+		Config: models.Config{
 			BaseURL:   server.URL,
 			Username:  "test-user",
 			APIToken:  "test-token",
@@ -162,7 +163,7 @@ func TestConfluenceService_RateLimiting(t *testing.T) {
 	}
 
 	// Test sync with rate limiting
-	err := service.SyncSpace(context.Background(), ds)
+	_, err := service.SyncSpace(context.Background(), ds, nil)
 	if err == nil {
 		t.Error("Expected rate limit error, got nil")
 	}
@@ -180,14 +181,14 @@ func TestConfluenceService_Cancellation(t *testing.T) {
 	defer server.Close()
 
 	// Create service with mock server URL
-	service := NewConfluenceService(server.URL, "test-user", "test-token")
+	service := NewConfluenceService(server.URL, "test-user", "test-token", newMockSink())
 
 	// Create test data source
 	ds := &models.DataSource{
 		ID:         uuid.New(),
 		InstanceID: uuid.New(),
 		Type:       "confluence",
-		Config: models.DataSourceConfig{
+		Config: models.Config{
 			BaseURL:   server.URL,
 			Username:  "test-user",
 			APIToken:  "test-token",
@@ -200,7 +201,7 @@ func TestConfluenceService_Cancellation(t *testing.T) {
 	defer cancel()
 
 	// Test sync with cancellation
-	err := service.SyncSpace(ctx, ds)
+	_, err := service.SyncSpace(ctx, ds, nil)
 	if err == nil {
 		t.Error("Expected context deadline exceeded error, got nil")
 	}
@@ -251,14 +252,15 @@ func TestConfluenceService_HTMLConversion(t *testing.T) {
 	defer server.Close()
 
 	// Create service with mock server URL
-	service := NewConfluenceService(server.URL, "test-user", "test-token")
+	sink := newMockSink()
+	service := NewConfluenceService(server.URL, "test-user", "test-token", sink)
 
 	// Create test data source
 	ds := &models.DataSource{
 		ID:         uuid.New(),
 		InstanceID: uuid.New(),
 		Type:       "confluence",
-		Config: models.DataSourceConfig{
+		Config: models.Config{
 			BaseURL:   server.URL,
 			Username:  "test-user",
 			APIToken:  "test-token",
@@ -267,10 +269,201 @@ func TestConfluenceService_HTMLConversion(t *testing.T) {
 	}
 
 	// Test sync
-	err := service.SyncSpace(context.Background(), ds)
+	_, err := service.SyncSpace(context.Background(), ds, nil)
 	if err != nil {
 		t.Errorf("SyncSpace() error = %v", err)
 	}
+
+	// Verify the converted page reached the sink
+	docs := sink.ingested()
+	if len(docs) != 1 {
+		t.Fatalf("Expected 1 ingested document, got %d", len(docs))
+	}
+	if docs[0].Title != "Test Page" || docs[0].Metadata.ExternalID != "page1" {
+		t.Errorf("Unexpected document: title=%q externalId=%q", docs[0].Title, docs[0].Metadata.ExternalID)
+	}
+	if strings.Contains(docs[0].Content, "<h1>") {
+		t.Errorf("Expected plain text content, got: %s", docs[0].Content)
+	}
+}
+
+func TestConfluenceService_IncrementalSync(t *testing.T) {
+	var cql string
+	var listed int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/wiki/rest/api/content/search":
+			if r.URL.Query().Get("cursor") == "" {
+				cql = r.URL.Query().Get("cql")
+				w.Write([]byte(`{
+					"results": [
+						{"id": "page1", "status": "current", "title": "Unchanged", "version": {"number": 3}}
+					],
+					"_links": {"next": "/rest/api/content/search?cql=next&cursor=abc"}
+				}`))
+				return
+			}
+			w.Write([]byte(`{
+				"results": [
+					{"id": "page2", "status": "current", "title": "Edited", "version": {"number": 5},
+					 "body": {"storage": {"value": "<p>New content</p>"}}}
+				],
+				"_links": {}
+			}`))
+		case strings.HasSuffix(r.URL.Path, "/content/page"):
+			atomic.AddInt32(&listed, 1)
+			w.Write([]byte(`{"results": []}`))
+		default:
+			w.Write([]byte(`{"id": "123", "key": "TEST", "name": "Test Space"}`))
+		}
+	}))
+	defer server.Close()
+
+	sink := newMockSink()
+	service := NewConfluenceService(server.URL, "test-user", "test-token", sink)
+
+	ds := &models.DataSource{
+		ID:         uuid.New(),
+		InstanceID: uuid.New(),
+		Type:       "confluence",
+		Config:     models.Config{BaseURL: server.URL, SpaceKey: "TEST"},
+	}
+
+	now := time.Now().UTC()
+	checkpoint := Checkpoint{
+		confluenceModifiedKey:   now.Add(-time.Hour).Format(time.RFC3339),
+		confluenceReconciledKey: now.Add(-time.Hour).Format(time.RFC3339),
+		"page1":                 "3",
+		"page2":                 "4",
+		"page3":                 "1",
+	}
+
+	next, err := service.SyncSpace(context.Background(), ds, checkpoint)
+	if err != nil {
+		t.Fatalf("SyncSpace() error = %v", err)
+	}
+
+	if atomic.LoadInt32(&listed) != 0 {
+		t.Errorf("Expected no full listing between reconciliations")
+	}
+	if !strings.HasPrefix(cql, `space = "TEST" and type = page and lastmodified >= now("-7`) {
+		t.Errorf("Unexpected CQL: %s", cql)
+	}
+
+	// Only the edited page is ingested again
+	docs := sink.ingested()
+	if len(docs) != 1 || docs[0].Metadata.ExternalID != "page2" {
+		t.Fatalf("Expected only page2 to be ingested, got %+v", docs)
+	}
+
+	// The unchanged page and the page not modified since are kept
+	kept := sink.keptIDs()
+	if len(kept) != 2 || !containsString(kept, "page1") || !containsString(kept, "page3") {
+		t.Errorf("Expected page1 and page3 to be kept, got %v", kept)
+	}
+
+	if next["page1"] != "3" || next["page2"] != "5" || next["page3"] != "1" {
+		t.Errorf("Unexpected page versions in checkpoint: %v", next)
+	}
+	if next[confluenceModifiedKey] <= checkpoint[confluenceModifiedKey] {
+		t.Errorf("Expected the modified cursor to advance, got %q", next[confluenceModifiedKey])
+	}
+	if next[confluenceReconciledKey] != checkpoint[confluenceReconciledKey] {
+		t.Errorf("Expected the reconciliation time to be unchanged, got %q", next[confluenceReconciledKey])
+	}
+
+	// A page that fails to ingest keeps its previous version, and isn't
+	// also counted as kept
+	sink = newMockSink()
+	sink.shouldFail = true
+	service.sink = sink
+
+	next, err = service.SyncSpace(context.Background(), ds, checkpoint)
+	if err != nil {
+		t.Fatalf("SyncSpace() error = %v", err)
+	}
+	if next["page2"] != "4" {
+		t.Errorf("Expected the previous version of page2, got %q", next["page2"])
+	}
+	if containsString(sink.keptIDs(), "page2") {
+		t.Errorf("Expected the failed page not to be kept, got %v", sink.keptIDs())
+	}
+	if next[confluenceModifiedKey] != checkpoint[confluenceModifiedKey] {
+		t.Errorf("Expected the modified cursor unchanged after a failure, got %q", next[confluenceModifiedKey])
+	}
+}
+
+func TestConfluenceService_Reconciliation(t *testing.T) {
+	var fetched []string
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/content/page"):
+			if r.URL.Query().Get("expand") != "version" {
+				t.Errorf("Expected pages to be listed without content, got expand=%s", r.URL.Query().Get("expand"))
+			}
+			w.Write([]byte(`{
+				"results": [
+					{"id": "page1", "status": "current", "title": "Unchanged", "version": {"number": 3}},
+					{"id": "page2", "status": "current", "title": "Edited", "version": {"number": 5}}
+				]
+			}`))
+		case strings.HasPrefix(r.URL.Path, "/wiki/rest/api/content/"):
+			mu.Lock()
+			fetched = append(fetched, strings.TrimPrefix(r.URL.Path, "/wiki/rest/api/content/"))
+			mu.Unlock()
+			w.Write([]byte(`{"id": "page2", "status": "current", "title": "Edited", "version": {"number": 5},
+				"space": {"key": "TEST"}, "body": {"storage": {"value": "<p>New content</p>"}}}`))
+		default:
+			w.Write([]byte(`{"id": "123", "key": "TEST", "name": "Test Space"}`))
+		}
+	}))
+	defer server.Close()
+
+	sink := newMockSink()
+	service := NewConfluenceService(server.URL, "test-user", "test-token", sink)
+
+	ds := &models.DataSource{
+		ID:         uuid.New(),
+		InstanceID: uuid.New(),
+		Type:       "confluence",
+		Config:     models.Config{BaseURL: server.URL, SpaceKey: "TEST"},
+	}
+
+	now := time.Now().UTC()
+	checkpoint := Checkpoint{
+		confluenceModifiedKey:   now.Add(-time.Hour).Format(time.RFC3339),
+		confluenceReconciledKey: now.Add(-2 * confluenceReconcileInterval).Format(time.RFC3339),
+		"page1":                 "3",
+		"page2":                 "4",
+		"page3":                 "1",
+	}
+
+	next, err := service.SyncSpace(context.Background(), ds, checkpoint)
+	if err != nil {
+		t.Fatalf("SyncSpace() error = %v", err)
+	}
+
+	// Only the edited page's content is fetched
+	if len(fetched) != 1 || fetched[0] != "page2" {
+		t.Errorf("Expected only page2 to be fetched, got %v", fetched)
+	}
+	docs := sink.ingested()
+	if len(docs) != 1 || docs[0].Content != "New content" {
+		t.Fatalf("Expected page2 to be ingested, got %+v", docs)
+	}
+
+	// The deleted page is neither kept nor in the checkpoint
+	kept := sink.keptIDs()
+	if len(kept) != 1 || kept[0] != "page1" {
+		t.Errorf("Expected only page1 to be kept, got %v", kept)
+	}
+	if _, ok := next["page3"]; ok {
+		t.Errorf("Expected page3 to be dropped from the checkpoint")
+	}
+	if next[confluenceReconciledKey] <= checkpoint[confluenceReconciledKey] {
+		t.Errorf("Expected the reconciliation time to advance, got %q", next[confluenceReconciledKey])
+	}
 }
 
 func TestConfluenceService_RetryBehavior(t *testing.T) {
@@ -296,11 +489,10 @@ func TestConfluenceService_RetryBehavior(t *testing.T) {
 	defer server.Close()
 
 	// Create service with mock server URL
-	service := NewConfluenceService(server.URL, "test-user", "test-token")
+	service := NewConfluenceService(server.URL, "test-user", "test-token", newMockSink())
 	service.maxRetries = 3
 
 	// Test retrying operation
-	var result ConfluenceSpace
 	err := service.withRetry(context.Background(), func() error {
 		req, _ := http.NewRequest("GET", server.URL+"/test", nil)
 		resp, err := service.client.Do(req)
@@ -309,11 +501,7 @@ func TestConfluenceService_RetryBehavior(t *testing.T) {
 		}
 		defer resp.Body.Close()
 
-		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("server error: %d", resp.StatusCode)
-		}
-
-		return nil
+		return httpx.CheckResponse(resp)
 	})
 
 	if err != nil {
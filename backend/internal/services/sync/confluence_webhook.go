@@ -0,0 +1,105 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Abraham12611/veritas/internal/httpx"
+	"github.com/Abraham12611/veritas/internal/logger"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// confluenceWebhookEvent is a delivery from a Confluence webhook
+type confluenceWebhookEvent struct {
+	WebhookEvent string `json:"webhookEvent"` // e.g. page_updated, page_removed
+	Page         struct {
+		ID       json.Number `json:"id"` // a number or a string, depending on the product
+		SpaceKey string      `json:"spaceKey"`
+	} `json:"page"`
+}
+
+// ParseWebhook maps Confluence page events to changes. Deliveries are signed
+// with the webhook's secret, which is the data source's webhook secret.
+func (confluenceConnector) ParseWebhook(ds *models.DataSource, req WebhookRequest) (*WebhookEvent, error) {
+	if err := verifyHMAC(ds.Config.WebhookSecret, req.Body, req.Header.Get("X-Hub-Signature"), "sha256="); err != nil {
+		return nil, err
+	}
+
+	var event confluenceWebhookEvent
+	if err := json.Unmarshal(req.Body, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse event: %w", err)
+	}
+
+	name := req.Header.Get("X-Event-Key")
+	if name == "" {
+		name = event.WebhookEvent
+	}
+
+	// Webhooks cover every space
+	pageID := event.Page.ID.String()
+	if !strings.HasPrefix(name, "page_") || pageID == "" ||
+		event.Page.SpaceKey != "" && event.Page.SpaceKey != ds.Config.SpaceKey {
+		return &WebhookEvent{}, nil
+	}
+
+	switch name {
+	case "page_removed", "page_trashed":
+		return &WebhookEvent{Deleted: []string{pageID}}, nil
+	default:
+		return &WebhookEvent{Changed: []string{pageID}}, nil
+	}
+}
+
+func (confluenceConnector) SyncItems(ctx context.Context, ds *models.DataSource, sink DocumentSink, externalIDs []string) ([]string, error) {
+	return newConfluenceServiceFor(ds, sink).SyncPages(ctx, ds, externalIDs)
+}
+
+// SyncPages syncs the pages with the given IDs. It returns the IDs of pages
+// that were deleted, trashed or moved out of the space.
+func (s *ConfluenceService) SyncPages(ctx context.Context, ds *models.DataSource, pageIDs []string) ([]string, error) {
+	var deleted []string
+	for _, pageID := range pageIDs {
+		page, err := s.getPage(ctx, pageID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get page %s: %w", pageID, err)
+		}
+		if page == nil || page.Status != "current" || page.Space.Key != ds.Config.SpaceKey {
+			deleted = append(deleted, pageID)
+			continue
+		}
+
+		if err := s.processPage(ctx, ds, *page); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			logger.Error("Failed to process page", err, logger.Fields{
+				"pageId": pageID,
+			})
+		}
+	}
+
+	return deleted, nil
+}
+
+// getPage retrieves a page, or returns nil if it doesn't exist
+func (s *ConfluenceService) getPage(ctx context.Context, pageID string) (*ConfluencePage, error) {
+	endpoint := fmt.Sprintf("%s/wiki/rest/api/content/%s?expand=body.storage,version,space",
+		s.baseURL, pageID)
+
+	var page ConfluencePage
+	err := s.getJSON(ctx, endpoint, &page)
+
+	var statusErr *httpx.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
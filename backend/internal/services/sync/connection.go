@@ -0,0 +1,81 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Abraham12611/veritas/internal/httpx"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// connectionTestTimeout bounds a connection test, which includes waiting for
+// the connector's rate limiter
+const connectionTestTimeout = 20 * time.Second
+
+// CheckConnection validates a data source's config and tests its connection,
+// classifying any failure so the user can tell what to fix
+func CheckConnection(ctx context.Context, connector Connector, ds *models.DataSource) models.ConnectionTestResult {
+	if err := connector.Validate(ds.Config); err != nil {
+		return models.ConnectionTestResult{
+			Status:  models.ConnectionInvalidConfig,
+			Message: err.Error(),
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, connectionTestTimeout)
+	defer cancel()
+
+	if err := connector.TestConnection(ctx, ds); err != nil {
+		return models.ConnectionTestResult{
+			Status:  connectionStatus(err),
+			Message: err.Error(),
+		}
+	}
+
+	return models.ConnectionTestResult{OK: true, Status: models.ConnectionOK}
+}
+
+// connectionStatus classifies a TestConnection error as one of the
+// models.Connection* statuses
+func connectionStatus(err error) string {
+	var slackErr *SlackAPIError
+	if errors.As(err, &slackErr) {
+		switch slackErr.Code {
+		case "not_authed", "invalid_auth", "account_inactive", "token_revoked", "token_expired":
+			return models.ConnectionUnauthorized
+		case "missing_scope", "not_allowed_token_type":
+			return models.ConnectionScopeMissing
+		case "channel_not_found":
+			return models.ConnectionNotFound
+		case "ratelimited":
+			return models.ConnectionRateLimited
+		}
+		return models.ConnectionError
+	}
+
+	var statusErr *httpx.StatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusTooManyRequests,
+			statusErr.StatusCode == http.StatusForbidden && statusErr.RetryAfter > 0:
+			return models.ConnectionRateLimited
+		case statusErr.StatusCode == http.StatusUnauthorized:
+			return models.ConnectionUnauthorized
+		case statusErr.StatusCode == http.StatusForbidden:
+			return models.ConnectionScopeMissing
+		case statusErr.StatusCode == http.StatusNotFound:
+			return models.ConnectionNotFound
+		}
+		return models.ConnectionError
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded) {
+		return models.ConnectionUnreachable
+	}
+
+	return models.ConnectionError
+}
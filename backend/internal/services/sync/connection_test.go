@@ -0,0 +1,84 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Abraham12611/veritas/internal/httpx"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// mockConnector is a Connector whose connection test fails with err
+type mockConnector struct {
+	err error
+}
+
+func (m mockConnector) Type() string { return "mock" }
+
+func (m mockConnector) Validate(cfg models.Config) error {
+	r := requiredFields{dsType: "mock"}
+	r.require("api_token", cfg.APIToken)
+	return r.err()
+}
+
+func (m mockConnector) TestConnection(ctx context.Context, ds *models.DataSource) error {
+	return m.err
+}
+
+func (m mockConnector) Sync(ctx context.Context, ds *models.DataSource, sink DocumentSink, checkpoint Checkpoint) (*SyncResult, error) {
+	return &SyncResult{}, nil
+}
+
+func TestCheckConnection(t *testing.T) {
+	ds := &models.DataSource{Type: "mock", Config: models.Config{APIToken: "token"}}
+
+	result := CheckConnection(context.Background(), mockConnector{}, ds)
+	if !result.OK || result.Status != models.ConnectionOK {
+		t.Errorf("Expected ok, got: %+v", result)
+	}
+
+	result = CheckConnection(context.Background(), mockConnector{}, &models.DataSource{Type: "mock"})
+	if result.OK || result.Status != models.ConnectionInvalidConfig {
+		t.Errorf("Expected invalid config, got: %+v", result)
+	}
+
+	notFound := fmt.Errorf("failed to get database: %w", &httpx.StatusError{StatusCode: http.StatusNotFound, Body: "object_not_found"})
+	result = CheckConnection(context.Background(), mockConnector{err: notFound}, ds)
+	if result.OK || result.Status != models.ConnectionNotFound || result.Message != notFound.Error() {
+		t.Errorf("Expected not found, got: %+v", result)
+	}
+}
+
+func TestConnectionStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"Unauthorized", &httpx.StatusError{StatusCode: http.StatusUnauthorized}, models.ConnectionUnauthorized},
+		{"Forbidden", &httpx.StatusError{StatusCode: http.StatusForbidden}, models.ConnectionScopeMissing},
+		{"GitHub rate limit", &httpx.StatusError{StatusCode: http.StatusForbidden, RetryAfter: time.Minute}, models.ConnectionRateLimited},
+		{"Too many requests", &httpx.StatusError{StatusCode: http.StatusTooManyRequests}, models.ConnectionRateLimited},
+		{"Not found", &httpx.StatusError{StatusCode: http.StatusNotFound}, models.ConnectionNotFound},
+		{"Server error", &httpx.StatusError{StatusCode: http.StatusBadGateway}, models.ConnectionError},
+		{"Slack invalid auth", &SlackAPIError{Code: "invalid_auth"}, models.ConnectionUnauthorized},
+		{"Slack missing scope", fmt.Errorf("failed to get channel C1: %w", &SlackAPIError{Code: "missing_scope"}), models.ConnectionScopeMissing},
+		{"Slack channel not found", &SlackAPIError{Code: "channel_not_found"}, models.ConnectionNotFound},
+		{"Network error", &net.OpError{Op: "dial", Err: errors.New("connection refused")}, models.ConnectionUnreachable},
+		{"Timeout", context.DeadlineExceeded, models.ConnectionUnreachable},
+		{"Other", errors.New("unexpected"), models.ConnectionError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := connectionStatus(tt.err); got != tt.want {
+				t.Errorf("connectionStatus() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
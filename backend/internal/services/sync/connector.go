@@ -0,0 +1,121 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	gosync "sync"
+
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// ErrInvalidConfig is returned when a data source config is missing fields
+// its connector needs
+var ErrInvalidConfig = errors.New("invalid data source config")
+
+// Checkpoint is connector-specific state carried from one successful sync to
+// the next, e.g. the time of the last sync for incremental fetching
+type Checkpoint map[string]string
+
+// SyncResult describes a successful sync
+type SyncResult struct {
+	// Checkpoint is stored and passed to the next sync
+	Checkpoint Checkpoint
+
+	// Complete is true if the sync produced every item in the source, so
+	// documents that weren't produced can be deleted. Incremental syncs
+	// only produce changed items and must leave it false.
+	Complete bool
+}
+
+// Connector syncs one type of data source. Connectors are stateless; the
+// data source carries the config and credentials.
+type Connector interface {
+	// Type returns the data source type handled by the connector
+	Type() string
+
+	// Validate checks that a config has the fields the connector needs.
+	// Failures wrap ErrInvalidConfig.
+	Validate(cfg models.Config) error
+
+	// TestConnection checks that the source is reachable with the configured
+	// credentials, without syncing anything. It makes a single cheap
+	// authenticated call and doesn't retry, so failures are reported quickly.
+	TestConnection(ctx context.Context, ds *models.DataSource) error
+
+	// Sync sends the source's documents to sink. checkpoint is the one
+	// returned by the previous successful sync, or nil.
+	Sync(ctx context.Context, ds *models.DataSource, sink DocumentSink, checkpoint Checkpoint) (*SyncResult, error)
+}
+
+var (
+	registryMu gosync.RWMutex
+	registry   = make(map[string]Connector)
+)
+
+// Register makes a connector available for its data source type. It panics
+// if the type is already registered.
+func Register(c Connector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	if _, exists := registry[c.Type()]; exists {
+		panic(fmt.Sprintf("sync: connector already registered for type %q", c.Type()))
+	}
+	registry[c.Type()] = c
+}
+
+// Lookup returns the connector registered for a data source type
+func Lookup(dsType string) (Connector, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	c, ok := registry[dsType]
+	return c, ok
+}
+
+// Types returns the registered data source types in alphabetical order
+func Types() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	types := make([]string, 0, len(registry))
+	for t := range registry {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// requiredFields collects missing config fields for Validate implementations
+type requiredFields struct {
+	dsType  string
+	missing []string
+}
+
+// require records field as missing if value is blank
+func (r *requiredFields) require(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		r.missing = append(r.missing, field)
+	}
+}
+
+// requireList records field as missing if values has no non-blank entries
+func (r *requiredFields) requireList(field string, values []string) {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return
+		}
+	}
+	r.missing = append(r.missing, field)
+}
+
+// err returns an ErrInvalidConfig listing the missing fields, or nil
+func (r *requiredFields) err() error {
+	if len(r.missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s requires %s", ErrInvalidConfig, r.dsType, strings.Join(r.missing, ", "))
+}
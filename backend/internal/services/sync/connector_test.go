@@ -0,0 +1,56 @@
+package sync
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+func TestRegistry(t *testing.T) {
+	want := []string{"confluence", "github", "notion", "slack"}
+	if got := Types(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Types() = %v, want %v", got, want)
+	}
+
+	for _, dsType := range want {
+		c, ok := Lookup(dsType)
+		if !ok {
+			t.Fatalf("Expected connector for %s", dsType)
+		}
+		if c.Type() != dsType {
+			t.Errorf("Expected connector type %s, got %s", dsType, c.Type())
+		}
+	}
+
+	if _, ok := Lookup("zendesk"); ok {
+		t.Error("Expected no connector for zendesk")
+	}
+}
+
+func TestRegister_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic registering a duplicate connector")
+		}
+	}()
+
+	Register(githubConnector{})
+}
+
+func TestConnector_Validate(t *testing.T) {
+	c, _ := Lookup("slack")
+
+	err := c.Validate(models.Config{APIToken: "token", Channels: []string{" "}})
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("Expected ErrInvalidConfig, got: %v", err)
+	}
+	if err.Error() != "invalid data source config: slack requires channels" {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if err := c.Validate(models.Config{APIToken: "token", Channels: []string{"C123456"}}); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
@@ -0,0 +1,344 @@
+package sync
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// defaultMaxFileSize is the largest file synced when the config doesn't set
+// max_file_size
+const defaultMaxFileSize = 1 << 20
+
+// githubFilters are the GitHub settings in Config.Filters:
+//
+//	branch, tag     the branch or tag to sync instead of the default branch
+//	include         glob patterns of the files to sync; patterns starting
+//	                with ! exclude. Replaces the default list of extensions.
+//	exclude         glob patterns of files not to sync
+//	max_file_size   largest file to sync, in bytes
+//	issues          also sync issues
+//	pull_requests   also sync pull requests
+//	labels          only sync issues and pull requests with any of these
+//	                labels
+//	state           only sync issues and pull requests that are "open" or
+//	                "closed"; "all" by default
+//	wiki            also sync the repository's wiki
+type githubFilters struct {
+	ref         string
+	include     []*regexp.Regexp
+	exclude     []*regexp.Regexp
+	maxFileSize int
+
+	issues       bool
+	pullRequests bool
+	labels       []string
+	state        string
+	wiki         bool
+}
+
+// parseGitHubFilters reads the GitHub settings from Config.Filters
+func parseGitHubFilters(filters map[string]interface{}) (*githubFilters, error) {
+	f := &githubFilters{maxFileSize: defaultMaxFileSize}
+
+	branch, err := filterString(filters, "branch")
+	if err != nil {
+		return nil, err
+	}
+	tag, err := filterString(filters, "tag")
+	if err != nil {
+		return nil, err
+	}
+	if branch != "" && tag != "" {
+		return nil, fmt.Errorf("filters can't set both branch and tag")
+	}
+	f.ref = branch + tag
+
+	if f.include, f.exclude, err = filterPatterns(filters); err != nil {
+		return nil, err
+	}
+
+	if f.maxFileSize, err = filterSize(filters, "max_file_size", defaultMaxFileSize); err != nil {
+		return nil, err
+	}
+
+	if f.issues, err = filterBool(filters, "issues"); err != nil {
+		return nil, err
+	}
+	if f.pullRequests, err = filterBool(filters, "pull_requests"); err != nil {
+		return nil, err
+	}
+	if f.wiki, err = filterBool(filters, "wiki"); err != nil {
+		return nil, err
+	}
+	if f.labels, err = filterStrings(filters, "labels"); err != nil {
+		return nil, err
+	}
+	if f.state, err = filterString(filters, "state"); err != nil {
+		return nil, err
+	}
+	switch f.state {
+	case "":
+		f.state = "all"
+	case "open", "closed", "all":
+	default:
+		return nil, fmt.Errorf("filters.state must be open, closed or all")
+	}
+
+	return f, nil
+}
+
+// matches reports whether a path passes the include and exclude patterns
+func (f *githubFilters) matches(p string) bool {
+	if len(f.include) > 0 && !matchAny(f.include, p) {
+		return false
+	}
+	return !matchAny(f.exclude, p)
+}
+
+// filterString returns a string setting from Config.Filters
+func filterString(filters map[string]interface{}, key string) (string, error) {
+	v, ok := filters[key]
+	if !ok || v == nil {
+		return "", nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("filters.%s must be a string", key)
+	}
+	return strings.TrimSpace(s), nil
+}
+
+// filterBool returns a boolean setting from Config.Filters
+func filterBool(filters map[string]interface{}, key string) (bool, error) {
+	v, ok := filters[key]
+	if !ok || v == nil {
+		return false, nil
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("filters.%s must be true or false", key)
+	}
+	return b, nil
+}
+
+// filterPatterns returns the glob patterns of the include and exclude
+// settings in Config.Filters. Include patterns starting with ! exclude.
+func filterPatterns(filters map[string]interface{}) (include, exclude []*regexp.Regexp, err error) {
+	includes, err := filterStrings(filters, "include")
+	if err != nil {
+		return nil, nil, err
+	}
+	excludes, err := filterStrings(filters, "exclude")
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, pattern := range includes {
+		if strings.HasPrefix(pattern, "!") {
+			excludes = append(excludes, strings.TrimPrefix(pattern, "!"))
+			continue
+		}
+		re, err := compileGlob(pattern)
+		if err != nil {
+			return nil, nil, err
+		}
+		include = append(include, re)
+	}
+	for _, pattern := range excludes {
+		re, err := compileGlob(pattern)
+		if err != nil {
+			return nil, nil, err
+		}
+		exclude = append(exclude, re)
+	}
+	return include, exclude, nil
+}
+
+// filterSize returns a size in bytes from Config.Filters, or def if it isn't
+// set
+func filterSize(filters map[string]interface{}, key string, def int) (int, error) {
+	v, ok := filters[key]
+	if !ok || v == nil {
+		return def, nil
+	}
+	size, ok := v.(float64)
+	if !ok || size <= 0 || size != float64(int(size)) {
+		return 0, fmt.Errorf("filters.%s must be a positive number of bytes", key)
+	}
+	return int(size), nil
+}
+
+// filterStrings returns a list setting from Config.Filters. A single string
+// is a list of one.
+func filterStrings(filters map[string]interface{}, key string) ([]string, error) {
+	switch v := filters[key].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []string:
+		return v, nil
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("filters.%s must be a list of strings", key)
+			}
+			values = append(values, s)
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("filters.%s must be a list of strings", key)
+}
+
+// compileGlob converts a glob pattern into a regular expression for
+// slash-separated paths. * and ? don't match a slash, **/ matches any number
+// of directories and a trailing ** everything below. As in .gitignore, a
+// pattern without a slash matches at any depth, and a pattern matching a
+// directory matches everything in it.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	p := strings.TrimSpace(pattern)
+	if p == "" {
+		return nil, fmt.Errorf("empty glob pattern")
+	}
+
+	p = strings.TrimSuffix(p, "/")
+	if !strings.Contains(p, "/") {
+		p = "**/" + p
+	}
+	p = strings.TrimPrefix(p, "/")
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; {
+		case c == '*' && strings.HasPrefix(p[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case c == '*' && strings.HasPrefix(p[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("(?:/.*)?$")
+
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+// matchAny reports whether p matches any of the patterns
+func matchAny(patterns []*regexp.Regexp, p string) bool {
+	for _, re := range patterns {
+		if re.MatchString(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// gitAttributes holds the linguist-generated and linguist-vendored markings
+// of a repository's .gitattributes files
+type gitAttributes struct {
+	rules []attributeRule
+}
+
+// attributeRule is one line of a .gitattributes file. A nil marking is left
+// as set by earlier lines.
+type attributeRule struct {
+	dir       string // directory of the .gitattributes file, "" or ending in /
+	pattern   *regexp.Regexp
+	generated *bool
+	vendored  *bool
+}
+
+// parseGitAttributes parses the .gitattributes files of a repository, keyed
+// by path. Files in deeper directories take precedence, as in git.
+func parseGitAttributes(files map[string]string) gitAttributes {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		di, dj := strings.Count(paths[i], "/"), strings.Count(paths[j], "/")
+		if di != dj {
+			return di < dj
+		}
+		return paths[i] < paths[j]
+	})
+
+	var attrs gitAttributes
+	for _, p := range paths {
+		dir := path.Dir(p) + "/"
+		if dir == "./" {
+			dir = ""
+		}
+
+		for _, line := range strings.Split(files[p], "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+
+			re, err := compileGlob(fields[0])
+			if err != nil {
+				continue
+			}
+
+			rule := attributeRule{dir: dir, pattern: re}
+			for _, attr := range fields[1:] {
+				switch name, value := parseAttribute(attr); name {
+				case "linguist-generated":
+					rule.generated = &value
+				case "linguist-vendored":
+					rule.vendored = &value
+				}
+			}
+			if rule.generated != nil || rule.vendored != nil {
+				attrs.rules = append(attrs.rules, rule)
+			}
+		}
+	}
+
+	return attrs
+}
+
+// parseAttribute parses a single attribute: name and name=true set it,
+// -name, !name and name=false unset it
+func parseAttribute(attr string) (string, bool) {
+	if strings.HasPrefix(attr, "-") || strings.HasPrefix(attr, "!") {
+		return attr[1:], false
+	}
+	if i := strings.Index(attr, "="); i >= 0 {
+		return attr[:i], attr[i+1:] == "true"
+	}
+	return attr, true
+}
+
+// excluded reports whether a file is marked as generated or vendored
+func (a gitAttributes) excluded(p string) bool {
+	var generated, vendored bool
+	for _, rule := range a.rules {
+		if !strings.HasPrefix(p, rule.dir) || !rule.pattern.MatchString(p[len(rule.dir):]) {
+			continue
+		}
+		if rule.generated != nil {
+			generated = *rule.generated
+		}
+		if rule.vendored != nil {
+			vendored = *rule.vendored
+		}
+	}
+	return generated || vendored
+}
@@ -0,0 +1,123 @@
+package sync
+
+import (
+	"testing"
+)
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"docs/**", "docs/guide.md", true},
+		{"docs/**", "docs/api/intro.md", true},
+		{"docs/**", "src/docs/guide.md", false},
+		{"docs", "docs/guide.md", true},
+		{"docs/", "docs/api/intro.md", true},
+		{"**/vendor/**", "vendor/lib.go", true},
+		{"**/vendor/**", "pkg/vendor/lib/x.go", true},
+		{"**/vendor/**", "vendors/lib.go", false},
+		{"*.md", "README.md", true},
+		{"*.md", "docs/api/intro.md", true},
+		{"docs/*.md", "docs/guide.md", true},
+		{"docs/*.md", "docs/api/intro.md", false},
+		{"/README.md", "README.md", true},
+		{"file?.txt", "file1.txt", true},
+		{"file?.txt", "file10.txt", false},
+		{"a+b.md", "a+b.md", true},
+	}
+
+	for _, tt := range tests {
+		re, err := compileGlob(tt.pattern)
+		if err != nil {
+			t.Fatalf("compileGlob(%q) error = %v", tt.pattern, err)
+		}
+		if got := re.MatchString(tt.path); got != tt.want {
+			t.Errorf("%q matching %q = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseGitHubFilters(t *testing.T) {
+	f, err := parseGitHubFilters(map[string]interface{}{
+		"branch":        "release",
+		"include":       []interface{}{"docs/**", "!**/drafts/**"},
+		"exclude":       "*.tmp.md",
+		"max_file_size": float64(1024),
+	})
+	if err != nil {
+		t.Fatalf("parseGitHubFilters() error = %v", err)
+	}
+
+	if f.ref != "release" || f.maxFileSize != 1024 {
+		t.Errorf("Unexpected filters: %+v", f)
+	}
+
+	for p, want := range map[string]bool{
+		"docs/guide.md":      true,
+		"docs/drafts/new.md": false,
+		"docs/notes.tmp.md":  false,
+		"src/main.go":        false,
+	} {
+		if got := f.matches(p); got != want {
+			t.Errorf("matches(%q) = %v, want %v", p, got, want)
+		}
+	}
+
+	defaults, err := parseGitHubFilters(nil)
+	if err != nil || defaults.ref != "" || defaults.maxFileSize != defaultMaxFileSize || !defaults.matches("any/file.md") {
+		t.Errorf("Unexpected default filters: %+v, %v", defaults, err)
+	}
+}
+
+func TestParseGitHubFilters_Invalid(t *testing.T) {
+	tests := []map[string]interface{}{
+		{"branch": "main", "tag": "v1.0"},
+		{"branch": 1},
+		{"include": []interface{}{"docs/**", 2}},
+		{"include": []interface{}{" "}},
+		{"max_file_size": "1MB"},
+		{"max_file_size": float64(-1)},
+		{"issues": "yes"},
+		{"state": "merged"},
+	}
+
+	for _, filters := range tests {
+		if _, err := parseGitHubFilters(filters); err == nil {
+			t.Errorf("Expected error for %v", filters)
+		}
+	}
+}
+
+func TestGitAttributes(t *testing.T) {
+	attrs := parseGitAttributes(map[string]string{
+		".gitattributes": `# Generated code
+*.pb.go linguist-generated
+third_party/** linguist-vendored=true
+docs/api.md linguist-generated
+`,
+		"docs/.gitattributes": "api.md -linguist-generated\n",
+	})
+
+	for p, want := range map[string]bool{
+		"pkg/service.pb.go":    true,
+		"pkg/service.go":       false,
+		"third_party/lib/x.md": true,
+		"docs/api.md":          false, // unset by the deeper file
+		"README.md":            false,
+	} {
+		if got := attrs.excluded(p); got != want {
+			t.Errorf("excluded(%q) = %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	if isBinary([]byte("# Readme\n")) {
+		t.Error("Expected text to not be binary")
+	}
+	if !isBinary([]byte("\x89PNG\r\n\x1a\n\x00\x00")) {
+		t.Error("Expected PNG to be binary")
+	}
+}
@@ -0,0 +1,357 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/Abraham12611/veritas/internal/logger"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+const (
+	issueDocumentType       = "github_issue"
+	pullRequestDocumentType = "github_pull_request"
+
+	// issueSinceKey is the checkpoint entry holding the updated_at cursor
+	// for listing issues. Issue entries are keyed by issueExternalID.
+	issueSinceKey = "#since"
+)
+
+// issueExternalID returns the external ID of an issue or pull request, e.g.
+// "#42". Issues and pull requests share a sequence of numbers. A file with
+// such a path has no extension, so it is only synced if an include pattern
+// asks for it.
+func issueExternalID(number int) string {
+	return "#" + strconv.Itoa(number)
+}
+
+// issueComment is an issue comment or pull request review comment
+type issueComment struct {
+	author    string
+	path      string // File a review comment is on
+	body      string
+	createdAt time.Time
+}
+
+// SyncIssues syncs a repository's issues and pull requests, if enabled in
+// the filters. Only those updated since the previous sync are fetched; the
+// others are kept. The returned checkpoint maps the external ID of every
+// issue and pull request synced or kept to its updated_at, plus the cursor
+// for the next sync.
+func (s *GitHubService) SyncIssues(ctx context.Context, ds *models.DataSource, checkpoint Checkpoint) (Checkpoint, error) {
+	owner, repo, err := repositoryName(ds.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	filters, err := parseGitHubFilters(ds.Config.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	if !filters.issues && !filters.pullRequests {
+		return Checkpoint{}, nil
+	}
+
+	var since time.Time
+	if v, ok := checkpoint[issueSinceKey]; ok {
+		if since, err = time.Parse(time.RFC3339, v); err != nil {
+			logger.Error("Ignoring invalid issue cursor", err, logger.Fields{
+				"dataSourceId": ds.ID,
+			})
+		}
+	}
+
+	// Everything updated since the cursor is listed regardless of state and
+	// labels, so issues that stop matching the filters are dropped
+	opts := &github.IssueListByRepoOptions{
+		State:       "all",
+		Sort:        "updated",
+		Direction:   "asc",
+		Since:       since,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+
+	next := make(Checkpoint)
+	listed := make(map[string]bool)
+	cursor := since
+	failed := false
+	counts := make(map[string]int)
+
+	for {
+		var issues []*github.Issue
+		var resp *github.Response
+		err := s.withRetry(ctx, func() error {
+			var err error
+			issues, resp, err = s.client.Issues.ListByRepo(ctx, owner, repo, opts)
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list issues: %w", err)
+		}
+
+		for _, issue := range issues {
+			externalID := issueExternalID(issue.GetNumber())
+			updatedAt := issue.GetUpdatedAt().UTC().Format(time.RFC3339)
+			listed[externalID] = true
+
+			// The cursor stops at the first failure, so it is listed again
+			if !failed {
+				cursor = issue.GetUpdatedAt().Time
+			}
+
+			if !filters.matchesIssue(issue) {
+				counts["filtered"]++
+				continue
+			}
+
+			if checkpoint[externalID] == updatedAt {
+				s.sink.KeepDocument(externalID)
+				next[externalID] = updatedAt
+				counts["unchanged"]++
+				continue
+			}
+
+			input, err := s.issueDocument(ctx, ds, owner, repo, issue)
+			ingested := err == nil
+			if ingested {
+				_, err = s.sink.IngestDocument(ctx, input)
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if err != nil {
+				logger.Error("Failed to sync issue", err, logger.Fields{
+					"dataSourceId": ds.ID,
+					"number":       issue.GetNumber(),
+				})
+				failed = true
+				counts["failed"]++
+
+				// Keep the previous version until it is synced again. A failed
+				// ingest has already marked the document as seen.
+				if previous, ok := checkpoint[externalID]; ok {
+					if !ingested {
+						s.sink.KeepDocument(externalID)
+					}
+					next[externalID] = previous
+				}
+				continue
+			}
+			next[externalID] = updatedAt
+			counts["synced"]++
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	// Issues not updated since the last sync are unchanged
+	for externalID, updatedAt := range checkpoint {
+		if externalID == issueSinceKey || !strings.HasPrefix(externalID, "#") || listed[externalID] {
+			continue
+		}
+		s.sink.KeepDocument(externalID)
+		next[externalID] = updatedAt
+	}
+
+	if !cursor.IsZero() {
+		next[issueSinceKey] = cursor.UTC().Format(time.RFC3339)
+	}
+
+	logger.Info("Synced GitHub issues", logger.Fields{
+		"dataSourceId": ds.ID,
+		"repository":   owner + "/" + repo,
+		"counts":       counts,
+	})
+
+	return next, nil
+}
+
+// matchesIssue reports whether an issue or pull request passes the filters
+func (f *githubFilters) matchesIssue(issue *github.Issue) bool {
+	if issue.IsPullRequest() && !f.pullRequests || !issue.IsPullRequest() && !f.issues {
+		return false
+	}
+	if f.state != "all" && issue.GetState() != f.state {
+		return false
+	}
+	if len(f.labels) == 0 {
+		return true
+	}
+	for _, label := range issue.Labels {
+		for _, want := range f.labels {
+			if strings.EqualFold(label.GetName(), want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// syncIssue fetches the comments of an issue or pull request and ingests it
+func (s *GitHubService) syncIssue(ctx context.Context, ds *models.DataSource, owner, repo string, issue *github.Issue) error {
+	input, err := s.issueDocument(ctx, ds, owner, repo, issue)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.sink.IngestDocument(ctx, input)
+	return err
+}
+
+// issueDocument fetches the comments of an issue or pull request and builds
+// its document
+func (s *GitHubService) issueDocument(ctx context.Context, ds *models.DataSource, owner, repo string, issue *github.Issue) (models.CreateDocumentInput, error) {
+	comments, err := s.getIssueComments(ctx, owner, repo, issue)
+	if err != nil {
+		return models.CreateDocumentInput{}, fmt.Errorf("failed to get comments: %w", err)
+	}
+
+	docType, category, sourcePath := issueDocumentType, "issue", "issues/"
+	if issue.IsPullRequest() {
+		docType, category, sourcePath = pullRequestDocumentType, "pull_request", "pull/"
+	}
+
+	var labels []string
+	for _, label := range issue.Labels {
+		labels = append(labels, label.GetName())
+	}
+
+	input := models.CreateDocumentInput{
+		InstanceID:   ds.InstanceID,
+		DataSourceID: ds.ID,
+		Title:        truncateTitle(fmt.Sprintf("#%d %s", issue.GetNumber(), issue.GetTitle())),
+		Content:      formatIssue(issue, labels, comments),
+		URL:          issue.GetHTMLURL(),
+		Type:         docType,
+		Metadata: models.Metadata{
+			Author:      issue.GetUser().GetLogin(),
+			LastUpdated: issue.GetUpdatedAt().Time,
+			Tags:        labels,
+			Category:    category,
+			ExternalID:  issueExternalID(issue.GetNumber()),
+			SourcePath:  sourcePath + strconv.Itoa(issue.GetNumber()),
+			Extra: map[string]interface{}{
+				"number":   issue.GetNumber(),
+				"state":    issue.GetState(),
+				"comments": len(comments),
+			},
+		},
+	}
+	if issue.ClosedAt != nil {
+		input.Metadata.Extra["closed_at"] = issue.GetClosedAt().Time
+	}
+
+	return input, nil
+}
+
+// getIssueComments returns the comments of an issue, or the comments and
+// review comments of a pull request, oldest first
+func (s *GitHubService) getIssueComments(ctx context.Context, owner, repo string, issue *github.Issue) ([]issueComment, error) {
+	var comments []issueComment
+
+	if issue.GetComments() > 0 {
+		opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+		for {
+			var page []*github.IssueComment
+			var resp *github.Response
+			err := s.withRetry(ctx, func() error {
+				var err error
+				page, resp, err = s.client.Issues.ListComments(ctx, owner, repo, issue.GetNumber(), opts)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			for _, c := range page {
+				comments = append(comments, issueComment{
+					author:    c.GetUser().GetLogin(),
+					body:      c.GetBody(),
+					createdAt: c.GetCreatedAt().Time,
+				})
+			}
+
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+	}
+
+	// The issue's comment count doesn't include review comments
+	if issue.IsPullRequest() {
+		opts := &github.PullRequestListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+		for {
+			var page []*github.PullRequestComment
+			var resp *github.Response
+			err := s.withRetry(ctx, func() error {
+				var err error
+				page, resp, err = s.client.PullRequests.ListComments(ctx, owner, repo, issue.GetNumber(), opts)
+				return err
+			})
+			if err != nil {
+				return nil, err
+			}
+
+			for _, c := range page {
+				comments = append(comments, issueComment{
+					author:    c.GetUser().GetLogin(),
+					path:      c.GetPath(),
+					body:      c.GetBody(),
+					createdAt: c.GetCreatedAt().Time,
+				})
+			}
+
+			if resp.NextPage == 0 {
+				break
+			}
+			opts.Page = resp.NextPage
+		}
+	}
+
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].createdAt.Before(comments[j].createdAt)
+	})
+	return comments, nil
+}
+
+// formatIssue renders an issue or pull request and its comments as markdown
+func formatIssue(issue *github.Issue, labels []string, comments []issueComment) string {
+	kind := "Issue"
+	if issue.IsPullRequest() {
+		kind = "Pull request"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", issue.GetTitle())
+	fmt.Fprintf(&sb, "%s #%d, %s, opened by @%s on %s\n",
+		kind, issue.GetNumber(), issue.GetState(), issue.GetUser().GetLogin(),
+		issue.GetCreatedAt().Format("2006-01-02"))
+	if len(labels) > 0 {
+		fmt.Fprintf(&sb, "Labels: %s\n", strings.Join(labels, ", "))
+	}
+
+	if body := strings.TrimSpace(issue.GetBody()); body != "" {
+		fmt.Fprintf(&sb, "\n%s\n", body)
+	}
+
+	for _, c := range comments {
+		sb.WriteString("\n---\n\n")
+		if c.path != "" {
+			fmt.Fprintf(&sb, "@%s commented on %s on %s:\n\n", c.author, c.path, c.createdAt.Format("2006-01-02"))
+		} else {
+			fmt.Fprintf(&sb, "@%s commented on %s:\n\n", c.author, c.createdAt.Format("2006-01-02"))
+		}
+		fmt.Fprintf(&sb, "%s\n", strings.TrimSpace(c.body))
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,193 @@
+package sync
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+func TestGitHubService_SyncIssues(t *testing.T) {
+	var since string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/org/repo/issues":
+			since = r.URL.Query().Get("since")
+			if since == "" {
+				w.Write([]byte(`[
+					{"number": 1, "title": "Crash on start", "state": "closed", "body": "It crashes.", "comments": 1,
+					 "user": {"login": "alice"}, "labels": [{"name": "bug"}],
+					 "html_url": "https://github.com/org/repo/issues/1",
+					 "created_at": "2024-01-01T00:00:00Z", "updated_at": "2024-01-02T00:00:00Z"},
+					{"number": 2, "title": "Fix crash", "state": "closed", "body": "Fixes #1", "comments": 0,
+					 "user": {"login": "bob"}, "labels": [{"name": "Bug"}], "pull_request": {"url": "x"},
+					 "html_url": "https://github.com/org/repo/pull/2",
+					 "created_at": "2024-01-02T00:00:00Z", "updated_at": "2024-01-03T00:00:00Z"},
+					{"number": 3, "title": "Roadmap", "state": "open", "comments": 0,
+					 "user": {"login": "carol"}, "labels": [{"name": "planning"}],
+					 "created_at": "2024-01-03T00:00:00Z", "updated_at": "2024-01-04T00:00:00Z"}
+				]`))
+				return
+			}
+			// Issue 1 lost its label, issue 2 is unchanged
+			w.Write([]byte(`[
+				{"number": 2, "title": "Fix crash", "state": "closed", "comments": 0,
+				 "user": {"login": "bob"}, "labels": [{"name": "Bug"}], "pull_request": {"url": "x"},
+				 "created_at": "2024-01-02T00:00:00Z", "updated_at": "2024-01-03T00:00:00Z"},
+				{"number": 1, "title": "Crash on start", "state": "closed", "comments": 1,
+				 "user": {"login": "alice"}, "labels": [],
+				 "created_at": "2024-01-01T00:00:00Z", "updated_at": "2024-01-05T00:00:00Z"}
+			]`))
+		case "/repos/org/repo/issues/1/comments":
+			w.Write([]byte(`[{"user": {"login": "bob"}, "body": "Can't reproduce.", "created_at": "2024-01-01T12:00:00Z"}]`))
+		case "/repos/org/repo/pulls/2/comments":
+			w.Write([]byte(`[{"user": {"login": "alice"}, "path": "main.go", "body": "Needs a test.", "created_at": "2024-01-02T12:00:00Z"}]`))
+		default:
+			t.Errorf("Unexpected request: %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}
+
+	sink := newMockSink()
+	service := newTestGitHubService(t, handler, sink)
+
+	ds := &models.DataSource{ID: uuid.New(), Config: models.Config{
+		Repository: "org/repo",
+		Filters: map[string]interface{}{
+			"issues":        true,
+			"pull_requests": true,
+			"labels":        []interface{}{"bug"},
+		},
+	}}
+
+	next, err := service.SyncIssues(context.Background(), ds, nil)
+	if err != nil {
+		t.Fatalf("SyncIssues() error = %v", err)
+	}
+
+	docs := sink.ingested()
+	if len(docs) != 2 {
+		t.Fatalf("Expected 2 documents, got %d", len(docs))
+	}
+
+	issue, pr := docs[0], docs[1]
+	if issue.Type != issueDocumentType || issue.Metadata.ExternalID != "#1" || issue.Title != "#1 Crash on start" {
+		t.Errorf("Unexpected issue document: %+v", issue)
+	}
+	if !strings.Contains(issue.Content, "It crashes.") || !strings.Contains(issue.Content, "@bob commented on 2024-01-01:\n\nCan't reproduce.") {
+		t.Errorf("Expected body and comments in content, got:\n%s", issue.Content)
+	}
+	if pr.Type != pullRequestDocumentType || pr.Metadata.Category != "pull_request" {
+		t.Errorf("Unexpected pull request document: %+v", pr)
+	}
+	if !strings.Contains(pr.Content, "@alice commented on main.go") {
+		t.Errorf("Expected review comment in content, got:\n%s", pr.Content)
+	}
+
+	if next[issueSinceKey] != "2024-01-04T00:00:00Z" {
+		t.Errorf("Expected cursor at the last update, got %q", next[issueSinceKey])
+	}
+	if _, ok := next["#3"]; ok {
+		t.Error("Expected filtered issue to be left out of the checkpoint")
+	}
+
+	// The next sync only lists updates, and drops issue 1 as it no longer
+	// has the label
+	sink = newMockSink()
+	service.sink = sink
+
+	next, err = service.SyncIssues(context.Background(), ds, next)
+	if err != nil {
+		t.Fatalf("SyncIssues() error = %v", err)
+	}
+
+	if since != "2024-01-04T00:00:00Z" {
+		t.Errorf("Expected issues listed since the cursor, got %q", since)
+	}
+	if len(sink.ingested()) != 0 {
+		t.Errorf("Expected no documents ingested, got %d", len(sink.ingested()))
+	}
+
+	kept := sink.keptIDs()
+	sort.Strings(kept)
+	if strings.Join(kept, ",") != "#2" {
+		t.Errorf("Expected only #2 kept, got %v", kept)
+	}
+	if _, ok := next["#1"]; ok {
+		t.Error("Expected unlabelled issue to be dropped from the checkpoint")
+	}
+}
+
+func TestGitHubService_SyncIssuesFailures(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/org/repo/issues":
+			w.Write([]byte(`[
+				{"number": 1, "title": "` + strings.Repeat("Ü", 300) + `", "state": "open", "comments": 0,
+				 "user": {"login": "alice"}, "updated_at": "2024-01-05T00:00:00Z"},
+				{"number": 2, "title": "Flaky test", "state": "open", "comments": 1,
+				 "user": {"login": "bob"}, "updated_at": "2024-01-05T00:00:00Z"}
+			]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}
+
+	sink := newMockSink()
+	sink.shouldFail = true
+	service := newTestGitHubService(t, handler, sink)
+
+	ds := &models.DataSource{ID: uuid.New(), Config: models.Config{
+		Repository: "org/repo",
+		Filters:    map[string]interface{}{"issues": true},
+	}}
+	checkpoint := Checkpoint{issueSinceKey: "2024-01-01T00:00:00Z", "#1": "2024-01-01T00:00:00Z", "#2": "2024-01-01T00:00:00Z"}
+
+	next, err := service.SyncIssues(context.Background(), ds, checkpoint)
+	if err != nil {
+		t.Fatalf("SyncIssues() error = %v", err)
+	}
+
+	// Both keep their previous versions, but only the issue whose comments
+	// couldn't be fetched is kept; the failed ingest already counts for #1
+	if next["#1"] != checkpoint["#1"] || next["#2"] != checkpoint["#2"] {
+		t.Errorf("Expected previous versions in checkpoint, got %v", next)
+	}
+	if kept := sink.keptIDs(); len(kept) != 1 || kept[0] != "#2" {
+		t.Errorf("Expected only #2 kept, got %v", kept)
+	}
+}
+
+func TestTruncateTitle(t *testing.T) {
+	if got := truncateTitle("#1 Short"); got != "#1 Short" {
+		t.Errorf("truncateTitle() = %q, want it unchanged", got)
+	}
+
+	long := "#1 " + strings.Repeat("Ü", 300)
+	got := truncateTitle(long)
+	if utf8.RuneCountInString(got) != maxTitleLength || !strings.HasPrefix(long, got) {
+		t.Errorf("Expected the first %d characters, got %d", maxTitleLength, utf8.RuneCountInString(got))
+	}
+}
+
+func TestGitHubService_SyncIssuesDisabled(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Unexpected request: %s", r.URL.Path)
+	}
+
+	service := newTestGitHubService(t, handler, newMockSink())
+	ds := &models.DataSource{ID: uuid.New(), Config: models.Config{Repository: "org/repo"}}
+
+	next, err := service.SyncIssues(context.Background(), ds, Checkpoint{"README.md": "sha"})
+	if err != nil {
+		t.Fatalf("SyncIssues() error = %v", err)
+	}
+	if len(next) != 0 {
+		t.Errorf("Expected empty checkpoint, got %v", next)
+	}
+}
@@ -1,13 +1,18 @@
 package sync
 
 import (
+	"bytes"
 	"context"
-	"encoding/base64"
+	"errors"
 	"fmt"
+	"net/url"
+	"path"
 	"strings"
 	"time"
 
 	"github.com/google/go-github/v57/github"
+	"github.com/Abraham12611/veritas/internal/httpx"
+	"github.com/Abraham12611/veritas/internal/logger"
 	"github.com/Abraham12611/veritas/internal/models"
 	"golang.org/x/oauth2"
 	"golang.org/x/time/rate"
@@ -18,217 +23,433 @@ type GitHubService struct {
 	client *github.Client
 	limiter *rate.Limiter
 	maxRetries int
+	sink DocumentSink
+	accessToken string
+	gitURL string // Where wikis are cloned from
 }
 
 // NewGitHubService creates a new GitHub sync service
-func NewGitHubService(accessToken string) *GitHubService {
+func NewGitHubService(accessToken string, sink DocumentSink) *GitHubService {
 	ctx := context.Background()
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: accessToken},
 	)
 	tc := oauth2.NewClient(ctx, ts)
 
-	// Create rate limiter: 5000 requests per hour (GitHub's limit)
-	// This equals approximately 1.4 requests per second
-	limiter := rate.NewLimiter(rate.Every(time.Second/1.4), 1)
+	// Rate limiter shared by all syncs with the same token: 5000 requests
+	// per hour (GitHub's limit), approximately 1.4 requests per second
+	limiter := httpx.SharedLimiter("github", accessToken, rate.Every(time.Hour/5000), 1)
 
 	return &GitHubService{
 		client: github.NewClient(tc),
 		limiter: limiter,
 		maxRetries: 3,
+		sink: sink,
+		accessToken: accessToken,
+		gitURL: "https://github.com",
 	}
 }
 
 // withRetry executes a function with retries and rate limiting
 func (s *GitHubService) withRetry(ctx context.Context, operation func() error) error {
-	var lastErr error
-	for attempt := 0; attempt <= s.maxRetries; attempt++ {
-		// Wait for rate limiter
-		if err := s.limiter.Wait(ctx); err != nil {
-			return fmt.Errorf("rate limiter error: %w", err)
-		}
+	return httpx.Retry(ctx, s.limiter, httpx.RetryPolicy{MaxRetries: s.maxRetries}, func() error {
+		return githubError(operation())
+	})
+}
 
-		// Execute operation
-		if err := operation(); err != nil {
-			lastErr = err
-			// Check if error is retryable
-			if isRetryableError(err) {
-				// Exponential backoff
-				backoff := time.Duration(attempt*attempt) * time.Second
-				select {
-				case <-time.After(backoff):
-					continue
-				case <-ctx.Done():
-					return ctx.Err()
-				}
-			}
-			return err // Non-retryable error
+// githubError converts go-github's HTTP errors to *httpx.StatusError so they
+// are retried like those of the other connectors. The original error is
+// kept and can still be matched with errors.As.
+func githubError(err error) error {
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	var respErr *github.ErrorResponse
+
+	switch {
+	case errors.As(err, &rateErr) && rateErr.Response != nil:
+		// go-github may return this without sending the request, so the
+		// reset time comes from the error rather than the headers
+		statusErr := httpx.NewStatusError(rateErr.Response, err)
+		statusErr.RetryAfter = time.Until(rateErr.Rate.Reset.Time)
+		if statusErr.RetryAfter < time.Second {
+			statusErr.RetryAfter = time.Second
+		}
+		return statusErr
+	case errors.As(err, &abuseErr) && abuseErr.Response != nil:
+		// GitHub asks to wait at least a minute if it doesn't say how long
+		statusErr := httpx.NewStatusError(abuseErr.Response, err)
+		if statusErr.RetryAfter == 0 {
+			statusErr.RetryAfter = time.Minute
 		}
-		return nil // Success
+		return statusErr
+	case errors.As(err, &respErr) && respErr.Response != nil:
+		return httpx.NewStatusError(respErr.Response, err)
 	}
-	return fmt.Errorf("max retries exceeded: %w", lastErr)
+
+	return err
 }
 
-// isRetryableError determines if an error should trigger a retry
-func isRetryableError(err error) bool {
-	if err == nil {
-		return false
+// githubConnector adapts GitHubService to the Connector interface
+type githubConnector struct{}
+
+func init() {
+	Register(githubConnector{})
+}
+
+func (githubConnector) Type() string {
+	return "github"
+}
+
+func (githubConnector) Validate(cfg models.Config) error {
+	r := requiredFields{dsType: "github"}
+	r.require("access_token", cfg.AccessToken)
+	r.require("repository", cfg.Repository)
+	if err := r.err(); err != nil {
+		return err
 	}
-	
-	// Check for rate limit errors
-	if _, ok := err.(*github.RateLimitError); ok {
-		return true
+
+	if _, _, err := repositoryName(cfg); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
 	}
-	
-	// Check for temporary GitHub errors
-	if _, ok := err.(*github.AbuseRateLimitError); ok {
-		return true
+	if _, err := parseGitHubFilters(cfg.Filters); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
 	}
+	return nil
+}
+
+func (githubConnector) TestConnection(ctx context.Context, ds *models.DataSource) error {
+	s := NewGitHubService(ds.Config.AccessToken, nil)
+	s.maxRetries = 0
+	return s.TestConnection(ctx, ds)
+}
 
-	// Check for network-related errors
-	if strings.Contains(err.Error(), "connection refused") ||
-		strings.Contains(err.Error(), "timeout") ||
-		strings.Contains(err.Error(), "temporary failure") {
-		return true
+func (githubConnector) Sync(ctx context.Context, ds *models.DataSource, sink DocumentSink, checkpoint Checkpoint) (*SyncResult, error) {
+	s := NewGitHubService(ds.Config.AccessToken, sink)
+
+	// Files, issues and wiki pages have distinct external IDs, so their
+	// checkpoints are merged into one
+	next := make(Checkpoint)
+	for _, syncPart := range []func(context.Context, *models.DataSource, Checkpoint) (Checkpoint, error){
+		s.SyncRepository,
+		s.SyncIssues,
+		s.SyncWiki,
+	} {
+		part, err := syncPart(ctx, ds, checkpoint)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range part {
+			next[key] = value
+		}
 	}
 
-	return false
+	return &SyncResult{Checkpoint: next, Complete: true}, nil
 }
 
-// SyncRepository syncs content from a GitHub repository
-func (s *GitHubService) SyncRepository(ctx context.Context, ds *models.DataSource) error {
-	// Extract repository information from config
-	owner := ds.Config.Organization
+// repositoryName returns the owner and name of the configured repository.
+// Repository is either "owner/repo", or just the name with the owner in
+// Organization.
+func repositoryName(cfg models.Config) (string, string, error) {
+	owner := cfg.Organization
 	if owner == "" {
-		parts := strings.Split(ds.Config.Repository, "/")
-		if len(parts) != 2 {
-			return fmt.Errorf("invalid repository format: %s", ds.Config.Repository)
+		parts := strings.Split(cfg.Repository, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return "", "", fmt.Errorf("invalid repository format: %s", cfg.Repository)
 		}
 		owner = parts[0]
 	}
-	repo := strings.TrimPrefix(ds.Config.Repository, owner+"/")
+	repo := strings.TrimPrefix(cfg.Repository, owner+"/")
 
-	var contents []*github.RepositoryContent
-	err := s.withRetry(ctx, func() error {
-		_, c, _, err := s.client.Repositories.GetContents(ctx, owner, repo, "", nil)
-		if err != nil {
-			return err
-		}
-		contents = c
-		return nil
-	})
+	return owner, repo, nil
+}
+
+// TestConnection checks that the repository can be read with the token
+func (s *GitHubService) TestConnection(ctx context.Context, ds *models.DataSource) error {
+	owner, repo, err := repositoryName(ds.Config)
 	if err != nil {
-		return fmt.Errorf("failed to get repository contents: %w", err)
+		return err
 	}
 
-	// Process repository contents
-	for _, content := range contents {
-		if err := s.processContent(ctx, ds, owner, repo, content); err != nil {
-			return fmt.Errorf("failed to process content %s: %w", *content.Path, err)
-		}
+	err = s.withRetry(ctx, func() error {
+		_, _, err := s.client.Repositories.Get(ctx, owner, repo)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get repository: %w", err)
 	}
 
 	return nil
 }
 
-// processContent processes a single file or directory from GitHub
-func (s *GitHubService) processContent(ctx context.Context, ds *models.DataSource, owner, repo string, content *github.RepositoryContent) error {
-	// Skip if content is nil or has no path
-	if content == nil || content.Path == nil {
-		return nil
+// SyncRepository syncs the files of a GitHub repository's default branch,
+// or the branch or tag set in the filters. It lists the whole tree in a
+// single call and only downloads the files whose blob SHA differs from the
+// one in checkpoint; unchanged files are kept. The returned checkpoint maps
+// the path of every file ingested, kept or skipped as binary to its blob SHA.
+func (s *GitHubService) SyncRepository(ctx context.Context, ds *models.DataSource, checkpoint Checkpoint) (Checkpoint, error) {
+	// Extract repository information from config
+	owner, repo, err := repositoryName(ds.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	filters, err := parseGitHubFilters(ds.Config.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	var repository *github.Repository
+	err = s.withRetry(ctx, func() error {
+		r, _, err := s.client.Repositories.Get(ctx, owner, repo)
+		repository = r
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repository: %w", err)
+	}
+	ref := filters.ref
+	if ref == "" {
+		ref = repository.GetDefaultBranch()
 	}
 
-	// Process directory
-	if content.Type != nil && *content.Type == "dir" {
-		return s.processDirectory(ctx, ds, owner, repo, *content.Path)
+	// Pin the sync to one commit, so the tree and blobs are consistent
+	var commitSHA string
+	err = s.withRetry(ctx, func() error {
+		sha, _, err := s.client.Repositories.GetCommitSHA1(ctx, owner, repo, ref, "")
+		commitSHA = sha
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve ref %s: %w", ref, err)
 	}
 
-	// Skip files we don't want to process
-	if !s.shouldProcessFile(*content.Path) {
-		return nil
+	files, err := s.listTree(ctx, owner, repo, commitSHA, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list repository files: %w", err)
 	}
 
-	// Get file content with retries
-	fileContent, err := s.getFileContent(ctx, owner, repo, *content.Path)
+	attributes, err := s.getAttributes(ctx, owner, repo, files)
 	if err != nil {
-		return fmt.Errorf("failed to get file content: %w", err)
+		return nil, fmt.Errorf("failed to get .gitattributes: %w", err)
 	}
 
-	// Create document input
-	input := models.CreateDocumentInput{
-		InstanceID:   ds.InstanceID,
-		DataSourceID: ds.ID,
-		Title:        *content.Path,
-		Content:      fileContent,
-		URL:         *content.HTMLURL,
-		Type:        s.getDocumentType(*content.Path),
-		Metadata: models.Metadata{
-			SourcePath: *content.Path,
-			ExternalID: *content.SHA,
-			Extra: map[string]interface{}{
-				"size":         content.Size,
-				"download_url": content.DownloadURL,
+	logger.Info("Listed GitHub repository files", logger.Fields{
+		"dataSourceId": ds.ID,
+		"repository":   owner + "/" + repo,
+		"ref":          ref,
+		"commit":       commitSHA,
+		"files":        len(files),
+	})
+
+	next := make(Checkpoint)
+	skipped := make(map[string]int)
+	for _, file := range files {
+		path, sha := file.GetPath(), file.GetSHA()
+
+		// Skip files we don't want to process
+		if reason := s.skipReason(filters, attributes, file); reason != "" {
+			skipped[reason]++
+			continue
+		}
+
+		switch checkpoint[path] {
+		case sha:
+			s.sink.KeepDocument(path)
+			next[path] = sha
+			continue
+		case binaryMarker + sha:
+			skipped["binary"]++
+			next[path] = binaryMarker + sha
+			continue
+		}
+
+		content, err := s.getBlob(ctx, owner, repo, sha)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get file %s: %w", path, err)
+		}
+
+		// Include patterns can match files of any type. Binary files are
+		// remembered so they aren't downloaded again while unchanged.
+		if isBinary(content) {
+			skipped["binary"]++
+			next[path] = binaryMarker + sha
+			continue
+		}
+
+		input := models.CreateDocumentInput{
+			InstanceID:   ds.InstanceID,
+			DataSourceID: ds.ID,
+			Title:        path,
+			Content:      string(content),
+			URL:          fmt.Sprintf("%s/blob/%s/%s", repository.GetHTMLURL(), escapePath(ref), escapePath(path)),
+			Type:         s.getDocumentType(path),
+			Metadata: models.Metadata{
+				SourcePath: path,
+				ExternalID: path, // Paths are stable across commits, SHAs are not
+				Extra: map[string]interface{}{
+					"sha":    sha,
+					"size":   file.GetSize(),
+					"ref":    ref,
+				},
 			},
-		},
+		}
+
+		if _, err := s.sink.IngestDocument(ctx, input); err != nil {
+			// Report the failed file and continue with the rest of the
+			// repository. It stays out of the checkpoint, so the next sync
+			// tries it again.
+			logger.Error("Failed to ingest file", err, logger.Fields{
+				"path": path,
+			})
+			continue
+		}
+		next[path] = sha
 	}
 
-	// TODO: Call ingestion service to process the document
-	// For now, we'll just log
-	fmt.Printf("Would process document: %s\n", input.Title)
+	if len(skipped) > 0 {
+		logger.Info("Skipped GitHub repository files", logger.Fields{
+			"dataSourceId": ds.ID,
+			"skipped":      skipped,
+		})
+	}
 
-	return nil
+	return next, nil
 }
 
-// processDirectory processes a directory from GitHub
-func (s *GitHubService) processDirectory(ctx context.Context, ds *models.DataSource, owner, repo, path string) error {
-	var contents []*github.RepositoryContent
-	err := s.withRetry(ctx, func() error {
-		_, c, _, err := s.client.Repositories.GetContents(ctx, owner, repo, path, nil)
+// binaryMarker prefixes the blob SHA of binary files in the checkpoint
+const binaryMarker = "binary:"
+
+// skipReason returns why a file is left out of the sync, or "" if it is
+// synced. Without include patterns only files with known text extensions
+// are synced.
+func (s *GitHubService) skipReason(filters *githubFilters, attributes gitAttributes, file *github.TreeEntry) string {
+	path := file.GetPath()
+	switch {
+	case !filters.matches(path):
+		return "filtered"
+	case len(filters.include) == 0 && !s.shouldProcessFile(path):
+		return "unsupported"
+	case file.GetSize() > filters.maxFileSize:
+		return "too_large"
+	case attributes.excluded(path):
+		return "generated_or_vendored"
+	}
+	return ""
+}
+
+// getAttributes downloads and parses the .gitattributes files among files
+func (s *GitHubService) getAttributes(ctx context.Context, owner, repo string, files []*github.TreeEntry) (gitAttributes, error) {
+	contents := make(map[string]string)
+	for _, file := range files {
+		if path.Base(file.GetPath()) != ".gitattributes" {
+			continue
+		}
+
+		content, err := s.getBlob(ctx, owner, repo, file.GetSHA())
 		if err != nil {
-			return err
+			return gitAttributes{}, err
 		}
-		contents = c
-		return nil
-	})
+		contents[file.GetPath()] = string(content)
+	}
+
+	return parseGitAttributes(contents), nil
+}
+
+// isBinary reports whether content looks like a binary file, using git's
+// heuristic of a NUL byte near the start
+func isBinary(content []byte) bool {
+	if len(content) > 8000 {
+		content = content[:8000]
+	}
+	return bytes.IndexByte(content, 0) >= 0
+}
+
+// listTree returns the files in a tree and its subtrees, with paths prefixed
+// by prefix. GitHub truncates recursive listings of very large trees, in
+// which case the subtrees are listed separately.
+func (s *GitHubService) listTree(ctx context.Context, owner, repo, sha, prefix string) ([]*github.TreeEntry, error) {
+	tree, err := s.getTree(ctx, owner, repo, sha, true)
 	if err != nil {
-		return fmt.Errorf("failed to get directory contents: %w", err)
+		return nil, err
 	}
 
-	for _, content := range contents {
-		if err := s.processContent(ctx, ds, owner, repo, content); err != nil {
-			return err
+	if !tree.GetTruncated() {
+		var files []*github.TreeEntry
+		for _, entry := range tree.Entries {
+			if isFile(entry) {
+				files = append(files, withPathPrefix(entry, prefix))
+			}
 		}
+		return files, nil
 	}
 
-	return nil
+	tree, err = s.getTree(ctx, owner, repo, sha, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*github.TreeEntry
+	for _, entry := range tree.Entries {
+		switch {
+		case isFile(entry):
+			files = append(files, withPathPrefix(entry, prefix))
+		case entry.GetType() == "tree":
+			subtree, err := s.listTree(ctx, owner, repo, entry.GetSHA(), prefix+entry.GetPath()+"/")
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, subtree...)
+		}
+	}
+
+	return files, nil
 }
 
-// getFileContent retrieves and decodes the content of a file
-func (s *GitHubService) getFileContent(ctx context.Context, owner, repo, path string) (string, error) {
-	var content *github.RepositoryContent
+// getTree retrieves a tree, optionally with all of its subtrees
+func (s *GitHubService) getTree(ctx context.Context, owner, repo, sha string, recursive bool) (*github.Tree, error) {
+	var tree *github.Tree
 	err := s.withRetry(ctx, func() error {
-		c, _, _, err := s.client.Repositories.GetContents(ctx, owner, repo, path, nil)
-		if err != nil {
-			return err
-		}
+		t, _, err := s.client.Git.GetTree(ctx, owner, repo, sha, recursive)
+		tree = t
+		return err
+	})
+	return tree, err
+}
+
+// getBlob retrieves the raw content of a file by its blob SHA
+func (s *GitHubService) getBlob(ctx context.Context, owner, repo, sha string) ([]byte, error) {
+	var content []byte
+	err := s.withRetry(ctx, func() error {
+		c, _, err := s.client.Git.GetBlobRaw(ctx, owner, repo, sha)
 		content = c
-		return nil
+		return err
 	})
-	if err != nil {
-		return "", err
-	}
+	return content, err
+}
 
-	if content.Content == nil {
-		return "", fmt.Errorf("no content found for file: %s", path)
-	}
+// isFile reports whether a tree entry is a regular file. Symlinks are blobs
+// too, and submodules are commits.
+func isFile(entry *github.TreeEntry) bool {
+	return entry.GetType() == "blob" && entry.GetMode() != "120000"
+}
 
-	// Decode base64 content
-	decoded, err := base64.StdEncoding.DecodeString(*content.Content)
-	if err != nil {
-		return "", fmt.Errorf("failed to decode content: %w", err)
+// withPathPrefix returns a copy of a tree entry with prefix added to its path
+func withPathPrefix(entry *github.TreeEntry, prefix string) *github.TreeEntry {
+	if prefix == "" {
+		return entry
 	}
+	e := *entry
+	e.Path = github.String(prefix + entry.GetPath())
+	return &e
+}
 
-	return string(decoded), nil
+// escapePath escapes each segment of a slash-separated path for use in a URL
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
 }
 
 // shouldProcessFile determines if a file should be processed based on its extension
@@ -2,14 +2,21 @@ package sync
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/google/uuid"
+	"golang.org/x/time/rate"
 	"github.com/Abraham12611/veritas/internal/models"
 )
 
 func TestGitHubService_ShouldProcessFile(t *testing.T) {
-	service := NewGitHubService("dummy-token")
+	service := NewGitHubService("dummy-token", newMockSink())
 
 	tests := []struct {
 		name     string
@@ -37,7 +44,7 @@ func TestGitHubService_ShouldProcessFile(t *testing.T) {
 }
 
 func TestGitHubService_GetDocumentType(t *testing.T) {
-	service := NewGitHubService("dummy-token")
+	service := NewGitHubService("dummy-token", newMockSink())
 
 	tests := []struct {
 		name     string
@@ -67,22 +74,226 @@ func TestGitHubService_SyncRepository(t *testing.T) {
 	t.Skip("Skipping GitHub API test - requires valid token")
 
 	ctx := context.Background()
-	service := NewGitHubService("your-github-token")
+	service := NewGitHubService("your-github-token", newMockSink())
 
 	// Create a test data source
 	ds := &models.DataSource{
 		ID:         uuid.New(),
 		InstanceID: uuid.New(),
 		Type:       "github",
-		Config: models.DataSourceConfig{
+		Config: models.Config{
 			Repository:   "Abraham12611/veritas",
 			AccessToken: "your-github-token",
 		},
 	}
 
 	// Test sync repository
-	err := service.SyncRepository(ctx, ds)
+	_, err := service.SyncRepository(ctx, ds, nil)
 	if err != nil {
 		t.Errorf("SyncRepository() error = %v", err)
 	}
-} 
\ No newline at end of file
+} 
+
+// newTestGitHubService creates a GitHub service that talks to a mock API server
+func newTestGitHubService(t *testing.T, handler http.HandlerFunc, sink DocumentSink) *GitHubService {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	service := NewGitHubService("test-token", sink)
+	service.client.BaseURL, _ = url.Parse(server.URL + "/")
+	service.limiter = rate.NewLimiter(rate.Inf, 1)
+	return service
+}
+
+func TestGitHubService_SyncRepositoryTree(t *testing.T) {
+	var blobRequests int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/org/repo":
+			w.Write([]byte(`{"default_branch": "main", "html_url": "https://github.com/org/repo"}`))
+		case "/repos/org/repo/commits/main":
+			w.Write([]byte("commit1"))
+		case "/repos/org/repo/git/trees/commit1":
+			// Too large to list recursively
+			if r.URL.Query().Get("recursive") != "" {
+				w.Write([]byte(`{"sha": "commit1", "tree": [], "truncated": true}`))
+				return
+			}
+			w.Write([]byte(`{"sha": "commit1", "tree": [
+				{"path": "README.md", "type": "blob", "mode": "100644", "sha": "readme1", "size": 6},
+				{"path": "logo.png", "type": "blob", "mode": "100644", "sha": "logo1", "size": 100},
+				{"path": "link.md", "type": "blob", "mode": "120000", "sha": "link1"},
+				{"path": "vendor-lib", "type": "commit", "sha": "sub1"},
+				{"path": "docs", "type": "tree", "sha": "docs1"}
+			]}`))
+		case "/repos/org/repo/git/trees/docs1":
+			w.Write([]byte(`{"sha": "docs1", "tree": [
+				{"path": "guide.md", "type": "blob", "mode": "100644", "sha": "guide2", "size": 5},
+				{"path": "api", "type": "tree", "sha": "api1"},
+				{"path": "api/intro.md", "type": "blob", "mode": "100644", "sha": "intro1", "size": 5}
+			]}`))
+		case "/repos/org/repo/git/blobs/guide2", "/repos/org/repo/git/blobs/intro1":
+			atomic.AddInt32(&blobRequests, 1)
+			w.Write([]byte("Guide"))
+		default:
+			t.Errorf("Unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+
+	sink := newMockSink()
+	service := newTestGitHubService(t, handler, sink)
+
+	ds := &models.DataSource{
+		ID:         uuid.New(),
+		InstanceID: uuid.New(),
+		Type:       "github",
+		Config:     models.Config{Repository: "org/repo", AccessToken: "test-token"},
+	}
+
+	// README.md is unchanged since the last sync and guide.md has changed
+	checkpoint := Checkpoint{"README.md": "readme1", "docs/guide.md": "guide1"}
+
+	next, err := service.SyncRepository(context.Background(), ds, checkpoint)
+	if err != nil {
+		t.Fatalf("SyncRepository() error = %v", err)
+	}
+
+	if kept := sink.keptIDs(); len(kept) != 1 || kept[0] != "README.md" {
+		t.Errorf("Expected README.md to be kept, got %v", kept)
+	}
+	if n := atomic.LoadInt32(&blobRequests); n != 2 {
+		t.Errorf("Expected 2 blob requests, got %d", n)
+	}
+
+	var paths []string
+	for _, doc := range sink.ingested() {
+		paths = append(paths, doc.Metadata.ExternalID)
+	}
+	sort.Strings(paths)
+	if strings.Join(paths, ",") != "docs/api/intro.md,docs/guide.md" {
+		t.Errorf("Unexpected ingested files: %v", paths)
+	}
+
+	for _, doc := range sink.ingested() {
+		if doc.Metadata.ExternalID == "docs/guide.md" && doc.URL != "https://github.com/org/repo/blob/main/docs/guide.md" {
+			t.Errorf("Unexpected URL: %s", doc.URL)
+		}
+	}
+
+	want := Checkpoint{"README.md": "readme1", "docs/guide.md": "guide2", "docs/api/intro.md": "intro1"}
+	if len(next) != len(want) {
+		t.Fatalf("Expected checkpoint %v, got %v", want, next)
+	}
+	for path, sha := range want {
+		if next[path] != sha {
+			t.Errorf("Expected checkpoint %s = %s, got %s", path, sha, next[path])
+		}
+	}
+}
+
+func TestGitHubService_SyncRepositoryIngestFailure(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/repos/org/repo":
+			w.Write([]byte(`{"default_branch": "main", "html_url": "https://github.com/org/repo"}`))
+		case r.URL.Path == "/repos/org/repo/commits/main":
+			w.Write([]byte("commit1"))
+		case strings.HasPrefix(r.URL.Path, "/repos/org/repo/git/trees/"):
+			w.Write([]byte(`{"sha": "commit1", "tree": [{"path": "README.md", "type": "blob", "mode": "100644", "sha": "readme1"}]}`))
+		default:
+			w.Write([]byte("# Readme"))
+		}
+	}
+
+	sink := newMockSink()
+	sink.shouldFail = true
+	service := newTestGitHubService(t, handler, sink)
+
+	ds := &models.DataSource{ID: uuid.New(), Config: models.Config{Repository: "org/repo"}}
+	next, err := service.SyncRepository(context.Background(), ds, nil)
+	if err != nil {
+		t.Fatalf("SyncRepository() error = %v", err)
+	}
+
+	// The failed file must be fetched again next time
+	if _, ok := next["README.md"]; ok {
+		t.Error("Expected failed file to be left out of the checkpoint")
+	}
+}
+
+func TestGitHubService_SyncRepositoryFilters(t *testing.T) {
+	var blobRequests []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/org/repo":
+			w.Write([]byte(`{"default_branch": "main", "html_url": "https://github.com/org/repo"}`))
+		case "/repos/org/repo/commits/v1.0":
+			w.Write([]byte("commit1"))
+		case "/repos/org/repo/git/trees/commit1":
+			w.Write([]byte(`{"sha": "commit1", "tree": [
+				{"path": ".gitattributes", "type": "blob", "mode": "100644", "sha": "attrs", "size": 40},
+				{"path": "README.md", "type": "blob", "mode": "100644", "sha": "readme", "size": 10},
+				{"path": "docs/guide.md", "type": "blob", "mode": "100644", "sha": "guide", "size": 10},
+				{"path": "docs/schema.csv", "type": "blob", "mode": "100644", "sha": "schema", "size": 10},
+				{"path": "docs/diagram.png", "type": "blob", "mode": "100644", "sha": "diagram", "size": 10},
+				{"path": "docs/huge.md", "type": "blob", "mode": "100644", "sha": "huge", "size": 5000},
+				{"path": "docs/reference.md", "type": "blob", "mode": "100644", "sha": "reference", "size": 10},
+				{"path": "docs/vendor/lib.md", "type": "blob", "mode": "100644", "sha": "lib", "size": 10}
+			]}`))
+		default:
+			sha := strings.TrimPrefix(r.URL.Path, "/repos/org/repo/git/blobs/")
+			blobRequests = append(blobRequests, sha)
+			switch sha {
+			case "attrs":
+				w.Write([]byte("docs/reference.md linguist-generated\n"))
+			case "diagram":
+				w.Write([]byte("\x89PNG\x00\x00"))
+			default:
+				w.Write([]byte("content"))
+			}
+		}
+	}
+
+	sink := newMockSink()
+	service := newTestGitHubService(t, handler, sink)
+
+	ds := &models.DataSource{ID: uuid.New(), Config: models.Config{
+		Repository: "org/repo",
+		Filters: map[string]interface{}{
+			"tag":           "v1.0",
+			"include":       []interface{}{"docs/**", "!**/vendor/**"},
+			"max_file_size": float64(1000),
+		},
+	}}
+
+	next, err := service.SyncRepository(context.Background(), ds, nil)
+	if err != nil {
+		t.Fatalf("SyncRepository() error = %v", err)
+	}
+
+	var paths []string
+	for _, doc := range sink.ingested() {
+		paths = append(paths, doc.Metadata.ExternalID)
+	}
+	sort.Strings(paths)
+	if strings.Join(paths, ",") != "docs/guide.md,docs/schema.csv" {
+		t.Errorf("Unexpected ingested files: %v", paths)
+	}
+	if doc := sink.ingested()[0]; !strings.Contains(doc.URL, "/blob/v1.0/docs/") {
+		t.Errorf("Expected URL on the tag, got %s", doc.URL)
+	}
+
+	// Binary files are remembered so an unchanged one isn't downloaded again
+	if next["docs/diagram.png"] != binaryMarker+"diagram" {
+		t.Errorf("Expected binary file in checkpoint, got %q", next["docs/diagram.png"])
+	}
+
+	blobRequests = nil
+	if _, err := service.SyncRepository(context.Background(), ds, next); err != nil {
+		t.Fatalf("SyncRepository() error = %v", err)
+	}
+	if strings.Join(blobRequests, ",") != "attrs" {
+		t.Errorf("Expected only .gitattributes to be downloaded again, got %v", blobRequests)
+	}
+}
@@ -0,0 +1,166 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/Abraham12611/veritas/internal/httpx"
+	"github.com/Abraham12611/veritas/internal/logger"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// ParseWebhook maps GitHub webhook deliveries to changes. Pushes to the
+// synced ref and wiki edits need a sync, which only downloads what changed.
+// Issue and pull request events name the item that changed.
+func (githubConnector) ParseWebhook(ds *models.DataSource, req WebhookRequest) (*WebhookEvent, error) {
+	if err := verifyHMAC(ds.Config.WebhookSecret, req.Body, req.Header.Get(github.SHA256SignatureHeader), "sha256="); err != nil {
+		return nil, err
+	}
+
+	// Webhooks can be configured to send the JSON as a form field
+	payload := req.Body
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(req.Body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse form payload: %w", err)
+		}
+		payload = []byte(form.Get("payload"))
+	}
+
+	event, err := github.ParseWebHook(req.Header.Get(github.EventTypeHeader), payload)
+	if err != nil {
+		// Event types we have no use for aren't an error
+		return &WebhookEvent{}, nil
+	}
+
+	owner, repo, err := repositoryName(ds.Config)
+	if err != nil {
+		return nil, err
+	}
+	filters, err := parseGitHubFilters(ds.Config.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	// Organization webhooks deliver events for every repository
+	sameRepo := func(fullName string) bool {
+		return strings.EqualFold(fullName, owner+"/"+repo)
+	}
+	issueChange := func(action string, number int, pullRequest bool) *WebhookEvent {
+		if pullRequest && !filters.pullRequests || !pullRequest && !filters.issues {
+			return &WebhookEvent{}
+		}
+		id := issueExternalID(number)
+		if action == "deleted" || action == "transferred" {
+			return &WebhookEvent{Deleted: []string{id}}
+		}
+		return &WebhookEvent{Changed: []string{id}}
+	}
+
+	switch e := event.(type) {
+	case *github.PushEvent:
+		if !sameRepo(e.GetRepo().GetFullName()) {
+			break
+		}
+		refs := []string{"refs/heads/" + e.GetRepo().GetDefaultBranch()}
+		if filters.ref != "" {
+			refs = []string{"refs/heads/" + filters.ref, "refs/tags/" + filters.ref}
+		}
+		for _, ref := range refs {
+			if e.GetRef() == ref {
+				return &WebhookEvent{Resync: true}, nil
+			}
+		}
+	case *github.GollumEvent:
+		if sameRepo(e.GetRepo().GetFullName()) && filters.wiki {
+			return &WebhookEvent{Resync: true}, nil
+		}
+	case *github.IssuesEvent:
+		if sameRepo(e.GetRepo().GetFullName()) {
+			return issueChange(e.GetAction(), e.GetIssue().GetNumber(), false), nil
+		}
+	case *github.IssueCommentEvent:
+		// Deleting a comment changes its issue
+		if sameRepo(e.GetRepo().GetFullName()) {
+			return issueChange("edited", e.GetIssue().GetNumber(), e.GetIssue().IsPullRequest()), nil
+		}
+	case *github.PullRequestEvent:
+		if sameRepo(e.GetRepo().GetFullName()) {
+			return issueChange(e.GetAction(), e.GetPullRequest().GetNumber(), true), nil
+		}
+	case *github.PullRequestReviewEvent:
+		if sameRepo(e.GetRepo().GetFullName()) {
+			return issueChange("edited", e.GetPullRequest().GetNumber(), true), nil
+		}
+	case *github.PullRequestReviewCommentEvent:
+		if sameRepo(e.GetRepo().GetFullName()) {
+			return issueChange("edited", e.GetPullRequest().GetNumber(), true), nil
+		}
+	}
+
+	return &WebhookEvent{}, nil
+}
+
+func (githubConnector) SyncItems(ctx context.Context, ds *models.DataSource, sink DocumentSink, externalIDs []string) ([]string, error) {
+	return NewGitHubService(ds.Config.AccessToken, sink).SyncIssueItems(ctx, ds, externalIDs)
+}
+
+// SyncIssueItems syncs the issues and pull requests with the given external
+// IDs. It returns the IDs of those that were deleted or transferred, or
+// don't match the filters. Other kinds of external IDs are ignored; files
+// and wiki pages are updated by a sync.
+func (s *GitHubService) SyncIssueItems(ctx context.Context, ds *models.DataSource, externalIDs []string) ([]string, error) {
+	owner, repo, err := repositoryName(ds.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	filters, err := parseGitHubFilters(ds.Config.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	var deleted []string
+	for _, externalID := range externalIDs {
+		number, err := strconv.Atoi(strings.TrimPrefix(externalID, "#"))
+		if !strings.HasPrefix(externalID, "#") || err != nil {
+			continue
+		}
+
+		var issue *github.Issue
+		err = s.withRetry(ctx, func() error {
+			i, _, err := s.client.Issues.Get(ctx, owner, repo, number)
+			issue = i
+			return err
+		})
+
+		// Deleted issues are gone, transferred ones have moved
+		var statusErr *httpx.StatusError
+		if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone) ||
+			err == nil && !filters.matchesIssue(issue) {
+			deleted = append(deleted, externalID)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get issue %d: %w", number, err)
+		}
+
+		if err := s.syncIssue(ctx, ds, owner, repo, issue); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			logger.Error("Failed to sync issue", err, logger.Fields{
+				"dataSourceId": ds.ID,
+				"number":       number,
+			})
+		}
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,270 @@
+package sync
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+	"github.com/Abraham12611/veritas/internal/httpx"
+	"github.com/Abraham12611/veritas/internal/logger"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// wikiPrefix starts the external IDs of wiki pages, which would otherwise
+// clash with the paths of the repository's files
+const wikiPrefix = "wiki:"
+
+// wikiExtensions are the markup formats GitHub renders as wiki pages
+var wikiExtensions = map[string]bool{
+	".md":        true,
+	".markdown":  true,
+	".mdown":     true,
+	".mkdn":      true,
+	".mediawiki": true,
+	".wiki":      true,
+	".textile":   true,
+	".rdoc":      true,
+	".org":       true,
+	".creole":    true,
+	".asciidoc":  true,
+	".adoc":      true,
+	".rst":       true,
+	".pod":       true,
+}
+
+// wikiPage is a page in a wiki's git tree
+type wikiPage struct {
+	path string
+	sha  string
+}
+
+// SyncWiki syncs the pages of a repository's wiki, if enabled in the
+// filters. The wiki isn't available through the API, so it is cloned with
+// git using the same token. The returned checkpoint maps the external ID of
+// every page ingested or kept to its blob SHA.
+func (s *GitHubService) SyncWiki(ctx context.Context, ds *models.DataSource, checkpoint Checkpoint) (Checkpoint, error) {
+	owner, repo, err := repositoryName(ds.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	filters, err := parseGitHubFilters(ds.Config.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	if !filters.wiki {
+		return Checkpoint{}, nil
+	}
+
+	var repository *github.Repository
+	err = s.withRetry(ctx, func() error {
+		r, _, err := s.client.Repositories.Get(ctx, owner, repo)
+		repository = r
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get repository: %w", err)
+	}
+	if !repository.GetHasWiki() {
+		logger.Info("GitHub repository has no wiki", logger.Fields{
+			"dataSourceId": ds.ID,
+			"repository":   owner + "/" + repo,
+		})
+		return Checkpoint{}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "veritas-wiki-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create wiki directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	remote := fmt.Sprintf("%s/%s/%s.wiki.git", s.gitURL, owner, repo)
+	err = s.withRetry(ctx, func() error {
+		// A failed attempt may leave a partial clone behind
+		if err := os.RemoveAll(dir); err != nil {
+			return err
+		}
+		_, err := s.git(ctx, "", "clone", "--quiet", "--depth", "1", "--", remote, dir)
+		return err
+	})
+	var statusErr *httpx.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		// GitHub only creates the wiki's repository with the first page
+		logger.Info("GitHub wiki has no pages", logger.Fields{
+			"dataSourceId": ds.ID,
+			"repository":   owner + "/" + repo,
+		})
+		return Checkpoint{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone wiki: %w", err)
+	}
+
+	pages, err := s.listWikiPages(ctx, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wiki pages: %w", err)
+	}
+
+	next := make(Checkpoint)
+	skipped := 0
+	for _, page := range pages {
+		externalID := wikiPrefix + page.path
+
+		if checkpoint[externalID] == page.sha {
+			s.sink.KeepDocument(externalID)
+			next[externalID] = page.sha
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(page.path)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read wiki page %s: %w", page.path, err)
+		}
+		if len(content) > filters.maxFileSize || strings.TrimSpace(string(content)) == "" {
+			skipped++
+			continue
+		}
+
+		name := strings.TrimSuffix(path.Base(page.path), path.Ext(page.path))
+		input := models.CreateDocumentInput{
+			InstanceID:   ds.InstanceID,
+			DataSourceID: ds.ID,
+			Title:        strings.ReplaceAll(name, "-", " "),
+			Content:      string(content),
+			URL:          repository.GetHTMLURL() + "/wiki/" + url.PathEscape(name),
+			Type:         wikiDocumentType(page.path),
+			Metadata: models.Metadata{
+				SourcePath: page.path,
+				ExternalID: externalID,
+				Category:   "wiki",
+				Extra: map[string]interface{}{
+					"sha": page.sha,
+				},
+			},
+		}
+
+		if _, err := s.sink.IngestDocument(ctx, input); err != nil {
+			logger.Error("Failed to ingest wiki page", err, logger.Fields{
+				"path": page.path,
+			})
+
+			// Keep the previous version until it is synced again
+			if previous, ok := checkpoint[externalID]; ok {
+				next[externalID] = previous
+			}
+			continue
+		}
+		next[externalID] = page.sha
+	}
+
+	logger.Info("Synced GitHub wiki", logger.Fields{
+		"dataSourceId": ds.ID,
+		"repository":   owner + "/" + repo,
+		"pages":        len(pages),
+		"skipped":      skipped,
+	})
+
+	return next, nil
+}
+
+// listWikiPages lists the pages in a cloned wiki. Sidebars, footers and
+// other files starting with _ aren't pages.
+func (s *GitHubService) listWikiPages(ctx context.Context, dir string) ([]wikiPage, error) {
+	// An empty wiki has no commits
+	if _, err := s.git(ctx, dir, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return nil, nil
+	}
+
+	out, err := s.git(ctx, dir, "ls-tree", "-r", "-z", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []wikiPage
+	for _, entry := range strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00") {
+		// <mode> SP <type> SP <sha> TAB <path>
+		meta, p, ok := strings.Cut(entry, "\t")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) != 3 || fields[1] != "blob" || fields[0] == "120000" {
+			continue
+		}
+		if strings.HasPrefix(path.Base(p), "_") || !wikiExtensions[strings.ToLower(path.Ext(p))] {
+			continue
+		}
+		pages = append(pages, wikiPage{path: p, sha: fields[2]})
+	}
+
+	return pages, nil
+}
+
+// wikiDocumentType returns the document type of a wiki page
+func wikiDocumentType(p string) string {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".md", ".markdown", ".mdown", ".mkdn":
+		return "markdown"
+	default:
+		return "text"
+	}
+}
+
+// gitHTTPStatus finds the HTTP status in git's error for a failed request
+var gitHTTPStatus = regexp.MustCompile(`The requested URL returned error: (\d{3})`)
+
+// git runs a git command in dir, authenticating HTTP requests with the
+// service's token. The token is passed in the environment rather than the
+// arguments, so it doesn't show in the process list. HTTP failures are
+// returned as *httpx.StatusError so they are retried like API calls.
+func (s *GitHubService) git(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+
+	credentials := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + s.accessToken))
+	cmd.Env = append(os.Environ(),
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic "+credentials,
+	)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err == nil {
+		return out, nil
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	msg := strings.TrimSpace(stderr.String())
+	err = fmt.Errorf("git %s failed: %w: %s", args[0], err, msg)
+
+	status := 0
+	if m := gitHTTPStatus.FindStringSubmatch(msg); m != nil {
+		status, _ = strconv.Atoi(m[1])
+	} else if strings.Contains(msg, "not found") {
+		status = http.StatusNotFound
+	}
+	if status != 0 {
+		return nil, &httpx.StatusError{StatusCode: status, Err: err}
+	}
+	return nil, err
+}
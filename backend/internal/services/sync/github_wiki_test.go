@@ -0,0 +1,166 @@
+package sync
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// newTestWiki creates a git repository for org/repo's wiki under a
+// temporary directory, returning the directory and a function that commits
+// files to it
+func newTestWiki(t *testing.T) (string, func(files map[string]string)) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	root := t.TempDir()
+	dir := filepath.Join(root, "org", "repo.wiki.git")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "--quiet")
+
+	commit := func(files map[string]string) {
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		git("add", "-A")
+		git("commit", "--quiet", "-m", "Update wiki")
+	}
+
+	return root, commit
+}
+
+func TestGitHubService_SyncWiki(t *testing.T) {
+	root, commit := newTestWiki(t)
+	commit(map[string]string{
+		"Home.md":            "# Welcome",
+		"Getting-Started.md": "Run `make`.",
+		"Design.rst":         "Design\n======",
+		"_Sidebar.md":        "* [[Home]]",
+		"diagram.png":        "\x89PNG",
+	})
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"has_wiki": true, "html_url": "https://github.com/org/repo"}`))
+	}
+
+	sink := newMockSink()
+	service := newTestGitHubService(t, handler, sink)
+	service.gitURL = "file://" + root
+
+	ds := &models.DataSource{ID: uuid.New(), Config: models.Config{
+		Repository: "org/repo",
+		Filters:    map[string]interface{}{"wiki": true},
+	}}
+
+	next, err := service.SyncWiki(context.Background(), ds, nil)
+	if err != nil {
+		t.Fatalf("SyncWiki() error = %v", err)
+	}
+
+	docs := make(map[string]models.CreateDocumentInput)
+	for _, doc := range sink.ingested() {
+		docs[doc.Metadata.ExternalID] = doc
+	}
+	if len(docs) != 3 {
+		t.Fatalf("Expected 3 pages, got %d", len(docs))
+	}
+
+	page := docs["wiki:Getting-Started.md"]
+	if page.Title != "Getting Started" || page.URL != "https://github.com/org/repo/wiki/Getting-Started" || page.Type != "markdown" {
+		t.Errorf("Unexpected page: %+v", page)
+	}
+	if docs["wiki:Design.rst"].Type != "text" {
+		t.Errorf("Expected reStructuredText page to be text, got %s", docs["wiki:Design.rst"].Type)
+	}
+
+	// Only changed pages are ingested again
+	commit(map[string]string{"Home.md": "# Welcome!"})
+
+	sink = newMockSink()
+	service.sink = sink
+	next, err = service.SyncWiki(context.Background(), ds, next)
+	if err != nil {
+		t.Fatalf("SyncWiki() error = %v", err)
+	}
+
+	if docs := sink.ingested(); len(docs) != 1 || docs[0].Metadata.ExternalID != "wiki:Home.md" {
+		t.Errorf("Expected only Home.md ingested, got %v", docs)
+	}
+	kept := sink.keptIDs()
+	sort.Strings(kept)
+	if strings.Join(kept, ",") != "wiki:Design.rst,wiki:Getting-Started.md" {
+		t.Errorf("Unexpected kept pages: %v", kept)
+	}
+
+	// A page that fails to ingest keeps its previous version
+	commit(map[string]string{"Home.md": "# Welcome!!"})
+
+	sink = newMockSink()
+	sink.shouldFail = true
+	service.sink = sink
+	failed, err := service.SyncWiki(context.Background(), ds, next)
+	if err != nil {
+		t.Fatalf("SyncWiki() error = %v", err)
+	}
+	if failed["wiki:Home.md"] != next["wiki:Home.md"] {
+		t.Errorf("Expected the previous SHA of Home.md, got %q", failed["wiki:Home.md"])
+	}
+}
+
+func TestGitHubService_SyncWikiDisabled(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"has_wiki": false, "html_url": "https://github.com/org/repo"}`))
+	}
+
+	service := newTestGitHubService(t, handler, newMockSink())
+	service.gitURL = "file:///nonexistent"
+
+	ds := &models.DataSource{ID: uuid.New(), Config: models.Config{
+		Repository: "org/repo",
+		Filters:    map[string]interface{}{"wiki": true},
+	}}
+
+	next, err := service.SyncWiki(context.Background(), ds, nil)
+	if err != nil {
+		t.Fatalf("SyncWiki() error = %v", err)
+	}
+	if len(next) != 0 {
+		t.Errorf("Expected empty checkpoint, got %v", next)
+	}
+}
+
+func TestGitHubService_GitError(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	service := NewGitHubService("secret-token", newMockSink())
+	_, err := service.git(context.Background(), t.TempDir(), "rev-parse", "HEAD")
+	if err == nil {
+		t.Fatal("Expected error outside a repository")
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("Expected token not to appear in error: %v", err)
+	}
+}
@@ -2,14 +2,16 @@ package sync
 
 import (
 	"bytes"
-	"io"
 	"regexp"
 	"strings"
+	"unicode"
 
 	"golang.org/x/net/html"
 )
 
-// HTMLToText converts HTML content to plain text while preserving structure
+// HTMLToText converts HTML content to plain text while preserving structure.
+// Block elements are separated by blank lines, list items and table rows by
+// newlines, and table cells by tabs. Preformatted text keeps its whitespace.
 func HTMLToText(htmlContent string) (string, error) {
 	doc, err := html.Parse(strings.NewReader(htmlContent))
 	if err != nil {
@@ -17,40 +19,75 @@ func HTMLToText(htmlContent string) (string, error) {
 	}
 
 	var buf bytes.Buffer
-	var lastNode *html.Node
-	var f func(*html.Node)
+	var sep string // separator to write before the next text
+	var space bool // whether the next text follows whitespace
+
+	// breakBefore requests a separator before the next text, keeping the
+	// strongest one requested so far
+	breakBefore := func(s string) {
+		if sep == "" || strings.Count(s, "\n") > strings.Count(sep, "\n") {
+			sep = s
+		}
+	}
+
+	// emit writes text after any pending separator or space
+	emit := func(text string) {
+		if buf.Len() > 0 {
+			if sep != "" {
+				buf.WriteString(sep)
+			} else if space && !strings.HasSuffix(buf.String(), " ") {
+				buf.WriteString(" ")
+			}
+		}
+		buf.WriteString(text)
+		sep = ""
+		space = false
+	}
 
+	var f func(*html.Node)
 	f = func(n *html.Node) {
 		switch n.Type {
+		case html.DocumentNode:
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				f(c)
+			}
+
 		case html.TextNode:
-			// Skip if it's empty whitespace
-			if strings.TrimSpace(n.Data) == "" {
+			// Collapse whitespace like a browser does
+			text := strings.Join(strings.Fields(cleanText(n.Data)), " ")
+			if text == "" {
+				space = space || n.Data != ""
 				return
 			}
 
-			// Add spacing based on previous node
-			if lastNode != nil && lastNode.Type == html.TextNode {
-				buf.WriteString(" ")
+			if strings.TrimLeftFunc(n.Data, unicode.IsSpace) != n.Data {
+				space = true
 			}
-
-			// Clean and write the text
-			text := cleanText(n.Data)
-			buf.WriteString(text)
-			lastNode = n
+			emit(text)
+			space = strings.TrimRightFunc(n.Data, unicode.IsSpace) != n.Data
 
 		case html.ElementNode:
 			switch n.Data {
-			case "p", "div", "br", "h1", "h2", "h3", "h4", "h5", "h6":
-				// Add newlines before block elements
-				if buf.Len() > 0 {
-					buf.WriteString("\n\n")
+			case "p", "div", "br", "h1", "h2", "h3", "h4", "h5", "h6", "ul", "ol", "table":
+				// Separate block elements with blank lines
+				breakBefore("\n\n")
+				for c := n.FirstChild; c != nil; c = c.NextSibling {
+					f(c)
 				}
+				breakBefore("\n\n")
+				return
 			case "li":
 				// Add bullet points for list items
-				if buf.Len() > 0 {
-					buf.WriteString("\n")
+				breakBefore("\n")
+				emit("• ")
+			case "tr":
+				// Put each row on its own line
+				breakBefore("\n")
+			case "td", "th":
+				// Separate cells in a row with tabs
+				if sep == "" && buf.Len() > 0 {
+					breakBefore("\t")
 				}
-				buf.WriteString("• ")
 			case "a":
 				// For links, include the URL in parentheses
 				var href string
@@ -64,61 +101,36 @@ func HTMLToText(htmlContent string) (string, error) {
 					for c := n.FirstChild; c != nil; c = c.NextSibling {
 						f(c)
 					}
-					buf.WriteString(" (" + href + ")")
+					space = true
+					emit("(" + href + ")")
 					return
 				}
 			case "code", "pre":
-				// Preserve code blocks with backticks
+				// Preserve code with backticks, keeping its whitespace
 				var code bytes.Buffer
 				extractText(n, &code)
-				if code.Len() > 0 {
-					if buf.Len() > 0 {
-						buf.WriteString("\n")
+				if strings.TrimSpace(code.String()) != "" {
+					if n.Data == "pre" {
+						breakBefore("\n\n")
 					}
-					buf.WriteString("`")
-					buf.Write(code.Bytes())
-					buf.WriteString("`")
+					emit("`" + code.String() + "`")
 					if n.Data == "pre" {
-						buf.WriteString("\n")
+						breakBefore("\n\n")
 					}
-					return
-				}
-			case "table":
-				// Add newlines around tables
-				if buf.Len() > 0 {
-					buf.WriteString("\n\n")
-				}
-			case "tr":
-				// Add newlines between rows
-				if buf.Len() > 0 {
-					buf.WriteString("\n")
-				}
-			case "td", "th":
-				// Add spacing between cells
-				if buf.Len() > 0 && !strings.HasSuffix(buf.String(), "\n") {
-					buf.WriteString("\t")
 				}
+				return
 			}
 
 			// Recursively process child nodes
 			for c := n.FirstChild; c != nil; c = c.NextSibling {
 				f(c)
 			}
-
-			// Add spacing after certain elements
-			switch n.Data {
-			case "table":
-				buf.WriteString("\n")
-			}
 		}
 	}
 
 	f(doc)
 
-	// Clean up the final text
-	text := buf.String()
-	text = cleanupWhitespace(text)
-	return text, nil
+	return strings.TrimSpace(buf.String()), nil
 }
 
 // extractText recursively extracts text from nodes
@@ -137,10 +149,10 @@ func cleanText(s string) string {
 	s = strings.ReplaceAll(s, "\u00a0", " ")
 	
 	// Normalize quotes
-	s = strings.ReplaceAll(s, """, "\"")
-	s = strings.ReplaceAll(s, """, "\"")
-	s = strings.ReplaceAll(s, "'", "'")
-	s = strings.ReplaceAll(s, "'", "'")
+	s = strings.ReplaceAll(s, "\u201c", "\"")
+	s = strings.ReplaceAll(s, "\u201d", "\"")
+	s = strings.ReplaceAll(s, "\u2018", "'")
+	s = strings.ReplaceAll(s, "\u2019", "'")
 	
 	// Remove extra whitespace
 	s = strings.TrimSpace(s)
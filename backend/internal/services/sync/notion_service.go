@@ -1,15 +1,17 @@
 package sync
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/Abraham12611/veritas/internal/httpx"
 	"github.com/Abraham12611/veritas/internal/logger"
 	"github.com/Abraham12611/veritas/internal/models"
 	"golang.org/x/sync/errgroup"
@@ -24,17 +26,19 @@ type NotionService struct {
 	apiKey      string
 	concurrency int
 	baseURL     string
+	sink        DocumentSink
 }
 
 // NewNotionService creates a new Notion sync service
-func NewNotionService(apiKey string) *NotionService {
+func NewNotionService(apiKey string, sink DocumentSink) *NotionService {
 	// Create HTTP client with reasonable timeouts
 	client := &http.Client{
 		Timeout: 30 * time.Second,
 	}
 
-	// Create rate limiter: 3 requests per second (Notion API limit)
-	limiter := rate.NewLimiter(rate.Every(time.Second/3), 1)
+	// Rate limiter shared by all syncs with the same key: 3 requests per
+	// second (Notion API limit)
+	limiter := httpx.SharedLimiter("notion", apiKey, rate.Every(time.Second/3), 1)
 
 	return &NotionService{
 		client:      client,
@@ -43,37 +47,78 @@ func NewNotionService(apiKey string) *NotionService {
 		apiKey:      apiKey,
 		concurrency: 5, // Process 5 pages concurrently
 		baseURL:     "https://api.notion.com/v1",
+		sink:        sink,
 	}
 }
 
 // withRetry executes a function with retries and rate limiting
 func (s *NotionService) withRetry(ctx context.Context, operation func() error) error {
-	var lastErr error
-	for attempt := 0; attempt <= s.maxRetries; attempt++ {
-		// Wait for rate limiter
-		if err := s.limiter.Wait(ctx); err != nil {
-			return fmt.Errorf("rate limiter error: %w", err)
+	return httpx.Retry(ctx, s.limiter, httpx.RetryPolicy{MaxRetries: s.maxRetries}, operation)
+}
+
+// doJSON sends an authenticated request with an optional JSON body and
+// decodes the JSON response
+func (s *NotionService) doJSON(ctx context.Context, method, endpoint string, body interface{}, out interface{}) error {
+	var jsonBody []byte
+	if body != nil {
+		var err error
+		if jsonBody, err = json.Marshal(body); err != nil {
+			return err
 		}
+	}
 
-		// Execute operation
-		if err := operation(); err != nil {
-			lastErr = err
-			// Check if error is retryable
-			if isRetryableError(err) {
-				// Exponential backoff
-				backoff := time.Duration(attempt*attempt) * time.Second
-				select {
-				case <-time.After(backoff):
-					continue
-				case <-ctx.Done():
-					return ctx.Err()
-				}
-			}
-			return err // Non-retryable error
+	return s.withRetry(ctx, func() error {
+		// A request body can only be read once, so build one per attempt
+		req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(jsonBody))
+		if err != nil {
+			return err
 		}
-		return nil // Success
+
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+		req.Header.Set("Notion-Version", "2022-06-28")
+		req.Header.Set("Content-Type", "application/json")
+
+		return httpx.DoJSON(s.client, req, out)
+	})
+}
+
+// notionConnector adapts NotionService to the Connector interface
+type notionConnector struct{}
+
+func init() {
+	Register(notionConnector{})
+}
+
+func (notionConnector) Type() string {
+	return "notion"
+}
+
+func (notionConnector) Validate(cfg models.Config) error {
+	r := requiredFields{dsType: "notion"}
+	r.require("api_token", cfg.APIToken)
+	r.require("database_id", cfg.DatabaseID)
+	return r.err()
+}
+
+func (notionConnector) TestConnection(ctx context.Context, ds *models.DataSource) error {
+	s := NewNotionService(ds.Config.APIToken, nil)
+	s.maxRetries = 0
+	return s.TestConnection(ctx, ds)
+}
+
+func (notionConnector) Sync(ctx context.Context, ds *models.DataSource, sink DocumentSink, checkpoint Checkpoint) (*SyncResult, error) {
+	if err := NewNotionService(ds.Config.APIToken, sink).SyncDatabase(ctx, ds); err != nil {
+		return nil, err
+	}
+	return &SyncResult{Complete: true}, nil
+}
+
+// TestConnection checks that the database can be read with the token
+func (s *NotionService) TestConnection(ctx context.Context, ds *models.DataSource) error {
+	if _, err := s.getDatabase(ctx, ds.Config.DatabaseID); err != nil {
+		return fmt.Errorf("failed to get database: %w", err)
 	}
-	return fmt.Errorf("max retries exceeded: %w", lastErr)
+	return nil
 }
 
 // SyncDatabase syncs content from a Notion database
@@ -83,15 +128,14 @@ func (s *NotionService) SyncDatabase(ctx context.Context, ds *models.DataSource)
 		"databaseId":  ds.Config.DatabaseID,
 	})
 
-	// Get database metadata
-	database, err := s.getDatabase(ctx, ds.Config.DatabaseID)
-	if err != nil {
+	// Check the database exists first
+	if _, err := s.getDatabase(ctx, ds.Config.DatabaseID); err != nil {
 		return fmt.Errorf("failed to get database: %w", err)
 	}
 
 	// Create error group for concurrent processing
 	g, ctx := errgroup.WithContext(ctx)
-	g.SetLimit(s.concurrency)
+	g.SetLimit(s.concurrency + 1) // Page workers plus the fetcher
 
 	// Channel for pages to process
 	pagesChan := make(chan NotionPage, s.concurrency*2)
@@ -167,33 +211,9 @@ func (s *NotionService) SyncDatabase(ctx context.Context, ds *models.DataSource)
 func (s *NotionService) getDatabase(ctx context.Context, databaseID string) (*NotionDatabase, error) {
 	endpoint := fmt.Sprintf("%s/databases/%s", s.baseURL, databaseID)
 	
-	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Authorization", "Bearer "+s.apiKey)
-	req.Header.Set("Notion-Version", "2022-06-28")
-	req.Header.Set("Content-Type", "application/json")
-
 	var database NotionDatabase
-	err = s.withRetry(ctx, func() error {
-		resp, err := s.client.Do(req)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("failed to get database: status %d: %s", resp.StatusCode, string(body))
-		}
-
-		return json.NewDecoder(resp.Body).Decode(&database)
-	})
-
-	if err != nil {
-		return nil, err
+	if err := s.doJSON(ctx, "GET", endpoint, nil, &database); err != nil {
+		return nil, fmt.Errorf("failed to get database: %w", err)
 	}
 
 	return &database, nil
@@ -210,43 +230,13 @@ func (s *NotionService) queryDatabase(ctx context.Context, databaseID string, st
 		body["start_cursor"] = startCursor
 	}
 
-	jsonBody, err := json.Marshal(body)
-	if err != nil {
-		return nil, "", err
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(string(jsonBody)))
-	if err != nil {
-		return nil, "", err
-	}
-
-	req.Header.Set("Authorization", "Bearer "+s.apiKey)
-	req.Header.Set("Notion-Version", "2022-06-28")
-	req.Header.Set("Content-Type", "application/json")
-
 	var response struct {
 		Results    []NotionPage `json:"results"`
 		NextCursor string       `json:"next_cursor"`
 		HasMore    bool         `json:"has_more"`
 	}
-
-	err = s.withRetry(ctx, func() error {
-		resp, err := s.client.Do(req)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("failed to query database: status %d: %s", resp.StatusCode, string(body))
-		}
-
-		return json.NewDecoder(resp.Body).Decode(&response)
-	})
-
-	if err != nil {
-		return nil, "", err
+	if err := s.doJSON(ctx, "POST", endpoint, body, &response); err != nil {
+		return nil, "", fmt.Errorf("failed to query database: %w", err)
 	}
 
 	return response.Results, response.NextCursor, nil
@@ -262,6 +252,9 @@ func (s *NotionService) processPage(ctx context.Context, ds *models.DataSource,
 	// Get page content
 	content, err := s.getPageContent(ctx, page.ID)
 	if err != nil {
+		// Keep the page's existing document rather than deleting it as
+		// missing at the end of the sync
+		s.sink.KeepDocument(page.ID)
 		return fmt.Errorf("failed to get page content: %w", err)
 	}
 
@@ -284,11 +277,9 @@ func (s *NotionService) processPage(ctx context.Context, ds *models.DataSource,
 		},
 	}
 
-	// TODO: Call ingestion service to process the document
-	logger.Info("Would process document", logger.Fields{
-		"title": input.Title,
-		"url":   input.URL,
-	})
+	if _, err := s.sink.IngestDocument(ctx, input); err != nil {
+		return fmt.Errorf("failed to ingest document: %w", err)
+	}
 
 	return nil
 }
@@ -312,53 +303,57 @@ func (s *NotionService) getPageContent(ctx context.Context, pageID string) (stri
 
 // getBlocks recursively retrieves all blocks including nested ones
 func (s *NotionService) getBlocks(ctx context.Context, blockID string) ([]NotionBlock, error) {
-	endpoint := fmt.Sprintf("%s/blocks/%s/children", s.baseURL, blockID)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Set("Authorization", "Bearer "+s.apiKey)
-	req.Header.Set("Notion-Version", "2022-06-28")
-
-	var response struct {
-		Results []NotionBlock `json:"results"`
-		HasMore bool          `json:"has_more"`
-		NextCursor string     `json:"next_cursor"`
-	}
-
-	err = s.withRetry(ctx, func() error {
-		resp, err := s.client.Do(req)
+	var blocks []NotionBlock
+	var cursor string
+	for {
+		results, nextCursor, err := s.listBlockChildren(ctx, blockID, cursor)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		defer resp.Body.Close()
+		blocks = append(blocks, results...)
 
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("failed to get blocks: status %d: %s", resp.StatusCode, string(body))
+		if nextCursor == "" {
+			break
 		}
-
-		return json.NewDecoder(resp.Body).Decode(&response)
-	})
-
-	if err != nil {
-		return nil, err
+		cursor = nextCursor
 	}
 
 	// Process nested blocks
-	for i, block := range response.Results {
+	for i, block := range blocks {
 		if block.HasChildren {
 			children, err := s.getBlocks(ctx, block.ID)
 			if err != nil {
 				return nil, err
 			}
-			response.Results[i].Children = children
+			blocks[i].Children = children
 		}
 	}
 
-	return response.Results, nil
+	return blocks, nil
+}
+
+// listBlockChildren retrieves one page of a block's children. The returned
+// cursor is empty after the last page.
+func (s *NotionService) listBlockChildren(ctx context.Context, blockID string, startCursor string) ([]NotionBlock, string, error) {
+	query := url.Values{"page_size": {"100"}}
+	if startCursor != "" {
+		query.Set("start_cursor", startCursor)
+	}
+	endpoint := fmt.Sprintf("%s/blocks/%s/children?%s", s.baseURL, blockID, query.Encode())
+
+	var response struct {
+		Results    []NotionBlock `json:"results"`
+		HasMore    bool          `json:"has_more"`
+		NextCursor string        `json:"next_cursor"`
+	}
+	if err := s.doJSON(ctx, "GET", endpoint, nil, &response); err != nil {
+		return nil, "", fmt.Errorf("failed to get blocks: %w", err)
+	}
+
+	if !response.HasMore {
+		return response.Results, "", nil
+	}
+	return response.Results, response.NextCursor, nil
 }
 
 // processBlock formats a block and its children into text
@@ -369,6 +364,7 @@ func (s *NotionService) processBlock(sb *strings.Builder, block NotionBlock, dep
 	switch block.Type {
 	case "paragraph":
 		if block.Paragraph != nil {
+			sb.WriteString(indent)
 			s.processRichText(sb, block.Paragraph.RichText)
 			sb.WriteString("\n\n")
 		}
@@ -556,6 +552,12 @@ type NotionPage struct {
 	LastEditedTime string                 `json:"last_edited_time"`
 	Title          string                 `json:"title"`
 	Properties     map[string]interface{} `json:"properties"`
+	Archived       bool                   `json:"archived"`
+	InTrash        bool                   `json:"in_trash"`
+	Parent         struct {
+		Type       string `json:"type"`
+		DatabaseID string `json:"database_id"`
+	} `json:"parent"`
 }
 
 // NotionBlock represents a block of content in a Notion page
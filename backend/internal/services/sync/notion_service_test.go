@@ -109,7 +109,7 @@ func TestNotionService_SyncDatabase(t *testing.T) {
 	defer server.Close()
 
 	// Create service with mock server URL
-	service := NewNotionService("test-token")
+	service := NewNotionService("test-token", newMockSink())
 	service.baseURL = server.URL
 	service.concurrency = 2 // Use 2 workers for testing
 
@@ -118,7 +118,7 @@ func TestNotionService_SyncDatabase(t *testing.T) {
 		ID:         uuid.New(),
 		InstanceID: uuid.New(),
 		Type:       "notion",
-		Config: models.DataSourceConfig{
+		Config: models.Config{
 			DatabaseID: "test-db",
 			APIToken:   "test-token",
 		},
@@ -137,6 +137,76 @@ func TestNotionService_SyncDatabase(t *testing.T) {
 	}
 }
 
+func TestNotionService_BlockFetchFailure(t *testing.T) {
+	// Create a mock HTTP server where one page's blocks can't be fetched
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/databases/test-db"):
+			w.Write([]byte(`{"id": "test-db"}`))
+
+		case strings.HasSuffix(r.URL.Path, "/databases/test-db/query"):
+			w.Write([]byte(`{
+				"results": [
+					{"id": "page1", "title": "Test Page 1"},
+					{"id": "page2", "title": "Test Page 2"}
+				],
+				"next_cursor": null,
+				"has_more": false
+			}`))
+
+		case strings.HasSuffix(r.URL.Path, "/blocks/page1/children"):
+			w.Write([]byte(`{
+				"results": [
+					{
+						"type": "paragraph",
+						"paragraph": {"rich_text": [{"type": "text", "plain_text": "Test content"}]}
+					}
+				],
+				"next_cursor": null,
+				"has_more": false
+			}`))
+
+		case strings.HasSuffix(r.URL.Path, "/blocks/page2/children"):
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message": "Internal error"}`))
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sink := newMockSink()
+	service := NewNotionService("test-token", sink)
+	service.baseURL = server.URL
+	service.maxRetries = 0
+
+	ds := &models.DataSource{
+		ID:         uuid.New(),
+		InstanceID: uuid.New(),
+		Type:       "notion",
+		Config: models.Config{
+			DatabaseID: "test-db",
+			APIToken:   "test-token",
+		},
+	}
+
+	// A page that fails doesn't fail the whole sync
+	if err := service.SyncDatabase(context.Background(), ds); err != nil {
+		t.Fatalf("SyncDatabase() error = %v", err)
+	}
+
+	docs := sink.ingested()
+	if len(docs) != 1 || docs[0].Metadata.ExternalID != "page1" {
+		t.Errorf("Expected only page1 to be ingested, got %+v", docs)
+	}
+
+	// The failed page is kept so the complete sync doesn't delete its document
+	if kept := sink.keptIDs(); len(kept) != 1 || kept[0] != "page2" {
+		t.Errorf("Expected page2 to be kept, got %v", kept)
+	}
+}
+
 func TestNotionService_RateLimiting(t *testing.T) {
 	// Create a mock HTTP server that always returns rate limit error
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -146,7 +216,7 @@ func TestNotionService_RateLimiting(t *testing.T) {
 	defer server.Close()
 
 	// Create service with mock server URL
-	service := NewNotionService("test-token")
+	service := NewNotionService("test-token", newMockSink())
 	service.baseURL = server.URL
 	service.maxRetries = 2 // Reduce retries for faster test
 
@@ -155,7 +225,7 @@ func TestNotionService_RateLimiting(t *testing.T) {
 		ID:         uuid.New(),
 		InstanceID: uuid.New(),
 		Type:       "notion",
-		Config: models.DataSourceConfig{
+		Config: models.Config{
 			DatabaseID: "test-db",
 			APIToken:   "test-token",
 		},
@@ -180,7 +250,7 @@ func TestNotionService_Cancellation(t *testing.T) {
 	defer server.Close()
 
 	// Create service with mock server URL
-	service := NewNotionService("test-token")
+	service := NewNotionService("test-token", newMockSink())
 	service.baseURL = server.URL
 
 	// Create test data source
@@ -188,7 +258,7 @@ func TestNotionService_Cancellation(t *testing.T) {
 		ID:         uuid.New(),
 		InstanceID: uuid.New(),
 		Type:       "notion",
-		Config: models.DataSourceConfig{
+		Config: models.Config{
 			DatabaseID: "test-db",
 			APIToken:   "test-token",
 		},
@@ -234,10 +304,10 @@ func TestNotionService_DatabasePagination(t *testing.T) {
 			w.Write([]byte(`{
 				"results": [
 					{
-						"id": "page` + string(currentPage) + `",
+						"id": "page` + fmt.Sprint(currentPage) + `",
 						"created_time": "2024-01-01T00:00:00Z",
 						"last_edited_time": "2024-01-01T00:00:00Z",
-						"title": "Test Page ` + string(currentPage) + `",
+						"title": "Test Page ` + fmt.Sprint(currentPage) + `",
 						"properties": {
 							"Name": {
 								"title": [{"text": {"content": "Test Page"}}]
@@ -270,7 +340,7 @@ func TestNotionService_DatabasePagination(t *testing.T) {
 	defer server.Close()
 
 	// Create service with mock server URL
-	service := NewNotionService("test-token")
+	service := NewNotionService("test-token", newMockSink())
 	service.baseURL = server.URL
 	service.concurrency = 2
 
@@ -279,7 +349,7 @@ func TestNotionService_DatabasePagination(t *testing.T) {
 		ID:         uuid.New(),
 		InstanceID: uuid.New(),
 		Type:       "notion",
-		Config: models.DataSourceConfig{
+		Config: models.Config{
 			DatabaseID: "test-db",
 			APIToken:   "test-token",
 		},
@@ -309,7 +379,7 @@ func TestNotionService_DatabasePagination(t *testing.T) {
 func TestNotionService_BlockParsing(t *testing.T) {
 	// Create a mock HTTP server that returns complex block structure
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if strings.Contains(r.URL.Path, "/blocks/") {
+		if strings.HasSuffix(r.URL.Path, "/blocks/test-page/children") {
 			w.Write([]byte(`{
 				"results": [
 					{
@@ -455,7 +525,7 @@ func TestNotionService_BlockParsing(t *testing.T) {
 		}
 
 		// Return child blocks for block1
-		if strings.Contains(r.URL.Path, "/block1/children") {
+		if strings.HasSuffix(r.URL.Path, "/blocks/block1/children") {
 			w.Write([]byte(`{
 				"results": [
 					{
@@ -489,7 +559,7 @@ func TestNotionService_BlockParsing(t *testing.T) {
 	defer server.Close()
 
 	// Create service with mock server URL
-	service := NewNotionService("test-token")
+	service := NewNotionService("test-token", newMockSink())
 	service.baseURL = server.URL
 
 	// Test block parsing
@@ -554,52 +624,49 @@ func TestNotionService_BlockParsing(t *testing.T) {
 }
 
 func TestNotionService_NestedBlockPagination(t *testing.T) {
-	var blockCount int32
 	var requestCount int32
 
-	// Create a mock HTTP server that returns paginated nested blocks
+	// Create a mock HTTP server that returns a page's blocks, and the
+	// children of its first block, over two pages each
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		atomic.AddInt32(&requestCount, 1)
 
-		if strings.Contains(r.URL.Path, "/blocks/") {
-			currentBlock := atomic.AddInt32(&blockCount, 1)
-			hasMore := currentBlock < 3
-
-			w.Write([]byte(fmt.Sprintf(`{
-				"results": [
-					{
-						"id": "block%d",
-						"type": "paragraph",
-						"paragraph": {
-							"rich_text": [
-								{
-									"type": "text",
-									"plain_text": "Block %d content",
-									"annotations": {
-										"bold": false,
-										"italic": false,
-										"code": false
-									}
-								}
-							]
-						},
-						"has_children": true
-					}
-				],
-				"next_cursor": %s,
-				"has_more": %v
-			}`, currentBlock, currentBlock,
-				map[bool]string{true: `"next-cursor"`, false: "null"}[hasMore],
-				hasMore)))
+		var prefix string
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/blocks/test-page/children"):
+			prefix = "block"
+		case strings.HasSuffix(r.URL.Path, "/blocks/block1/children"):
+			prefix = "block1."
+		default:
+			w.WriteHeader(http.StatusNotFound)
 			return
 		}
 
-		w.WriteHeader(http.StatusNotFound)
+		// The first page links to the second with a cursor
+		n, nextCursor, hasMore := 1, `"cursor-2"`, true
+		if r.URL.Query().Get("start_cursor") == "cursor-2" {
+			n, nextCursor, hasMore = 2, "null", false
+		}
+
+		w.Write([]byte(fmt.Sprintf(`{
+			"results": [
+				{
+					"id": "%s%d",
+					"type": "paragraph",
+					"paragraph": {
+						"rich_text": [{"type": "text", "plain_text": "Block %s%d content"}]
+					},
+					"has_children": %v
+				}
+			],
+			"next_cursor": %s,
+			"has_more": %v
+		}`, prefix, n, prefix, n, prefix+fmt.Sprint(n) == "block1", nextCursor, hasMore)))
 	}))
 	defer server.Close()
 
 	// Create service with mock server URL
-	service := NewNotionService("test-token")
+	service := NewNotionService("test-token", newMockSink())
 	service.baseURL = server.URL
 
 	// Test nested block pagination
@@ -609,15 +676,19 @@ func TestNotionService_NestedBlockPagination(t *testing.T) {
 		return
 	}
 
-	// Verify block count
-	if len(blocks) != 3 {
-		t.Errorf("Expected 3 blocks, got %d", len(blocks))
+	// Verify both pages of blocks and of children were fetched
+	if len(blocks) != 2 || blocks[0].ID != "block1" || blocks[1].ID != "block2" {
+		t.Fatalf("Expected blocks block1 and block2, got %+v", blocks)
+	}
+	children := blocks[0].Children
+	if len(children) != 2 || children[0].ID != "block1.1" || children[1].ID != "block1.2" {
+		t.Errorf("Expected children block1.1 and block1.2, got %+v", children)
 	}
 
 	// Verify request count
 	count := atomic.LoadInt32(&requestCount)
-	expectedRequests := int32(3) // One request per block
+	expectedRequests := int32(4) // Two pages of blocks and two of children
 	if count != expectedRequests {
 		t.Errorf("Expected %d requests, got %d", expectedRequests, count)
 	}
-} 
\ No newline at end of file
+}
@@ -0,0 +1,123 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Abraham12611/veritas/internal/httpx"
+	"github.com/Abraham12611/veritas/internal/logger"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// notionWebhookEvent is a delivery from a Notion webhook subscription
+type notionWebhookEvent struct {
+	// VerificationToken is sent once, unsigned, when the subscription is
+	// created. Once confirmed, it signs the deliveries that follow.
+	VerificationToken string `json:"verification_token"`
+
+	Type   string `json:"type"` // e.g. page.content_updated, page.deleted
+	Entity struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	} `json:"entity"`
+	Data struct {
+		Parent struct {
+			ID   string `json:"id"`
+			Type string `json:"type"`
+		} `json:"parent"`
+	} `json:"data"`
+}
+
+// ParseWebhook maps Notion webhook deliveries to changes. The verification
+// token Notion sends when the subscription is created is returned for an
+// admin to confirm; deliveries are rejected until it's set as the data
+// source's webhook secret.
+func (notionConnector) ParseWebhook(ds *models.DataSource, req WebhookRequest) (*WebhookEvent, error) {
+	var event notionWebhookEvent
+	signature := req.Header.Get("X-Notion-Signature")
+
+	if signature == "" {
+		if err := json.Unmarshal(req.Body, &event); err != nil || event.VerificationToken == "" {
+			return nil, ErrInvalidSignature
+		}
+		return &WebhookEvent{VerificationToken: event.VerificationToken}, nil
+	}
+
+	if err := verifyHMAC(ds.Config.WebhookSecret, req.Body, signature, "sha256="); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(req.Body, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse event: %w", err)
+	}
+
+	// Subscriptions cover the whole workspace
+	if event.Entity.Type != "page" ||
+		event.Data.Parent.Type == "database" && !sameNotionID(event.Data.Parent.ID, ds.Config.DatabaseID) {
+		return &WebhookEvent{}, nil
+	}
+
+	if event.Type == "page.deleted" {
+		return &WebhookEvent{Deleted: []string{event.Entity.ID}}, nil
+	}
+	return &WebhookEvent{Changed: []string{event.Entity.ID}}, nil
+}
+
+func (notionConnector) SyncItems(ctx context.Context, ds *models.DataSource, sink DocumentSink, externalIDs []string) ([]string, error) {
+	return NewNotionService(ds.Config.APIToken, sink).SyncPages(ctx, ds, externalIDs)
+}
+
+// sameNotionID reports whether two Notion IDs are equal. IDs are UUIDs that
+// may be written with or without dashes.
+func sameNotionID(a, b string) bool {
+	return strings.EqualFold(strings.ReplaceAll(a, "-", ""), strings.ReplaceAll(b, "-", ""))
+}
+
+// SyncPages syncs the pages with the given IDs. It returns the IDs of pages
+// that were deleted, archived or are no longer in the database.
+func (s *NotionService) SyncPages(ctx context.Context, ds *models.DataSource, pageIDs []string) ([]string, error) {
+	var deleted []string
+	for _, pageID := range pageIDs {
+		page, err := s.getPage(ctx, pageID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get page %s: %w", pageID, err)
+		}
+		if page == nil || page.Archived || page.InTrash || !sameNotionID(page.Parent.DatabaseID, ds.Config.DatabaseID) {
+			deleted = append(deleted, pageID)
+			continue
+		}
+
+		if err := s.processPage(ctx, ds, *page); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			logger.Error("Failed to process page", err, logger.Fields{
+				"pageId": pageID,
+			})
+		}
+	}
+
+	return deleted, nil
+}
+
+// getPage retrieves a page, or returns nil if it doesn't exist or isn't
+// shared with the integration
+func (s *NotionService) getPage(ctx context.Context, pageID string) (*NotionPage, error) {
+	endpoint := fmt.Sprintf("%s/pages/%s", s.baseURL, pageID)
+
+	var page NotionPage
+	err := s.doJSON(ctx, "GET", endpoint, nil, &page)
+
+	var statusErr *httpx.StatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
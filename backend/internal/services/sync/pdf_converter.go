@@ -0,0 +1,283 @@
+package sync
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// ErrNoPDFText is returned for PDFs without text that can be extracted, e.g.
+// scans, or text in embedded fonts that number glyphs their own way
+var ErrNoPDFText = errors.New("no extractable text in PDF")
+
+// pdfStreamPattern matches a stream object's dictionary and the start of its
+// data
+var pdfStreamPattern = regexp.MustCompile(`(?s)<<((?:[^<>]|<<(?:[^<>]|<<[^<>]*>>)*>>|<[0-9A-Fa-f\s]*>)*)>>\s*stream\r?\n`)
+
+// PDFToText extracts the text of a PDF. It reads the text drawn by the page
+// content streams, uncompressed or Flate-compressed, in the order it is drawn;
+// that is reading order for PDFs exported from documents.
+func PDFToText(data []byte) (string, error) {
+	var buf bytes.Buffer
+
+	for _, loc := range pdfStreamPattern.FindAllSubmatchIndex(data, -1) {
+		dict := string(data[loc[2]:loc[3]])
+		start := loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		stream := data[start : start+end]
+
+		switch {
+		case strings.Contains(dict, "/FlateDecode"):
+			r, err := zlib.NewReader(bytes.NewReader(stream))
+			if err != nil {
+				continue
+			}
+			// A truncated stream still has text worth keeping
+			stream, _ = io.ReadAll(r)
+		case strings.Contains(dict, "/Filter"):
+			// Images and other encodings carry no text
+			continue
+		}
+		if strings.Contains(dict, "/Subtype") || !bytes.Contains(stream, []byte("BT")) {
+			// Fonts, images and forms aren't page content
+			continue
+		}
+
+		extractPDFText(stream, &buf)
+	}
+
+	text := cleanupWhitespace(buf.String())
+	if !readablePDFText(text) {
+		return "", ErrNoPDFText
+	}
+	return text, nil
+}
+
+// readablePDFText reports whether extracted text looks like words rather
+// than glyph numbers read as characters: mostly letters
+func readablePDFText(text string) bool {
+	letters, others := 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r):
+			letters++
+		case !unicode.IsSpace(r):
+			others++
+		}
+	}
+	return letters > 0 && letters >= others
+}
+
+// extractPDFText writes the text shown by the operators of a content stream.
+// Operands are collected until the operator that uses them.
+func extractPDFText(stream []byte, buf *bytes.Buffer) {
+	var operands []string
+	var numbers []float64
+	var lineY float64
+	inArray := false
+
+	for i := 0; i < len(stream); {
+		c := stream[i]
+		switch {
+		case c == '(':
+			s, next := readPDFLiteral(stream, i)
+			operands = append(operands, s)
+			i = next
+		case c == '<' && i+1 < len(stream) && stream[i+1] != '<':
+			end := bytes.IndexByte(stream[i:], '>')
+			if end < 0 {
+				return
+			}
+			operands = append(operands, decodePDFHex(stream[i+1:i+end]))
+			i += end + 1
+		case c == '[':
+			inArray = true
+			i++
+		case c == ']':
+			inArray = false
+			i++
+		case c == '%':
+			for i < len(stream) && stream[i] != '\n' && stream[i] != '\r' {
+				i++
+			}
+		case isPDFDelimiter(c):
+			i++
+		default:
+			start := i
+			for i < len(stream) && !isPDFDelimiter(stream[i]) {
+				i++
+			}
+			token := string(stream[start:i])
+
+			if n, ok := parsePDFNumber(token); ok {
+				if inArray && n < -200 && len(operands) > 0 {
+					// A wide gap between strings in a TJ array is a space
+					operands[len(operands)-1] += " "
+				}
+				numbers = append(numbers, n)
+				continue
+			}
+
+			switch token {
+			case "Tj", "TJ":
+				for _, s := range operands {
+					buf.WriteString(s)
+				}
+			case "'", "\"":
+				buf.WriteString("\n")
+				for _, s := range operands {
+					buf.WriteString(s)
+				}
+			case "T*", "ET":
+				buf.WriteString("\n")
+			case "Td", "TD":
+				if len(numbers) >= 2 && numbers[len(numbers)-1] != 0 {
+					buf.WriteString("\n")
+				} else {
+					buf.WriteString(" ")
+				}
+			case "Tm":
+				// Text placed lower or higher starts a new line
+				if len(numbers) >= 6 && numbers[5] != lineY {
+					lineY = numbers[5]
+					buf.WriteString("\n")
+				} else {
+					buf.WriteString(" ")
+				}
+			}
+			operands, numbers = nil, nil
+		}
+	}
+}
+
+// readPDFLiteral reads the literal string starting at the ( at stream[start],
+// returning it decoded and the index after it
+func readPDFLiteral(stream []byte, start int) (string, int) {
+	var b []byte
+	depth := 0
+	i := start
+	for ; i < len(stream); i++ {
+		c := stream[i]
+		switch {
+		case c == '\\' && i+1 < len(stream):
+			i++
+			switch e := stream[i]; e {
+			case 'n':
+				b = append(b, '\n')
+			case 'r':
+				b = append(b, '\r')
+			case 't':
+				b = append(b, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					n := 0
+					for j := 0; j < 3 && i < len(stream) && stream[i] >= '0' && stream[i] <= '7'; j++ {
+						n = n*8 + int(stream[i]-'0')
+						i++
+					}
+					i--
+					b = append(b, byte(n))
+				} else {
+					b = append(b, e)
+				}
+			}
+		case c == '(':
+			if depth > 0 {
+				b = append(b, c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return decodePDFString(b), i + 1
+			}
+			b = append(b, c)
+		default:
+			b = append(b, c)
+		}
+	}
+	return decodePDFString(b), i
+}
+
+// decodePDFHex decodes a hex string, e.g. <48656C6C6F>
+func decodePDFHex(hex []byte) string {
+	var b []byte
+	var digits []byte
+	for _, c := range hex {
+		if unicode.Is(unicode.ASCII_Hex_Digit, rune(c)) {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	for i := 0; i < len(digits); i += 2 {
+		b = append(b, hexValue(digits[i])<<4|hexValue(digits[i+1]))
+	}
+	return decodePDFString(b)
+}
+
+func hexValue(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	default:
+		return c - '0'
+	}
+}
+
+// decodePDFString decodes a string's bytes as UTF-16 if it has a byte order
+// mark, otherwise as Latin-1, dropping control characters
+func decodePDFString(b []byte) string {
+	var runes []rune
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		runes = utf16.Decode(units)
+	} else {
+		for _, c := range b {
+			runes = append(runes, rune(c))
+		}
+	}
+
+	var s strings.Builder
+	for _, r := range runes {
+		if r == '\n' || r == '\t' || !unicode.IsControl(r) {
+			s.WriteRune(r)
+		}
+	}
+	return s.String()
+}
+
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', 0, '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+// parsePDFNumber parses a numeric operand such as 12, -3.5 or .25
+func parsePDFNumber(token string) (float64, bool) {
+	if token == "" || !strings.ContainsAny(token[:1], "+-.0123456789") {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(token, 64)
+	return n, err == nil
+}
@@ -0,0 +1,93 @@
+package sync
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// buildPDF returns a minimal PDF with one page per content stream
+func buildPDF(t *testing.T, compress bool, contents ...string) []byte {
+	t.Helper()
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	pdf.WriteString("1 0 obj\n<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>\nendobj\n")
+	for i, content := range contents {
+		stream := []byte(content)
+		dict := fmt.Sprintf("/Length %d", len(stream))
+		if compress {
+			var buf bytes.Buffer
+			w := zlib.NewWriter(&buf)
+			w.Write(stream)
+			w.Close()
+			stream = buf.Bytes()
+			dict = fmt.Sprintf("/Length %d /Filter /FlateDecode", len(stream))
+		}
+		fmt.Fprintf(&pdf, "%d 0 obj\n<< %s >>\nstream\n", i+2, dict)
+		pdf.Write(stream)
+		pdf.WriteString("\nendstream\nendobj\n")
+	}
+	pdf.WriteString("%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestPDFToText(t *testing.T) {
+	tests := []struct {
+		name     string
+		compress bool
+		contents []string
+		expected string
+	}{
+		{
+			name:     "Lines",
+			contents: []string{"BT /F1 12 Tf 72 720 Td (Design spec) Tj 0 -14 Td (Second line) Tj ET"},
+			expected: "Design spec\nSecond line",
+		},
+		{
+			name:     "Compressed pages",
+			compress: true,
+			contents: []string{"BT /F1 12 Tf (Page one) Tj ET", "BT /F1 12 Tf (Page two) Tj ET"},
+			expected: "Page one\nPage two",
+		},
+		{
+			name:     "Kerning and escapes",
+			contents: []string{`BT [(Hel) 20 (lo) -300 (world)] TJ T* (\(draft\) caf\351) Tj ET`},
+			expected: "Hello world\n(draft) café",
+		},
+		{
+			name:     "Hex strings",
+			contents: []string{"BT <48656C6C6F> Tj ET"},
+			expected: "Hello",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := PDFToText(buildPDF(t, tt.compress, tt.contents...))
+			if err != nil {
+				t.Fatalf("PDFToText() error = %v", err)
+			}
+			if text != tt.expected {
+				t.Errorf("PDFToText() = %q, want %q", text, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPDFToText_NoText(t *testing.T) {
+	tests := map[string]string{
+		"Drawing only":  "0 0 m 100 100 l S",
+		"Glyph numbers": "BT <0003001200150011> Tj ET",
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := PDFToText(buildPDF(t, false, content)); !errors.Is(err, ErrNoPDFText) {
+				t.Errorf("Expected ErrNoPDFText, got %v", err)
+			}
+		})
+	}
+}
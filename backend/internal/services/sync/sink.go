@@ -0,0 +1,47 @@
+package sync
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// maxTitleLength is the longest document title, in characters, that the
+// documents table stores
+const maxTitleLength = 255
+
+// DocumentSink receives the documents produced by a connector during a sync.
+// The production implementation is the services package's per-sync sink,
+// which passes each document to the ingestion service to be chunked, embedded
+// and stored.
+type DocumentSink interface {
+	// IngestDocument processes and stores a single document
+	IngestDocument(ctx context.Context, input models.CreateDocumentInput) (*models.Document, error)
+
+	// KeepDocument records that a document ingested by an earlier sync is
+	// unchanged in the source. Connectors that skip unchanged items call it
+	// instead of IngestDocument so the document isn't deleted as missing.
+	KeepDocument(externalID string)
+}
+
+// DryRunSink is implemented by sinks that only look at the documents a
+// connector produces, such as a sync preview's. Connectors don't change the
+// source, e.g. by joining Slack channels, when syncing into one.
+type DryRunSink interface {
+	DryRun() bool
+}
+
+// isDryRun reports whether a sync into sink must leave the source unchanged
+func isDryRun(sink DocumentSink) bool {
+	d, ok := sink.(DryRunSink)
+	return ok && d.DryRun()
+}
+
+// truncateTitle shortens a title to maxTitleLength characters
+func truncateTitle(title string) string {
+	if utf8.RuneCountInString(title) <= maxTitleLength {
+		return title
+	}
+	return string([]rune(title)[:maxTitleLength])
+}
@@ -0,0 +1,75 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	gosync "sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// mockSink implements DocumentSink for testing by collecting documents in memory
+type mockSink struct {
+	mu         gosync.Mutex
+	documents  []models.CreateDocumentInput
+	kept       []string
+	shouldFail bool
+}
+
+func newMockSink() *mockSink {
+	return &mockSink{}
+}
+
+func (m *mockSink) IngestDocument(ctx context.Context, input models.CreateDocumentInput) (*models.Document, error) {
+	if m.shouldFail {
+		return nil, errors.New("mock ingestion error")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.documents = append(m.documents, input)
+
+	return &models.Document{
+		ID:           uuid.New(),
+		InstanceID:   input.InstanceID,
+		DataSourceID: input.DataSourceID,
+		Title:        input.Title,
+		Content:      input.Content,
+		URL:          input.URL,
+		Type:         input.Type,
+		Metadata:     input.Metadata,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}, nil
+}
+
+func (m *mockSink) KeepDocument(externalID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.kept = append(m.kept, externalID)
+}
+
+// dryRunSink is a mockSink that reports a dry run, like a sync preview's
+type dryRunSink struct {
+	*mockSink
+}
+
+func (dryRunSink) DryRun() bool {
+	return true
+}
+
+// ingested returns a copy of the documents received so far
+func (m *mockSink) ingested() []models.CreateDocumentInput {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]models.CreateDocumentInput(nil), m.documents...)
+}
+
+// keptIDs returns a copy of the external IDs kept so far
+func (m *mockSink) keptIDs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.kept...)
+}
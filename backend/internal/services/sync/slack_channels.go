@@ -0,0 +1,144 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/Abraham12611/veritas/internal/logger"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// channelIDs returns the channels to sync: those listed in the config, then
+// those discovery finds
+func (s *SlackService) channelIDs(ctx context.Context, ds *models.DataSource, filters *slackFilters) ([]string, error) {
+	var ids []string
+	for _, id := range ds.Config.Channels {
+		if id = strings.TrimSpace(id); id != "" && !containsString(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if !filters.discover {
+		return ids, nil
+	}
+
+	discovered, err := s.discoverChannels(ctx, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover channels: %w", err)
+	}
+	for _, id := range discovered {
+		if !containsString(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// discoverChannels returns the channels the filters select. In public mode,
+// the app can only read selected channels it isn't in yet if auto_join is
+// set, in which case it joins them, except during a dry run.
+func (s *SlackService) discoverChannels(ctx context.Context, filters *slackFilters) ([]string, error) {
+	var ids []string
+	var cursor string
+	for {
+		channels, nextCursor, err := s.listChannels(ctx, filters, cursor, 200)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, channel := range channels {
+			if !filters.selectsChannel(channel) {
+				continue
+			}
+
+			if !channel.IsMember {
+				// Archived channels can't be joined, and previews don't join
+				if channel.IsArchived || !filters.autoJoin || isDryRun(s.sink) {
+					continue
+				}
+				if err := s.joinChannel(ctx, channel.ID); err != nil {
+					if ctx.Err() != nil {
+						return nil, ctx.Err()
+					}
+					logger.Error("Failed to join Slack channel", err, logger.Fields{
+						"channelId":   channel.ID,
+						"channelName": channel.Name,
+					})
+					continue
+				}
+			}
+
+			ids = append(ids, channel.ID)
+		}
+
+		if nextCursor == "" {
+			break
+		}
+		cursor = nextCursor
+	}
+
+	return ids, nil
+}
+
+// listChannels retrieves a page of the channels discovery looks at: those the
+// app is a member of, public or private, or all public channels
+func (s *SlackService) listChannels(ctx context.Context, filters *slackFilters, cursor string, limit int) ([]SlackChannel, string, error) {
+	method := "users.conversations"
+	params := url.Values{}
+	params.Set("types", "public_channel,private_channel")
+	if filters.membership == "public" {
+		method = "conversations.list"
+		params.Set("types", "public_channel")
+	}
+	params.Set("exclude_archived", fmt.Sprintf("%t", !filters.includeArchived))
+	params.Set("limit", fmt.Sprintf("%d", limit))
+	if cursor != "" {
+		params.Set("cursor", cursor)
+	}
+
+	var response struct {
+		OK               bool           `json:"ok"`
+		Error            string         `json:"error,omitempty"`
+		Channels         []SlackChannel `json:"channels"`
+		ResponseMetadata struct {
+			NextCursor string `json:"next_cursor"`
+		} `json:"response_metadata"`
+	}
+	if err := s.getJSON(ctx, method, params, &response); err != nil {
+		return nil, "", fmt.Errorf("failed to list channels: %w", err)
+	}
+
+	if !response.OK {
+		return nil, "", &SlackAPIError{Code: response.Error}
+	}
+
+	if method == "users.conversations" {
+		// Only the app's channels are listed, but is_member isn't set
+		for i := range response.Channels {
+			response.Channels[i].IsMember = true
+		}
+	}
+
+	return response.Channels, response.ResponseMetadata.NextCursor, nil
+}
+
+// joinChannel adds the app to a public channel
+func (s *SlackService) joinChannel(ctx context.Context, channelID string) error {
+	params := url.Values{}
+	params.Set("channel", channelID)
+
+	var response struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error,omitempty"`
+	}
+	if err := s.postForm(ctx, "conversations.join", params, &response); err != nil {
+		return fmt.Errorf("failed to join channel: %w", err)
+	}
+
+	if !response.OK {
+		return &SlackAPIError{Code: response.Error}
+	}
+
+	return nil
+}
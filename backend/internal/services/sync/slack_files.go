@@ -0,0 +1,261 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/Abraham12611/veritas/internal/httpx"
+	"github.com/Abraham12611/veritas/internal/logger"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// SlackFile represents a file shared in a message. Canvases are files too.
+type SlackFile struct {
+	ID                 string `json:"id"`
+	Name               string `json:"name"`
+	Title              string `json:"title"`
+	Mimetype           string `json:"mimetype"`
+	Filetype           string `json:"filetype"`
+	PrettyType         string `json:"pretty_type"`
+	User               string `json:"user"`
+	Mode               string `json:"mode"` // hosted, snippet, external, tombstone, ...
+	Size               int    `json:"size"`
+	URLPrivateDownload string `json:"url_private_download"`
+	Permalink          string `json:"permalink"`
+	FileAccess         string `json:"file_access,omitempty"` // check_file_info if the rest must be looked up
+}
+
+// kind returns which of slackFileTypes the file is, or "" if its content
+// can't be synced
+func (f SlackFile) kind() string {
+	ext := strings.ToLower(path.Ext(f.Name))
+	switch {
+	case f.Mode == "external" || f.Mode == "tombstone" || f.Mode == "hidden_by_limit":
+		return ""
+	case f.Filetype == "quip" || f.Filetype == "canvas":
+		return "canvas"
+	case f.Filetype == "pdf" || f.Mimetype == "application/pdf":
+		return "pdf"
+	case f.Filetype == "markdown" || ext == ".md" || ext == ".markdown":
+		return "markdown"
+	case f.Mode == "snippet" || f.Filetype == "text" || strings.HasPrefix(f.Mimetype, "text/"):
+		return "text"
+	default:
+		return ""
+	}
+}
+
+// slackFileExternalID returns the external ID of the document of a file
+// shared in a channel
+func slackFileExternalID(channelID, fileID string) string {
+	return slackExternalID(channelID, "file:"+fileID)
+}
+
+// keepConversation marks an unchanged conversation's document, and those of
+// the files shared in it, as still present
+func (s *SlackService) keepConversation(channelID string, conv slackConversation) {
+	s.sink.KeepDocument(conv.externalID)
+	for _, msg := range conv.messages {
+		for _, file := range msg.Files {
+			s.sink.KeepDocument(slackFileExternalID(channelID, file.ID))
+		}
+	}
+}
+
+// processFiles ingests the content of the files shared in a conversation as
+// documents of their own, linked to the message that shared them. Files of
+// other types, or larger than the filters allow, are skipped. So are files
+// that can't be synced, e.g. without the files:read scope, so they don't
+// hold back the channel; only cancellation is returned.
+func (s *SlackService) processFiles(ctx context.Context, ds *models.DataSource, channel *SlackChannel, conv slackConversation, filters *slackFilters) error {
+	for _, msg := range conv.messages {
+		for _, file := range msg.Files {
+			err := s.syncFile(ctx, ds, channel, conv, msg, file, filters)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				logger.Error("Failed to sync Slack file", err, logger.Fields{
+					"channelId": channel.ID,
+					"fileId":    file.ID,
+				})
+
+				// Keep a document synced before until the file syncs again
+				s.sink.KeepDocument(slackFileExternalID(channel.ID, file.ID))
+			}
+		}
+	}
+	return nil
+}
+
+// syncFile ingests the content of a file shared in message msg, unless the
+// filters skip it
+func (s *SlackService) syncFile(ctx context.Context, ds *models.DataSource, channel *SlackChannel, conv slackConversation, msg SlackMessage, file SlackFile, filters *slackFilters) error {
+	if file.FileAccess == "check_file_info" {
+		info, err := s.getFileInfo(ctx, file.ID)
+		var apiErr *SlackAPIError
+		if errors.As(err, &apiErr) && (apiErr.Code == "file_not_found" || apiErr.Code == "file_deleted") {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get file %s: %w", file.ID, err)
+		}
+		file = *info
+	}
+
+	kind := file.kind()
+	if !filters.fileTypes[kind] || file.Size > filters.maxFileSize || file.URLPrivateDownload == "" {
+		return nil
+	}
+
+	return s.processFile(ctx, ds, channel, conv, msg, file, kind, filters.maxFileSize)
+}
+
+// processFile ingests the content of a file shared in message msg. Failures
+// to ingest are logged, since the document is marked as seen either way.
+func (s *SlackService) processFile(ctx context.Context, ds *models.DataSource, channel *SlackChannel, conv slackConversation, msg SlackMessage, file SlackFile, kind string, maxSize int) error {
+	data, err := s.downloadFile(ctx, file, maxSize)
+	if errors.Is(err, errFileTooLarge) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download file %s: %w", file.ID, err)
+	}
+
+	docType := kind
+	var content string
+	switch kind {
+	case "pdf":
+		if content, err = PDFToText(data); err != nil {
+			logger.Debug("Skipping PDF without text", logger.Fields{
+				"fileId": file.ID,
+				"name":   file.Name,
+			})
+			return nil
+		}
+	case "canvas":
+		if content, err = HTMLToText(string(data)); err != nil {
+			return fmt.Errorf("failed to convert canvas %s: %w", file.ID, err)
+		}
+		docType = "text"
+	default:
+		content = strings.ToValidUTF8(string(data), "")
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil
+	}
+
+	title := file.Title
+	if title == "" {
+		title = file.Name
+	}
+	if title == "" {
+		title = file.ID
+	}
+	var author string
+	if file.User != "" {
+		author = s.userName(ctx, file.User)
+	}
+
+	input := models.CreateDocumentInput{
+		InstanceID:   ds.InstanceID,
+		DataSourceID: ds.ID,
+		Title:        truncateTitle(title),
+		Content:      content,
+		URL:          file.Permalink,
+		Type:         docType,
+		Metadata: models.Metadata{
+			Author:     author,
+			SourcePath: fmt.Sprintf("/channels/%s/files/%s", channel.ID, file.ID),
+			ExternalID: slackFileExternalID(channel.ID, file.ID),
+			Extra: map[string]interface{}{
+				"channelId":         channel.ID,
+				"channelName":       channel.Name,
+				"fileId":            file.ID,
+				"fileName":          file.Name,
+				"fileType":          kind,
+				"messageExternalId": conv.externalID,
+				"messageUrl":        slackPermalink(channel.ID, msg),
+			},
+		},
+	}
+
+	if _, err := s.sink.IngestDocument(ctx, input); err != nil && ctx.Err() == nil {
+		logger.Error("Failed to ingest Slack file", err, logger.Fields{
+			"channelId": channel.ID,
+			"fileId":    file.ID,
+		})
+	}
+
+	return nil
+}
+
+// errFileTooLarge is returned by downloadFile for files over the size limit
+var errFileTooLarge = errors.New("file too large")
+
+// downloadFile downloads a file's content, up to maxSize bytes
+func (s *SlackService) downloadFile(ctx context.Context, file SlackFile, maxSize int) ([]byte, error) {
+	var data []byte
+	err := s.withRetry(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, "GET", file.URLPrivateDownload, nil)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Authorization", "Bearer "+s.token)
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		if err := httpx.CheckResponse(resp); err != nil {
+			return err
+		}
+
+		// Without the files:read scope Slack serves its sign-in page instead
+		if file.kind() != "canvas" && strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") &&
+			!strings.HasPrefix(file.Mimetype, "text/html") {
+			return fmt.Errorf("got a web page instead of the file; the token needs the files:read scope")
+		}
+
+		data, err = io.ReadAll(io.LimitReader(resp.Body, int64(maxSize)+1))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > maxSize {
+		return nil, errFileTooLarge
+	}
+	return data, nil
+}
+
+// getFileInfo retrieves a file's details
+func (s *SlackService) getFileInfo(ctx context.Context, fileID string) (*SlackFile, error) {
+	params := url.Values{}
+	params.Set("file", fileID)
+
+	var response struct {
+		OK    bool      `json:"ok"`
+		Error string    `json:"error,omitempty"`
+		File  SlackFile `json:"file"`
+	}
+	if err := s.getJSON(ctx, "files.info", params, &response); err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	if !response.OK {
+		return nil, &SlackAPIError{Code: response.Error}
+	}
+
+	return &response.File, nil
+}
@@ -0,0 +1,139 @@
+package sync
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// slackDefaultMaxFileSize is the largest shared file synced when the config
+// doesn't set max_file_size. Specs shared as PDFs are often a few megabytes.
+const slackDefaultMaxFileSize = 10 << 20
+
+// slackFileTypes are the kinds of shared files whose content can be synced
+var slackFileTypes = []string{"text", "markdown", "pdf", "canvas"}
+
+// slackFilters are the Slack settings in Config.Filters:
+//
+//	conversation_window   group top-level messages into one document per
+//	                      window of this length, e.g. "1h". Otherwise each
+//	                      top-level message is its own document. Threads
+//	                      are always one document.
+//	file_types            kinds of shared files to sync as documents of
+//	                      their own: text, markdown, pdf and canvas. All by
+//	                      default; an empty list syncs no files.
+//	max_file_size         largest shared file to sync, in bytes
+//	include               glob patterns of the names of channels to discover,
+//	                      e.g. "help-*"; patterns starting with ! exclude
+//	exclude               glob patterns of the names of channels not to
+//	                      discover
+//	membership            discover the channels the app is a "member" of,
+//	                      the default, or all "public" channels
+//	auto_join             join discovered public channels the app isn't in,
+//	                      so they can be read. Slack posts a message in each
+//	                      channel joined; otherwise they're skipped.
+//	include_archived      also discover archived channels
+//
+// Setting include, exclude or membership turns on discovery: the matching
+// channels are found again on every sync and synced along with Channels.
+// Documents of channels no longer found are deleted.
+type slackFilters struct {
+	conversationWindow time.Duration
+	fileTypes          map[string]bool
+	maxFileSize        int
+
+	discover        bool
+	include         []*regexp.Regexp
+	exclude         []*regexp.Regexp
+	membership      string
+	autoJoin        bool
+	includeArchived bool
+}
+
+// parseSlackFilters reads the Slack settings from Config.Filters
+func parseSlackFilters(filters map[string]interface{}) (*slackFilters, error) {
+	f := &slackFilters{fileTypes: make(map[string]bool)}
+
+	window, err := filterString(filters, "conversation_window")
+	if err != nil {
+		return nil, err
+	}
+	if window != "" {
+		if f.conversationWindow, err = time.ParseDuration(window); err != nil {
+			return nil, fmt.Errorf("filters.conversation_window must be a duration such as \"1h\"")
+		}
+		if f.conversationWindow < time.Minute {
+			return nil, fmt.Errorf("filters.conversation_window must be at least a minute")
+		}
+	}
+
+	fileTypes := slackFileTypes
+	if _, ok := filters["file_types"]; ok {
+		if fileTypes, err = filterStrings(filters, "file_types"); err != nil {
+			return nil, err
+		}
+	}
+	for _, fileType := range fileTypes {
+		if !containsString(slackFileTypes, fileType) {
+			return nil, fmt.Errorf("filters.file_types must only list %s", strings.Join(slackFileTypes, ", "))
+		}
+		f.fileTypes[fileType] = true
+	}
+
+	if f.maxFileSize, err = filterSize(filters, "max_file_size", slackDefaultMaxFileSize); err != nil {
+		return nil, err
+	}
+
+	if f.include, f.exclude, err = filterPatterns(filters); err != nil {
+		return nil, err
+	}
+	if f.membership, err = filterString(filters, "membership"); err != nil {
+		return nil, err
+	}
+	f.discover = len(f.include) > 0 || len(f.exclude) > 0 || f.membership != ""
+	switch f.membership {
+	case "":
+		f.membership = "member"
+	case "member", "public":
+	default:
+		return nil, fmt.Errorf("filters.membership must be member or public")
+	}
+	if f.autoJoin, err = filterBool(filters, "auto_join"); err != nil {
+		return nil, err
+	}
+	if f.includeArchived, err = filterBool(filters, "include_archived"); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// selectsChannel reports whether discovery picks a channel: its name matches
+// the patterns, and it's one the app is a member of, or public
+func (f *slackFilters) selectsChannel(channel SlackChannel) bool {
+	if !f.discover || channel.IsArchived && !f.includeArchived {
+		return false
+	}
+	if f.membership == "member" && !channel.IsMember || f.membership == "public" && channel.IsPrivate {
+		return false
+	}
+	if len(f.include) > 0 && !matchAny(f.include, channel.Name) {
+		return false
+	}
+	return !matchAny(f.exclude, channel.Name)
+}
+
+// windowKey returns the key of the conversation window containing the
+// message posted at Slack timestamp ts, e.g. "1622505600-1622509200", or ""
+// if top-level messages aren't grouped
+func (f *slackFilters) windowKey(ts string) string {
+	if f.conversationWindow == 0 {
+		return ""
+	}
+
+	seconds := slackTSSeconds(ts)
+	length := int64(f.conversationWindow / time.Second)
+	start := seconds - seconds%length
+	return fmt.Sprintf("%d-%d", start, start+length)
+}
@@ -2,21 +2,37 @@ package sync
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/Abraham12611/veritas/internal/httpx"
 	"github.com/Abraham12611/veritas/internal/logger"
 	"github.com/Abraham12611/veritas/internal/models"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/time/rate"
 )
 
+const (
+	// slackThreadLookback is how far before the newest synced message an
+	// incremental sync looks for threads with new replies. Replies to older
+	// threads are found by the next full pass.
+	slackThreadLookback = 7 * 24 * time.Hour
+
+	// slackFullSyncInterval is how often a sync lists the whole history of
+	// every channel, to find new replies to old threads
+	slackFullSyncInterval = 7 * 24 * time.Hour
+
+	// slackFullSyncKey is the checkpoint entry holding when the last full
+	// pass started
+	slackFullSyncKey = "#fullsync"
+)
+
 // SlackService handles syncing content from Slack
 type SlackService struct {
 	client      *http.Client
@@ -25,17 +41,20 @@ type SlackService struct {
 	token       string
 	concurrency int
 	baseURL     string
+	sink        DocumentSink
+	directory   *slackDirectory
 }
 
 // NewSlackService creates a new Slack sync service
-func NewSlackService(token string) *SlackService {
+func NewSlackService(token string, sink DocumentSink) *SlackService {
 	// Create HTTP client with reasonable timeouts
 	client := &http.Client{
 		Timeout: 30 * time.Second,
 	}
 
-	// Create rate limiter: 20 requests per minute (Slack's Tier 3 limit)
-	limiter := rate.NewLimiter(rate.Every(time.Minute/20), 1)
+	// Rate limiter shared by all syncs with the same token: 20 requests per
+	// minute (Slack's Tier 3 limit)
+	limiter := httpx.SharedLimiter("slack", token, rate.Every(time.Minute/20), 1)
 
 	return &SlackService{
 		client:      client,
@@ -44,101 +63,263 @@ func NewSlackService(token string) *SlackService {
 		token:       token,
 		concurrency: 5, // Process 5 channels concurrently
 		baseURL:     "https://slack.com/api",
+		sink:        sink,
+		directory:   newSlackDirectory(),
 	}
 }
 
 // withRetry executes a function with retries and rate limiting
 func (s *SlackService) withRetry(ctx context.Context, operation func() error) error {
-	var lastErr error
-	for attempt := 0; attempt <= s.maxRetries; attempt++ {
-		// Wait for rate limiter
-		if err := s.limiter.Wait(ctx); err != nil {
-			return fmt.Errorf("rate limiter error: %w", err)
+	return httpx.Retry(ctx, s.limiter, httpx.RetryPolicy{MaxRetries: s.maxRetries}, operation)
+}
+
+// getJSON calls a Web API method with the given query parameters and
+// decodes the JSON response
+func (s *SlackService) getJSON(ctx context.Context, method string, params url.Values, out interface{}) error {
+	endpoint := fmt.Sprintf("%s/%s?%s", s.baseURL, method, params.Encode())
+
+	return s.withRetry(ctx, func() error {
+		// A request can only be sent once, so build one per attempt
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return err
 		}
 
-		// Execute operation
-		if err := operation(); err != nil {
-			lastErr = err
-			// Check if error is retryable
-			if isRetryableError(err) {
-				// Exponential backoff
-				backoff := time.Duration(attempt*attempt) * time.Second
-				select {
-				case <-time.After(backoff):
-					continue
-				case <-ctx.Done():
-					return ctx.Err()
-				}
-			}
-			return err // Non-retryable error
+		req.Header.Set("Authorization", "Bearer "+s.token)
+
+		return httpx.DoJSON(s.client, req, out)
+	})
+}
+
+// postForm calls a Web API method that changes something, with the given
+// form parameters, and decodes the JSON response
+func (s *SlackService) postForm(ctx context.Context, method string, params url.Values, out interface{}) error {
+	endpoint := fmt.Sprintf("%s/%s", s.baseURL, method)
+	body := params.Encode()
+
+	return s.withRetry(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(body))
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set("Authorization", "Bearer "+s.token)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		return httpx.DoJSON(s.client, req, out)
+	})
+}
+
+// slackConnector adapts SlackService to the Connector interface
+type slackConnector struct{}
+
+func init() {
+	Register(slackConnector{})
+}
+
+func (slackConnector) Type() string {
+	return "slack"
+}
+
+func (slackConnector) Validate(cfg models.Config) error {
+	filters, err := parseSlackFilters(cfg.Filters)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	r := requiredFields{dsType: "slack"}
+	r.require("api_token", cfg.APIToken)
+	if !filters.discover {
+		r.requireList("channels", cfg.Channels)
+	}
+	return r.err()
+}
+
+func (slackConnector) TestConnection(ctx context.Context, ds *models.DataSource) error {
+	s := NewSlackService(ds.Config.APIToken, nil)
+	s.maxRetries = 0
+	return s.TestConnection(ctx, ds)
+}
+
+func (slackConnector) Sync(ctx context.Context, ds *models.DataSource, sink DocumentSink, checkpoint Checkpoint) (*SyncResult, error) {
+	next, err := NewSlackService(ds.Config.APIToken, sink).SyncChannels(ctx, ds, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only a full pass sees every message, so only then can messages missing
+	// from it be deleted. Each full pass records when it started.
+	return &SyncResult{Checkpoint: next, Complete: next[slackFullSyncKey] != checkpoint[slackFullSyncKey]}, nil
+}
+
+// TestConnection checks that every configured channel can be read with the
+// token, and that channels can be listed if they're discovered
+func (s *SlackService) TestConnection(ctx context.Context, ds *models.DataSource) error {
+	for _, channelID := range ds.Config.Channels {
+		if _, err := s.getChannelInfo(ctx, channelID); err != nil {
+			return fmt.Errorf("failed to get channel %s: %w", channelID, err)
 		}
-		return nil // Success
 	}
-	return fmt.Errorf("max retries exceeded: %w", lastErr)
+
+	filters, err := parseSlackFilters(ds.Config.Filters)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	if filters.discover {
+		if _, _, err := s.listChannels(ctx, filters, "", 1); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-// SyncChannels syncs content from specified Slack channels
-func (s *SlackService) SyncChannels(ctx context.Context, ds *models.DataSource) error {
+// SyncChannels syncs content from the configured and discovered Slack
+// channels. The checkpoint maps each channel to its newest synced message,
+// and each thread to its latest reply. Channels in it are synced from
+// shortly before their newest message instead of from the start of their
+// history; newly discovered channels are synced in full. Once a week, or
+// when a channel synced before is no longer selected, every channel's whole
+// history is listed again, so threads with new replies are fetched however
+// old they are, and documents of channels no longer synced are deleted.
+func (s *SlackService) SyncChannels(ctx context.Context, ds *models.DataSource, checkpoint Checkpoint) (Checkpoint, error) {
+	started := time.Now().UTC()
+
+	filters, err := parseSlackFilters(ds.Config.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	// A missing or unreadable time makes a full pass due
+	lastFull, _ := time.Parse(time.RFC3339Nano, checkpoint[slackFullSyncKey])
+	full := started.Sub(lastFull) >= slackFullSyncInterval
+
+	channelIDs, err := s.channelIDs(ctx, ds, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	// Channels that were archived, left or renamed out of the filters need
+	// a full pass for their documents to be deleted
+	for key := range checkpoint {
+		if !strings.HasPrefix(key, "#") && !strings.Contains(key, "/") && !containsString(channelIDs, key) {
+			full = true
+		}
+	}
+
 	logger.Info("Starting Slack channel sync", logger.Fields{
 		"dataSourceId": ds.ID,
-		"channels":     ds.Config.Channels,
+		"channels":     channelIDs,
+		"full":         full,
 	})
 
 	// Create error group for concurrent processing
 	g, ctx := errgroup.WithContext(ctx)
 	g.SetLimit(s.concurrency)
 
+	var mu sync.Mutex
+	next := make(Checkpoint)
+
 	// Process each channel
-	for _, channelID := range ds.Config.Channels {
+	for _, channelID := range channelIDs {
 		channelID := channelID // Create new variable for goroutine
 		g.Go(func() error {
-			return s.syncChannel(ctx, ds, channelID)
+			channelNext, err := s.syncChannel(ctx, ds, channelID, checkpoint, full)
+			if err != nil {
+				return err
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for key, ts := range channelNext {
+				next[key] = ts
+			}
+			return nil
 		})
 	}
 
 	// Wait for all channels to be processed
 	if err := g.Wait(); err != nil {
-		return fmt.Errorf("error during sync: %w", err)
+		return nil, fmt.Errorf("error during sync: %w", err)
+	}
+
+	if full {
+		next[slackFullSyncKey] = started.Format(time.RFC3339Nano)
+	} else if lastFull, ok := checkpoint[slackFullSyncKey]; ok {
+		next[slackFullSyncKey] = lastFull
 	}
 
 	logger.Info("Completed Slack channel sync", logger.Fields{
 		"dataSourceId": ds.ID,
-		"channels":     ds.Config.Channels,
+		"channels":     channelIDs,
 	})
 
-	return nil
+	return next, nil
+}
+
+// slackConversation is the messages synced as one document: a thread, the
+// top-level messages of a conversation window, or a single top-level message
+type slackConversation struct {
+	externalID string
+	messages   []SlackMessage // oldest first
 }
 
-// syncChannel syncs content from a single channel
-func (s *SlackService) syncChannel(ctx context.Context, ds *models.DataSource, channelID string) error {
+// syncChannel syncs content from a single channel and returns its entries for
+// the next checkpoint. Conversations without messages posted or edited since
+// the last sync aren't ingested again. A full pass lists the channel's whole
+// history rather than only its recent messages.
+func (s *SlackService) syncChannel(ctx context.Context, ds *models.DataSource, channelID string, checkpoint Checkpoint, full bool) (Checkpoint, error) {
+	filters, err := parseSlackFilters(ds.Config.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
 	// Get channel info
 	channel, err := s.getChannelInfo(ctx, channelID)
 	if err != nil {
-		return fmt.Errorf("failed to get channel info: %w", err)
+		return nil, fmt.Errorf("failed to get channel info: %w", err)
+	}
+
+	newest := checkpoint[channelID]
+	var oldest string
+	if newest != "" && !full {
+		oldest = slackTSBefore(newest, slackThreadLookback)
+
+		// Start at a window boundary, so the first window is complete
+		if start, _, ok := strings.Cut(filters.windowKey(oldest), "-"); ok {
+			oldest = start + ".000000"
+		}
+	}
+
+	// Written by the fetcher only
+	next := make(Checkpoint)
+	if newest != "" {
+		next[channelID] = newest
 	}
+	var failed int32
 
-	// Create error group for concurrent message processing
+	// Create error group for concurrent conversation processing
 	g, ctx := errgroup.WithContext(ctx)
-	g.SetLimit(s.concurrency)
+	g.SetLimit(s.concurrency + 1) // Conversation workers plus the fetcher
 
-	// Channel for messages to process
-	messagesChan := make(chan SlackMessage, s.concurrency*2)
+	// Channel for conversations to process
+	conversations := make(chan slackConversation, s.concurrency*2)
 
-	// Start message processor workers
+	// Start conversation processor workers
 	var processWg sync.WaitGroup
 	processWg.Add(s.concurrency)
 	for i := 0; i < s.concurrency; i++ {
 		g.Go(func() error {
 			defer processWg.Done()
-			for msg := range messagesChan {
+			for conv := range conversations {
 				select {
 				case <-ctx.Done():
 					return ctx.Err()
 				default:
-					if err := s.processMessage(ctx, ds, channel, msg); err != nil {
-						logger.Error("Failed to process message", err, logger.Fields{
-							"channelId": channelID,
-							"messageTs": msg.Timestamp,
+					if err := s.processConversation(ctx, ds, channel, conv, filters); err != nil {
+						atomic.StoreInt32(&failed, 1)
+						logger.Error("Failed to process conversation", err, logger.Fields{
+							"channelId":  channelID,
+							"externalId": conv.externalID,
 						})
 						continue
 					}
@@ -148,43 +329,82 @@ func (s *SlackService) syncChannel(ctx context.Context, ds *models.DataSource, c
 		})
 	}
 
+	queue := func(conv slackConversation) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case conversations <- conv:
+			return nil
+		}
+	}
+
+	// send queues a conversation for processing if any of its messages was
+	// posted or edited since the last sync
+	send := func(conv slackConversation) error {
+		for _, msg := range conv.messages {
+			if msg.changedSince(newest) {
+				return queue(conv)
+			}
+		}
+		s.keepConversation(channelID, conv)
+		return nil
+	}
+
 	// Start message fetcher
 	g.Go(func() error {
-		defer close(messagesChan)
+		defer close(conversations)
+
+		// Top-level messages of the conversation window being collected,
+		// newest first
+		var window []SlackMessage
+		var windowKey string
+		flush := func() error {
+			if len(window) == 0 {
+				return nil
+			}
+			conv := slackConversation{externalID: slackExternalID(channelID, windowKey)}
+			for i := len(window) - 1; i >= 0; i-- {
+				conv.messages = append(conv.messages, window[i])
+			}
+			window = nil
+			return send(conv)
+		}
 
 		var cursor string
 		for {
-			messages, nextCursor, err := s.getMessages(ctx, channelID, cursor)
+			messages, nextCursor, err := s.getMessages(ctx, channelID, oldest, "", cursor)
 			if err != nil {
 				return fmt.Errorf("failed to get messages: %w", err)
 			}
 
-			// Send messages to processor
 			for _, msg := range messages {
-				select {
-				case <-ctx.Done():
-					return ctx.Err()
-				case messagesChan <- msg:
+				if next[channelID] == "" || slackTSAfter(msg.Timestamp, next[channelID]) {
+					next[channelID] = msg.Timestamp
 				}
 
-				// If message has thread, process replies
-				if msg.ThreadTimestamp != "" && msg.ThreadTimestamp == msg.Timestamp {
-					replies, err := s.getThreadReplies(ctx, channelID, msg.Timestamp)
-					if err != nil {
-						logger.Error("Failed to get thread replies", err, logger.Fields{
-							"channelId": channelID,
-							"threadTs":  msg.Timestamp,
-						})
-						continue
+				switch {
+				case msg.isThreadParent():
+					if err := s.sendThread(ctx, channelID, msg, checkpoint, next, queue); err != nil {
+						return err
 					}
-
-					for _, reply := range replies {
-						select {
-						case <-ctx.Done():
-							return ctx.Err()
-						case messagesChan <- reply:
+				case msg.ThreadTimestamp != "":
+					// Replies also sent to the channel are part of their thread
+				case filters.conversationWindow == 0:
+					conv := slackConversation{
+						externalID: slackExternalID(channelID, msg.Timestamp),
+						messages:   []SlackMessage{msg},
+					}
+					if err := send(conv); err != nil {
+						return err
+					}
+				default:
+					if key := filters.windowKey(msg.Timestamp); key != windowKey {
+						if err := flush(); err != nil {
+							return err
 						}
+						windowKey = key
 					}
+					window = append(window, msg)
 				}
 			}
 
@@ -195,82 +415,119 @@ func (s *SlackService) syncChannel(ctx context.Context, ds *models.DataSource, c
 			cursor = nextCursor
 		}
 
-		return nil
+		return flush()
 	})
 
 	// Wait for all goroutines to complete
 	if err := g.Wait(); err != nil {
-		return fmt.Errorf("error during channel sync: %w", err)
+		return nil, fmt.Errorf("error during channel sync: %w", err)
 	}
 
-	return nil
+	// Go over the same messages next time rather than skip what failed
+	if atomic.LoadInt32(&failed) != 0 {
+		kept := make(Checkpoint)
+		for key, ts := range checkpoint {
+			if key == channelID || strings.HasPrefix(key, channelID+"/") {
+				kept[key] = ts
+			}
+		}
+		return kept, nil
+	}
+
+	// Threads started before the messages listed keep their latest reply,
+	// so the next full pass only fetches those with new replies
+	if !full {
+		for key, ts := range checkpoint {
+			if _, ok := next[key]; !ok && strings.HasPrefix(key, channelID+"/") {
+				next[key] = ts
+			}
+		}
+	}
+
+	return next, nil
 }
 
-// getChannelInfo retrieves channel information
-func (s *SlackService) getChannelInfo(ctx context.Context, channelID string) (*SlackChannel, error) {
-	endpoint := fmt.Sprintf("%s/conversations.info", s.baseURL)
-	
-	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+// sendThread queues the thread started by parent for processing if it got
+// replies since the last sync, and records its latest reply in next
+func (s *SlackService) sendThread(ctx context.Context, channelID string, parent SlackMessage, checkpoint, next Checkpoint, queue func(slackConversation) error) error {
+	externalID := slackExternalID(channelID, parent.Timestamp)
+	threadKey := channelID + "/" + parent.Timestamp
+
+	unchanged := slackConversation{externalID: externalID, messages: []SlackMessage{parent}}
+
+	latestReply := checkpoint[threadKey]
+	if latestReply != "" {
+		next[threadKey] = latestReply
+		if !slackTSAfter(parent.LatestReply, latestReply) && !parent.changedSince(checkpoint[channelID]) {
+			s.keepConversation(channelID, unchanged)
+			return nil
+		}
+	}
+
+	thread, err := s.getThread(ctx, channelID, parent.Timestamp)
 	if err != nil {
-		return nil, err
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		logger.Error("Failed to get thread replies", err, logger.Fields{
+			"channelId": channelID,
+			"threadTs":  parent.Timestamp,
+		})
+		s.keepConversation(channelID, unchanged)
+		return nil
+	}
+	if len(thread) == 0 {
+		return nil
 	}
 
-	q := req.URL.Query()
-	q.Add("channel", channelID)
-	req.URL.RawQuery = q.Encode()
+	if err := queue(slackConversation{externalID: externalID, messages: thread}); err != nil {
+		return err
+	}
+	if parent.LatestReply != "" {
+		next[threadKey] = parent.LatestReply
+	}
+	return nil
+}
 
-	req.Header.Set("Authorization", "Bearer "+s.token)
+// getChannelInfo retrieves channel information
+func (s *SlackService) getChannelInfo(ctx context.Context, channelID string) (*SlackChannel, error) {
+	params := url.Values{}
+	params.Set("channel", channelID)
 
 	var response struct {
 		OK      bool         `json:"ok"`
 		Error   string       `json:"error,omitempty"`
 		Channel SlackChannel `json:"channel"`
 	}
-
-	err = s.withRetry(ctx, func() error {
-		resp, err := s.client.Do(req)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("failed to get channel info: status %d: %s", resp.StatusCode, string(body))
-		}
-
-		return json.NewDecoder(resp.Body).Decode(&response)
-	})
-
-	if err != nil {
-		return nil, err
+	if err := s.getJSON(ctx, "conversations.info", params, &response); err != nil {
+		return nil, fmt.Errorf("failed to get channel info: %w", err)
 	}
 
 	if !response.OK {
-		return nil, fmt.Errorf("slack API error: %s", response.Error)
+		return nil, &SlackAPIError{Code: response.Error}
 	}
 
 	return &response.Channel, nil
 }
 
-// getMessages retrieves messages from a channel
-func (s *SlackService) getMessages(ctx context.Context, channelID string, cursor string) ([]SlackMessage, string, error) {
-	endpoint := fmt.Sprintf("%s/conversations.history", s.baseURL)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
-	if err != nil {
-		return nil, "", err
+// getMessages retrieves messages from a channel, newest first. If oldest or
+// latest are set, only messages posted between them are returned.
+func (s *SlackService) getMessages(ctx context.Context, channelID, oldest, latest, cursor string) ([]SlackMessage, string, error) {
+	params := url.Values{}
+	params.Set("channel", channelID)
+	params.Set("limit", "100")
+	if oldest != "" {
+		params.Set("oldest", oldest)
+	}
+	if latest != "" {
+		params.Set("latest", latest)
+	}
+	if oldest != "" || latest != "" {
+		params.Set("inclusive", "true")
 	}
-
-	q := req.URL.Query()
-	q.Add("channel", channelID)
-	q.Add("limit", "100")
 	if cursor != "" {
-		q.Add("cursor", cursor)
+		params.Set("cursor", cursor)
 	}
-	req.URL.RawQuery = q.Encode()
-
-	req.Header.Set("Authorization", "Bearer "+s.token)
 
 	var response struct {
 		OK                bool           `json:"ok"`
@@ -280,90 +537,133 @@ func (s *SlackService) getMessages(ctx context.Context, channelID string, cursor
 			NextCursor string `json:"next_cursor"`
 		} `json:"response_metadata"`
 	}
-
-	err = s.withRetry(ctx, func() error {
-		resp, err := s.client.Do(req)
-		if err != nil {
-			return err
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("failed to get messages: status %d: %s", resp.StatusCode, string(body))
-		}
-
-		return json.NewDecoder(resp.Body).Decode(&response)
-	})
-
-	if err != nil {
-		return nil, "", err
+	if err := s.getJSON(ctx, "conversations.history", params, &response); err != nil {
+		return nil, "", fmt.Errorf("failed to get messages: %w", err)
 	}
 
 	if !response.OK {
-		return nil, "", fmt.Errorf("slack API error: %s", response.Error)
+		return nil, "", &SlackAPIError{Code: response.Error}
 	}
 
 	return response.Messages, response.ResponseMetadata.NextCursor, nil
 }
 
-// getThreadReplies retrieves replies in a message thread
-func (s *SlackService) getThreadReplies(ctx context.Context, channelID string, threadTS string) ([]SlackMessage, error) {
-	endpoint := fmt.Sprintf("%s/conversations.replies", s.baseURL)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	q := req.URL.Query()
-	q.Add("channel", channelID)
-	q.Add("ts", threadTS)
-	req.URL.RawQuery = q.Encode()
-
-	req.Header.Set("Authorization", "Bearer "+s.token)
+// getThread retrieves a thread's parent message followed by its replies, or
+// returns nil if the message doesn't exist. A message without replies is
+// returned on its own.
+func (s *SlackService) getThread(ctx context.Context, channelID string, threadTS string) ([]SlackMessage, error) {
+	params := url.Values{}
+	params.Set("channel", channelID)
+	params.Set("ts", threadTS)
 
 	var response struct {
 		OK       bool           `json:"ok"`
 		Error    string         `json:"error,omitempty"`
 		Messages []SlackMessage `json:"messages"`
 	}
+	if err := s.getJSON(ctx, "conversations.replies", params, &response); err != nil {
+		return nil, fmt.Errorf("failed to get thread replies: %w", err)
+	}
 
-	err = s.withRetry(ctx, func() error {
-		resp, err := s.client.Do(req)
-		if err != nil {
-			return err
+	if !response.OK {
+		if response.Error == "thread_not_found" || response.Error == "message_not_found" {
+			return nil, nil
 		}
-		defer resp.Body.Close()
+		return nil, &SlackAPIError{Code: response.Error}
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return fmt.Errorf("failed to get thread replies: status %d: %s", resp.StatusCode, string(body))
-		}
+	return response.Messages, nil
+}
 
-		return json.NewDecoder(resp.Body).Decode(&response)
+// processConversation ingests a conversation as one document. Each message is
+// a turn attributed to its speaker, anchored to the message's permalink. The
+// files shared in it are documents of their own.
+func (s *SlackService) processConversation(ctx context.Context, ds *models.DataSource, channel *SlackChannel, conv slackConversation, filters *slackFilters) error {
+	logger.Debug("Processing Slack conversation", logger.Fields{
+		"channelId":  channel.ID,
+		"externalId": conv.externalID,
+		"messages":   len(conv.messages),
 	})
 
-	if err != nil {
-		return nil, err
+	// Build conversation content, with mentions and speakers by name
+	var content strings.Builder
+	var anchors []models.Anchor
+	var author, summary string
+	var participants []string
+	hasAttachments := false
+	for i, msg := range conv.messages {
+		if i > 0 {
+			content.WriteString("\n\n")
+		}
+		anchors = append(anchors, models.Anchor{
+			Offset: content.Len(),
+			URL:    slackPermalink(channel.ID, msg),
+		})
+
+		speaker := s.speaker(ctx, msg)
+		msg.Text = s.resolveReferences(ctx, msg.Text)
+		writeSlackMessage(&content, speaker, msg)
+
+		if i == 0 {
+			author, summary = speaker, slackSummary(msg.Text)
+		}
+		if !containsString(participants, speaker) {
+			participants = append(participants, speaker)
+		}
+		hasAttachments = hasAttachments || len(msg.Attachments) > 0
 	}
 
-	if !response.OK {
-		return nil, fmt.Errorf("slack API error: %s", response.Error)
+	first := conv.messages[0]
+	kind := "Message"
+	switch {
+	case first.isThreadParent() || len(conv.messages) > 1 && conv.messages[1].ThreadTimestamp == first.Timestamp:
+		kind = "Thread"
+	case len(conv.messages) > 1:
+		kind = "Conversation"
+	}
+	title := fmt.Sprintf("%s in #%s at %s", kind, channel.Name, first.Timestamp)
+	if summary != "" {
+		title = fmt.Sprintf("%s in #%s: %s", kind, channel.Name, summary)
 	}
+	_, key, _ := strings.Cut(conv.externalID, ":")
 
-	return response.Messages[1:], nil // Skip the parent message
-}
+	// Create document input
+	input := models.CreateDocumentInput{
+		InstanceID:   ds.InstanceID,
+		DataSourceID: ds.ID,
+		Title:        title,
+		Content:      content.String(),
+		URL:          anchors[0].URL,
+		Type:         "slack",
+		Metadata: models.Metadata{
+			Author:     author,
+			SourcePath: fmt.Sprintf("/channels/%s/messages/%s", channel.ID, key),
+			ExternalID: conv.externalID,
+			Extra: map[string]interface{}{
+				"channelId":       channel.ID,
+				"channelName":     channel.Name,
+				"userId":          first.User,
+				"participants":    participants,
+				"threadTimestamp": first.ThreadTimestamp,
+				"messageCount":    len(conv.messages),
+				"hasAttachments":  hasAttachments,
+			},
+		},
+		Anchors: anchors,
+	}
 
-// processMessage processes a single Slack message
-func (s *SlackService) processMessage(ctx context.Context, ds *models.DataSource, channel *SlackChannel, msg SlackMessage) error {
-	logger.Debug("Processing Slack message", logger.Fields{
-		"channelId": channel.ID,
-		"messageTs": msg.Timestamp,
-	})
+	if _, err := s.sink.IngestDocument(ctx, input); err != nil {
+		return fmt.Errorf("failed to ingest document: %w", err)
+	}
 
-	// Build message content
-	var content strings.Builder
+	return s.processFiles(ctx, ds, channel, conv, filters)
+}
+
+// writeSlackMessage writes a message as a conversation turn: its speaker and
+// time, then its text and attachments
+func writeSlackMessage(content *strings.Builder, speaker string, msg SlackMessage) {
+	posted := time.Unix(slackTSSeconds(msg.Timestamp), 0).UTC()
+	content.WriteString(fmt.Sprintf("%s (%s):\n", speaker, posted.Format("2006-01-02 15:04 UTC")))
 	content.WriteString(msg.Text)
 
 	// Process attachments
@@ -390,34 +690,53 @@ func (s *SlackService) processMessage(ctx context.Context, ds *models.DataSource
 		}
 	}
 
-	// Create document input
-	input := models.CreateDocumentInput{
-		InstanceID:   ds.InstanceID,
-		DataSourceID: ds.ID,
-		Title:        fmt.Sprintf("Message in #%s at %s", channel.Name, msg.Timestamp),
-		Content:      content.String(),
-		URL:         fmt.Sprintf("https://slack.com/archives/%s/p%s", channel.ID, strings.Replace(msg.Timestamp, ".", "", 1)),
-		Type:        "slack",
-		Metadata: models.Metadata{
-			SourcePath: fmt.Sprintf("/channels/%s/messages/%s", channel.ID, msg.Timestamp),
-			ExternalID: msg.Timestamp,
-			Extra: map[string]interface{}{
-				"channelId":       channel.ID,
-				"channelName":     channel.Name,
-				"userId":          msg.User,
-				"threadTimestamp": msg.ThreadTimestamp,
-				"hasAttachments": len(msg.Attachments) > 0,
-			},
-		},
+	if len(msg.Files) > 0 {
+		content.WriteString("\n\nFiles:\n")
+		for _, file := range msg.Files {
+			name := file.Title
+			if name == "" {
+				name = file.Name
+			}
+			if name == "" {
+				continue
+			}
+			if file.Permalink != "" {
+				content.WriteString(fmt.Sprintf("[%s](%s)\n", name, file.Permalink))
+			} else {
+				content.WriteString(name + "\n")
+			}
+		}
 	}
+}
 
-	// TODO: Call ingestion service to process the document
-	logger.Info("Would process document", logger.Fields{
-		"title": input.Title,
-		"url":   input.URL,
-	})
+// slackPermalink returns the URL of a message. Replies link into their thread.
+func slackPermalink(channelID string, msg SlackMessage) string {
+	link := fmt.Sprintf("https://slack.com/archives/%s/p%s", channelID, strings.Replace(msg.Timestamp, ".", "", 1))
+	if msg.ThreadTimestamp != "" && !msg.isThreadParent() {
+		link += fmt.Sprintf("?thread_ts=%s&cid=%s", msg.ThreadTimestamp, channelID)
+	}
+	return link
+}
 
-	return nil
+// slackSummary returns the first line of a message's text, shortened for a
+// document title
+func slackSummary(text string) string {
+	const maxLength = 80
+
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	if runes := []rune(line); len(runes) > maxLength {
+		line = strings.TrimSpace(string(runes[:maxLength])) + "..."
+	}
+	return line
+}
+
+// SlackAPIError is returned when the Slack Web API responds with ok: false
+type SlackAPIError struct {
+	Code string // e.g. invalid_auth, missing_scope, channel_not_found
+}
+
+func (e *SlackAPIError) Error() string {
+	return fmt.Sprintf("slack API error: %s", e.Code)
 }
 
 // SlackChannel represents a Slack channel
@@ -426,6 +745,7 @@ type SlackChannel struct {
 	Name        string `json:"name"`
 	IsArchived  bool   `json:"is_archived"`
 	IsPrivate   bool   `json:"is_private"`
+	IsMember    bool   `json:"is_member"`
 	Creator     string `json:"creator"`
 	CreatedTime int64  `json:"created"`
 	Topic       struct {
@@ -442,11 +762,17 @@ type SlackChannel struct {
 type SlackMessage struct {
 	Type            string           `json:"type"`
 	User            string           `json:"user"`
+	Username        string           `json:"username,omitempty"` // bots without a user
 	Text            string           `json:"text"`
 	Timestamp       string           `json:"ts"`
 	ThreadTimestamp string           `json:"thread_ts,omitempty"`
 	ReplyCount      int             `json:"reply_count,omitempty"`
+	LatestReply     string          `json:"latest_reply,omitempty"` // thread parents only
+	Edited          struct {
+		Timestamp string `json:"ts"`
+	} `json:"edited,omitempty"`
 	Attachments     []SlackAttachment `json:"attachments,omitempty"`
+	Files           []SlackFile       `json:"files,omitempty"`
 }
 
 // SlackAttachment represents a file or link attachment in a Slack message
@@ -458,4 +784,48 @@ type SlackAttachment struct {
 	FileURL   string `json:"file_url"`
 	ThumbURL  string `json:"thumb_url"`
 	Color     string `json:"color"`
-} 
\ No newline at end of file
+} 
+// isThreadParent reports whether the message started a thread
+func (m SlackMessage) isThreadParent() bool {
+	return m.ThreadTimestamp != "" && m.ThreadTimestamp == m.Timestamp
+}
+
+// changedSince reports whether the message was posted or edited after the
+// Slack timestamp ts. Every message has changed since an empty ts.
+func (m SlackMessage) changedSince(ts string) bool {
+	return ts == "" || slackTSAfter(m.Timestamp, ts) ||
+		m.Edited.Timestamp != "" && slackTSAfter(m.Edited.Timestamp, ts)
+}
+
+// slackTSAfter reports whether Slack timestamp a is later than b. Timestamps
+// are seconds since the epoch with six decimals, e.g. "1622505600.000100".
+func slackTSAfter(a, b string) bool {
+	aSeconds, aMicros, _ := strings.Cut(a, ".")
+	bSeconds, bMicros, _ := strings.Cut(b, ".")
+	if len(aSeconds) != len(bSeconds) {
+		return len(aSeconds) > len(bSeconds)
+	}
+	if aSeconds != bSeconds {
+		return aSeconds > bSeconds
+	}
+	return aMicros > bMicros
+}
+
+// slackTSSeconds returns the whole seconds of a Slack timestamp, or 0 if it
+// is malformed
+func slackTSSeconds(ts string) int64 {
+	seconds, _, _ := strings.Cut(ts, ".")
+	n, _ := strconv.ParseInt(seconds, 10, 64)
+	return n
+}
+
+// slackTSBefore returns the Slack timestamp d before ts, or an empty string
+// if ts is malformed
+func slackTSBefore(ts string, d time.Duration) string {
+	seconds, _, _ := strings.Cut(ts, ".")
+	n, err := strconv.ParseInt(seconds, 10, 64)
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatInt(n-int64(d/time.Second), 10) + ".000000"
+}
@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
 	"strings"
 	"sync/atomic"
 	"testing"
@@ -12,6 +15,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/Abraham12611/veritas/internal/models"
+	"golang.org/x/time/rate"
 )
 
 func TestSlackService_SyncChannels(t *testing.T) {
@@ -121,7 +125,7 @@ func TestSlackService_SyncChannels(t *testing.T) {
 	defer server.Close()
 
 	// Create service with mock server URL
-	service := NewSlackService("test-token")
+	service := NewSlackService("test-token", newMockSink())
 	service.baseURL = server.URL
 	service.concurrency = 2 // Use 2 workers for testing
 
@@ -130,14 +134,14 @@ func TestSlackService_SyncChannels(t *testing.T) {
 		ID:         uuid.New(),
 		InstanceID: uuid.New(),
 		Type:       "slack",
-		Config: models.DataSourceConfig{
+		Config: models.Config{
 			Channels: []string{"C123456"},
 			APIToken: "test-token",
 		},
 	}
 
 	// Test sync
-	err := service.SyncChannels(context.Background(), ds)
+	_, err := service.SyncChannels(context.Background(), ds, nil)
 	if err != nil {
 		t.Errorf("SyncChannels() error = %v", err)
 	}
@@ -158,7 +162,7 @@ func TestSlackService_RateLimiting(t *testing.T) {
 	defer server.Close()
 
 	// Create service with mock server URL
-	service := NewSlackService("test-token")
+	service := NewSlackService("test-token", newMockSink())
 	service.baseURL = server.URL
 	service.maxRetries = 2 // Reduce retries for faster test
 
@@ -167,14 +171,14 @@ func TestSlackService_RateLimiting(t *testing.T) {
 		ID:         uuid.New(),
 		InstanceID: uuid.New(),
 		Type:       "slack",
-		Config: models.DataSourceConfig{
+		Config: models.Config{
 			Channels: []string{"C123456"},
 			APIToken: "test-token",
 		},
 	}
 
 	// Test sync with rate limiting
-	err := service.SyncChannels(context.Background(), ds)
+	_, err := service.SyncChannels(context.Background(), ds, nil)
 	if err == nil {
 		t.Error("Expected rate limit error, got nil")
 	}
@@ -192,7 +196,7 @@ func TestSlackService_Cancellation(t *testing.T) {
 	defer server.Close()
 
 	// Create service with mock server URL
-	service := NewSlackService("test-token")
+	service := NewSlackService("test-token", newMockSink())
 	service.baseURL = server.URL
 
 	// Create test data source
@@ -200,7 +204,7 @@ func TestSlackService_Cancellation(t *testing.T) {
 		ID:         uuid.New(),
 		InstanceID: uuid.New(),
 		Type:       "slack",
-		Config: models.DataSourceConfig{
+		Config: models.Config{
 			Channels: []string{"C123456"},
 			APIToken: "test-token",
 		},
@@ -211,7 +215,7 @@ func TestSlackService_Cancellation(t *testing.T) {
 	defer cancel()
 
 	// Test sync with cancellation
-	err := service.SyncChannels(ctx, ds)
+	_, err := service.SyncChannels(ctx, ds, nil)
 	if err == nil {
 		t.Error("Expected context deadline exceeded error, got nil")
 	}
@@ -268,7 +272,8 @@ func TestSlackService_MessageProcessing(t *testing.T) {
 	defer server.Close()
 
 	// Create service with mock server URL
-	service := NewSlackService("test-token")
+	sink := newMockSink()
+	service := NewSlackService("test-token", sink)
 	service.baseURL = server.URL
 
 	// Create test data source
@@ -276,17 +281,29 @@ func TestSlackService_MessageProcessing(t *testing.T) {
 		ID:         uuid.New(),
 		InstanceID: uuid.New(),
 		Type:       "slack",
-		Config: models.DataSourceConfig{
+		Config: models.Config{
 			Channels: []string{"C123456"},
 			APIToken: "test-token",
 		},
 	}
 
 	// Test sync
-	err := service.SyncChannels(context.Background(), ds)
+	_, err := service.SyncChannels(context.Background(), ds, nil)
 	if err != nil {
 		t.Errorf("SyncChannels() error = %v", err)
 	}
+
+	// Verify the message reached the sink
+	docs := sink.ingested()
+	if len(docs) != 1 {
+		t.Fatalf("Expected 1 ingested document, got %d", len(docs))
+	}
+	if !strings.Contains(docs[0].Content, "File Attachment") {
+		t.Errorf("Expected attachment in content, got: %s", docs[0].Content)
+	}
+	if docs[0].Metadata.ExternalID != "C123456:1622505600.000100" {
+		t.Errorf("Expected channel-scoped external ID, got: %s", docs[0].Metadata.ExternalID)
+	}
 }
 
 func TestSlackService_ThreadProcessing(t *testing.T) {
@@ -325,6 +342,17 @@ func TestSlackService_ThreadProcessing(t *testing.T) {
 			return
 		}
 
+		if strings.Contains(r.URL.Path, "/users.info") {
+			names := map[string]string{"U123456": "Jane Doe", "U234567": "John Smith"}
+			name, ok := names[r.URL.Query().Get("user")]
+			if !ok {
+				w.Write([]byte(`{"ok": false, "error": "user_not_found"}`))
+				return
+			}
+			fmt.Fprintf(w, `{"ok": true, "user": {"id": %q, "profile": {"real_name": %q}}}`, r.URL.Query().Get("user"), name)
+			return
+		}
+
 		if strings.Contains(r.URL.Path, "/conversations.replies") {
 			atomic.AddInt32(&messageCount, 1)
 			w.Write([]byte(`{
@@ -339,7 +367,7 @@ func TestSlackService_ThreadProcessing(t *testing.T) {
 					{
 						"type": "message",
 						"user": "U234567",
-						"text": "Reply 1",
+						"text": "Reply 1, cc <@U123456>",
 						"ts": "1622505601.000100",
 						"thread_ts": "1622505600.000100"
 					},
@@ -360,22 +388,24 @@ func TestSlackService_ThreadProcessing(t *testing.T) {
 	defer server.Close()
 
 	// Create service with mock server URL
-	service := NewSlackService("test-token")
+	sink := newMockSink()
+	service := NewSlackService("test-token", sink)
 	service.baseURL = server.URL
+	service.limiter = rate.NewLimiter(rate.Inf, 1)
 
 	// Create test data source
 	ds := &models.DataSource{
 		ID:         uuid.New(),
 		InstanceID: uuid.New(),
 		Type:       "slack",
-		Config: models.DataSourceConfig{
+		Config: models.Config{
 			Channels: []string{"C123456"},
 			APIToken: "test-token",
 		},
 	}
 
 	// Test sync
-	err := service.SyncChannels(context.Background(), ds)
+	_, err := service.SyncChannels(context.Background(), ds, nil)
 	if err != nil {
 		t.Errorf("SyncChannels() error = %v", err)
 	}
@@ -385,4 +415,638 @@ func TestSlackService_ThreadProcessing(t *testing.T) {
 	if count != 1 {
 		t.Errorf("Expected 1 thread to be processed, got %d", count)
 	}
-} 
\ No newline at end of file
+
+	// The parent and its replies are one document, a turn per message
+	docs := sink.ingested()
+	if len(docs) != 1 {
+		t.Fatalf("Expected 1 ingested document, got %d", len(docs))
+	}
+	doc := docs[0]
+	if doc.Metadata.ExternalID != "C123456:1622505600.000100" {
+		t.Errorf("Expected the thread keyed by its parent, got: %s", doc.Metadata.ExternalID)
+	}
+	if doc.Title != "Thread in #general: Parent message" {
+		t.Errorf("Unexpected title: %s", doc.Title)
+	}
+	for _, turn := range []string{
+		"Jane Doe (2021-06-01 00:00 UTC):\nParent message",
+		"John Smith (2021-06-01 00:00 UTC):\nReply 1, cc @Jane Doe",
+		"U345678 (2021-06-01 00:00 UTC):\nReply 2",
+	} {
+		if !strings.Contains(doc.Content, turn) {
+			t.Errorf("Expected turn %q in content, got: %s", turn, doc.Content)
+		}
+	}
+	if doc.Metadata.Author != "Jane Doe" {
+		t.Errorf("Expected the thread's author by name, got: %s", doc.Metadata.Author)
+	}
+	if participants := doc.Metadata.Extra["participants"]; !reflect.DeepEqual(participants, []string{"Jane Doe", "John Smith", "U345678"}) {
+		t.Errorf("Unexpected participants: %v", participants)
+	}
+	if len(doc.Anchors) != 3 {
+		t.Fatalf("Expected an anchor per message, got %d", len(doc.Anchors))
+	}
+	if want := "https://slack.com/archives/C123456/p1622505601000100?thread_ts=1622505600.000100&cid=C123456"; doc.Anchors[1].URL != want {
+		t.Errorf("Expected reply permalink %s, got %s", want, doc.Anchors[1].URL)
+	}
+	if doc.Anchors[1].Offset != strings.Index(doc.Content, "John Smith") {
+		t.Errorf("Expected the reply anchor at its turn, got offset %d", doc.Anchors[1].Offset)
+	}
+}
+
+func TestSlackService_ConversationWindows(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "/conversations.info"):
+			w.Write([]byte(`{"ok": true, "channel": {"id": "C1", "name": "general"}}`))
+		case strings.Contains(r.URL.Path, "/conversations.history"):
+			w.Write([]byte(`{"ok": true, "messages": [
+				{"type": "message", "user": "U3", "text": "Next hour", "ts": "1700003700.000000"},
+				{"type": "message", "user": "U2", "text": "Second", "ts": "1700001000.000000"},
+				{"type": "message", "user": "U1", "text": "Thread", "ts": "1700000900.000000", "thread_ts": "1700000900.000000"},
+				{"type": "message", "user": "U1", "text": "First", "ts": "1700000800.000000"}
+			]}`))
+		case strings.Contains(r.URL.Path, "/conversations.replies"):
+			w.Write([]byte(`{"ok": true, "messages": [
+				{"type": "message", "user": "U1", "text": "Thread", "ts": "1700000900.000000", "thread_ts": "1700000900.000000"}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sink := newMockSink()
+	service := NewSlackService("test-token", sink)
+	service.baseURL = server.URL
+	service.limiter = rate.NewLimiter(rate.Inf, 1)
+
+	ds := &models.DataSource{ID: uuid.New(), Config: models.Config{
+		Channels: []string{"C1"},
+		Filters:  map[string]interface{}{"conversation_window": "1h"},
+	}}
+
+	if _, err := service.SyncChannels(context.Background(), ds, nil); err != nil {
+		t.Fatalf("SyncChannels() error = %v", err)
+	}
+
+	docs := make(map[string]models.CreateDocumentInput)
+	for _, doc := range sink.ingested() {
+		docs[doc.Metadata.ExternalID] = doc
+	}
+	if len(docs) != 3 {
+		t.Fatalf("Expected 2 windows and a thread, got %v", docs)
+	}
+
+	window, ok := docs["C1:1699999200-1700002800"]
+	if !ok {
+		t.Fatalf("Expected the first window, got %v", docs)
+	}
+	if first, second := strings.Index(window.Content, "First"), strings.Index(window.Content, "Second"); first < 0 || second < first {
+		t.Errorf("Expected both messages oldest first, got: %s", window.Content)
+	}
+	if strings.Contains(window.Content, "Thread") {
+		t.Errorf("Expected the thread kept out of the window, got: %s", window.Content)
+	}
+	if window.Metadata.Extra["participants"] == nil || len(window.Anchors) != 2 {
+		t.Errorf("Expected participants and an anchor per message, got %v and %v", window.Metadata.Extra, window.Anchors)
+	}
+	if _, ok := docs["C1:1700002800-1700006400"]; !ok {
+		t.Errorf("Expected the second window, got %v", docs)
+	}
+	if _, ok := docs["C1:1700000900.000000"]; !ok {
+		t.Errorf("Expected the thread on its own, got %v", docs)
+	}
+}
+func TestSlackService_SyncChannelsIncremental(t *testing.T) {
+	var oldest string
+	var threads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "/conversations.info"):
+			w.Write([]byte(`{"ok": true, "channel": {"id": "C1", "name": "general"}}`))
+		case strings.Contains(r.URL.Path, "/conversations.history"):
+			oldest = r.URL.Query().Get("oldest")
+			w.Write([]byte(`{"ok": true, "messages": [
+				{"type": "message", "text": "New", "ts": "1700000300.000000"},
+				{"type": "message", "text": "Busy thread", "ts": "1700000200.000000", "thread_ts": "1700000200.000000", "latest_reply": "1700000250.000000"},
+				{"type": "message", "text": "Edited", "ts": "1700000100.000000", "edited": {"ts": "1700000280.000000"}},
+				{"type": "message", "text": "Quiet thread", "ts": "1700000050.000000", "thread_ts": "1700000050.000000", "latest_reply": "1700000060.000000"}
+			]}`))
+		case strings.Contains(r.URL.Path, "/conversations.replies"):
+			threads = append(threads, r.URL.Query().Get("ts"))
+			w.Write([]byte(`{"ok": true, "messages": [
+				{"type": "message", "text": "Busy thread", "ts": "1700000200.000000"},
+				{"type": "message", "text": "Old reply", "ts": "1700000210.000000", "thread_ts": "1700000200.000000"},
+				{"type": "message", "text": "New reply", "ts": "1700000250.000000", "thread_ts": "1700000200.000000"}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sink := newMockSink()
+	service := NewSlackService("test-token", sink)
+	service.baseURL = server.URL
+	service.limiter = rate.NewLimiter(rate.Inf, 1)
+
+	ds := &models.DataSource{ID: uuid.New(), Config: models.Config{Channels: []string{"C1"}}}
+	lastFull := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339Nano)
+	checkpoint := Checkpoint{
+		slackFullSyncKey:       lastFull,
+		"C1":                   "1700000200.000000",
+		"C1/1700000200.000000": "1700000210.000000",
+		"C1/1700000050.000000": "1700000060.000000",
+		"C1/1690000000.000000": "1690000100.000000",
+	}
+
+	next, err := service.SyncChannels(context.Background(), ds, checkpoint)
+	if err != nil {
+		t.Fatalf("SyncChannels() error = %v", err)
+	}
+
+	// A week before the newest synced message
+	if oldest != "1699395400.000000" {
+		t.Errorf("Expected oldest 1699395400.000000, got %q", oldest)
+	}
+	if len(threads) != 1 || threads[0] != "1700000200.000000" {
+		t.Errorf("Expected only the thread with new replies fetched, got %v", threads)
+	}
+
+	var ingested []string
+	for _, doc := range sink.ingested() {
+		ingested = append(ingested, doc.Metadata.ExternalID)
+	}
+	sort.Strings(ingested)
+	if want := "C1:1700000100.000000,C1:1700000200.000000,C1:1700000300.000000"; strings.Join(ingested, ",") != want {
+		t.Errorf("Expected %s ingested, got %v", want, ingested)
+	}
+	if kept := sink.keptIDs(); !containsString(kept, "C1:1700000050.000000") {
+		t.Errorf("Expected the quiet thread kept, got %v", kept)
+	}
+
+	// The thread started before the messages listed is kept for the next
+	// full pass
+	want := Checkpoint{
+		slackFullSyncKey:       lastFull,
+		"C1":                   "1700000300.000000",
+		"C1/1700000200.000000": "1700000250.000000",
+		"C1/1700000050.000000": "1700000060.000000",
+		"C1/1690000000.000000": "1690000100.000000",
+	}
+	if !reflect.DeepEqual(next, want) {
+		t.Errorf("Expected checkpoint %v, got %v", want, next)
+	}
+}
+
+func TestSlackService_SyncChannelsFullPass(t *testing.T) {
+	oldest := "unset"
+	var threads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "/conversations.info"):
+			w.Write([]byte(`{"ok": true, "channel": {"id": "C1", "name": "general"}}`))
+		case strings.Contains(r.URL.Path, "/conversations.history"):
+			oldest = r.URL.Query().Get("oldest")
+			w.Write([]byte(`{"ok": true, "messages": [
+				{"type": "message", "text": "Recent", "ts": "1700000200.000000"},
+				{"type": "message", "text": "Old thread", "ts": "1690000000.000000", "thread_ts": "1690000000.000000", "latest_reply": "1700000100.000000"},
+				{"type": "message", "text": "Old quiet thread", "ts": "1680000000.000000", "thread_ts": "1680000000.000000", "latest_reply": "1680000100.000000"}
+			]}`))
+		case strings.Contains(r.URL.Path, "/conversations.replies"):
+			threads = append(threads, r.URL.Query().Get("ts"))
+			w.Write([]byte(`{"ok": true, "messages": [
+				{"type": "message", "text": "Old thread", "ts": "1690000000.000000"},
+				{"type": "message", "text": "Late reply", "ts": "1700000100.000000", "thread_ts": "1690000000.000000"}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sink := newMockSink()
+	service := NewSlackService("test-token", sink)
+	service.baseURL = server.URL
+	service.limiter = rate.NewLimiter(rate.Inf, 1)
+
+	ds := &models.DataSource{ID: uuid.New(), Config: models.Config{Channels: []string{"C1"}}}
+	checkpoint := Checkpoint{
+		slackFullSyncKey:       time.Now().Add(-slackFullSyncInterval - time.Hour).UTC().Format(time.RFC3339Nano),
+		"C1":                   "1700000200.000000",
+		"C1/1690000000.000000": "1690000100.000000",
+		"C1/1680000000.000000": "1680000100.000000",
+		"C1/1670000000.000000": "1670000100.000000",
+	}
+
+	next, err := service.SyncChannels(context.Background(), ds, checkpoint)
+	if err != nil {
+		t.Fatalf("SyncChannels() error = %v", err)
+	}
+
+	// The whole history is listed, and only the old thread with a new reply
+	// is fetched
+	if oldest != "" {
+		t.Errorf("Expected the whole history listed, got oldest %q", oldest)
+	}
+	if len(threads) != 1 || threads[0] != "1690000000.000000" {
+		t.Errorf("Expected only the old thread with a new reply fetched, got %v", threads)
+	}
+	if docs := sink.ingested(); len(docs) != 1 || docs[0].Metadata.ExternalID != "C1:1690000000.000000" {
+		t.Errorf("Expected only the old thread ingested, got %v", docs)
+	}
+
+	if next[slackFullSyncKey] == checkpoint[slackFullSyncKey] {
+		t.Error("Expected the full pass to be recorded")
+	}
+	if next["C1/1690000000.000000"] != "1700000100.000000" {
+		t.Errorf("Expected the old thread's latest reply recorded, got %v", next)
+	}
+	if _, ok := next["C1/1670000000.000000"]; ok {
+		t.Error("Expected the deleted thread dropped from the checkpoint")
+	}
+}
+
+func TestSlackService_Files(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "/conversations.info"):
+			w.Write([]byte(`{"ok": true, "channel": {"id": "C1", "name": "design"}}`))
+		case strings.Contains(r.URL.Path, "/conversations.history"):
+			fmt.Fprintf(w, `{"ok": true, "messages": [{
+				"type": "message", "user": "U1", "text": "Specs attached", "ts": "1700000000.000100",
+				"files": [
+					{"id": "F1", "name": "spec.md", "title": "Spec", "filetype": "markdown", "size": 20,
+					 "url_private_download": "%[1]s/files/F1", "permalink": "https://example.slack.com/files/F1"},
+					{"id": "F2", "name": "huge.pdf", "filetype": "pdf", "size": 50000000,
+					 "url_private_download": "%[1]s/files/F2"},
+					{"id": "F3", "name": "photo.png", "filetype": "png", "size": 20,
+					 "url_private_download": "%[1]s/files/F3"},
+					{"id": "F4", "file_access": "check_file_info"},
+					{"id": "F5", "name": "notes.txt", "filetype": "text", "size": 20,
+					 "url_private_download": "%[1]s/files/F5"}
+				]
+			}]}`, server.URL)
+		case strings.Contains(r.URL.Path, "/files.info"):
+			fmt.Fprintf(w, `{"ok": true, "file": {"id": "F4", "title": "Roadmap", "filetype": "quip", "size": 40,
+				"url_private_download": "%s/files/F4"}}`, server.URL)
+		case r.URL.Path == "/files/F1":
+			if r.Header.Get("Authorization") != "Bearer test-token" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte("# Spec\n\nThe details."))
+		case r.URL.Path == "/files/F4":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<h1>Roadmap</h1><p>Ship it.</p>"))
+		case r.URL.Path == "/files/F5":
+			// The sign-in page served without the files:read scope
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html>Sign in</html>"))
+		case strings.HasPrefix(r.URL.Path, "/files/"):
+			t.Errorf("Unexpected download of %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sink := newMockSink()
+	service := NewSlackService("test-token", sink)
+	service.baseURL = server.URL
+	service.limiter = rate.NewLimiter(rate.Inf, 1)
+
+	ds := &models.DataSource{ID: uuid.New(), Config: models.Config{Channels: []string{"C1"}}}
+	next, err := service.SyncChannels(context.Background(), ds, nil)
+	if err != nil {
+		t.Fatalf("SyncChannels() error = %v", err)
+	}
+
+	// The file that can't be downloaded doesn't hold back the channel
+	if next["C1"] != "1700000000.000100" {
+		t.Errorf("Expected the channel checkpoint to advance, got %v", next)
+	}
+	if kept := sink.keptIDs(); !containsString(kept, "C1:file:F5") {
+		t.Errorf("Expected the failed file kept, got %v", kept)
+	}
+
+	docs := make(map[string]models.CreateDocumentInput)
+	for _, doc := range sink.ingested() {
+		docs[doc.Metadata.ExternalID] = doc
+	}
+	if len(docs) != 3 {
+		t.Fatalf("Expected the message and 2 files, got %v", docs)
+	}
+
+	message := docs["C1:1700000000.000100"]
+	if !strings.Contains(message.Content, "[Spec](https://example.slack.com/files/F1)") {
+		t.Errorf("Expected the file listed in the message, got: %s", message.Content)
+	}
+
+	spec, ok := docs["C1:file:F1"]
+	if !ok {
+		t.Fatalf("Expected the markdown file ingested, got %v", docs)
+	}
+	if spec.Type != "markdown" || spec.Content != "# Spec\n\nThe details." || spec.Title != "Spec" {
+		t.Errorf("Unexpected file document: %+v", spec)
+	}
+	if spec.Metadata.Extra["messageExternalId"] != "C1:1700000000.000100" {
+		t.Errorf("Expected the file linked to its message, got %v", spec.Metadata.Extra)
+	}
+
+	canvas, ok := docs["C1:file:F4"]
+	if !ok {
+		t.Fatalf("Expected the canvas ingested, got %v", docs)
+	}
+	if !strings.Contains(canvas.Content, "Ship it.") || strings.Contains(canvas.Content, "<p>") {
+		t.Errorf("Expected the canvas as text, got: %s", canvas.Content)
+	}
+}
+
+func TestSlackService_ResolveReferences(t *testing.T) {
+	var lookups int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "/users.info"):
+			atomic.AddInt32(&lookups, 1)
+			w.Write([]byte(`{"ok": true, "user": {"id": "U1", "name": "jdoe", "profile": {"display_name": "jane"}}}`))
+		case strings.Contains(r.URL.Path, "/conversations.info"):
+			w.Write([]byte(`{"ok": true, "channel": {"id": "C2", "name": "design"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	service := NewSlackService("test-token", newMockSink())
+	service.baseURL = server.URL
+	service.limiter = rate.NewLimiter(rate.Inf, 1)
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"<@U1> agreed with <@U1|old-name>", "@jane agreed with @jane"},
+		{"See <#C1|general> and <#C2>", "See #general and #design"},
+		{"<!here> ping <!subteam^S1|@platform>", "@here ping @platform"},
+		{"Docs at <https://example.com|link>", "Docs at <https://example.com|link>"},
+	}
+
+	for _, tt := range tests {
+		if got := service.resolveReferences(context.Background(), tt.text); got != tt.want {
+			t.Errorf("resolveReferences(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+
+	if n := atomic.LoadInt32(&lookups); n != 1 {
+		t.Errorf("Expected the user looked up once, got %d lookups", n)
+	}
+}
+
+func TestSlackService_DiscoverChannels(t *testing.T) {
+	var joined []string
+	var listed url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "/conversations.list"):
+			listed = r.URL.Query()
+			if r.URL.Query().Get("cursor") == "" {
+				w.Write([]byte(`{"ok": true, "channels": [
+					{"id": "C1", "name": "help-billing", "is_member": true},
+					{"id": "C2", "name": "help-api"},
+					{"id": "C3", "name": "random", "is_member": true}
+				], "response_metadata": {"next_cursor": "page2"}}`))
+				return
+			}
+			w.Write([]byte(`{"ok": true, "channels": [
+				{"id": "C4", "name": "help-internal", "is_member": true},
+				{"id": "C5", "name": "help-old", "is_archived": true}
+			]}`))
+		case strings.Contains(r.URL.Path, "/conversations.join"):
+			if r.Method != http.MethodPost {
+				t.Errorf("Expected conversations.join to be a POST, got %s", r.Method)
+			}
+			r.ParseForm()
+			joined = append(joined, r.PostForm.Get("channel"))
+			w.Write([]byte(`{"ok": true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	service := NewSlackService("test-token", newMockSink())
+	service.baseURL = server.URL
+	service.limiter = rate.NewLimiter(rate.Inf, 1)
+
+	ds := &models.DataSource{Config: models.Config{
+		Channels: []string{"C9"},
+		Filters: map[string]interface{}{
+			"include":          []interface{}{"help-*", "!help-internal"},
+			"membership":       "public",
+			"auto_join":        true,
+			"include_archived": true,
+		},
+	}}
+	filters, err := parseSlackFilters(ds.Config.Filters)
+	if err != nil {
+		t.Fatalf("parseSlackFilters() error = %v", err)
+	}
+
+	ids, err := service.channelIDs(context.Background(), ds, filters)
+	if err != nil {
+		t.Fatalf("channelIDs() error = %v", err)
+	}
+
+	// Listed channels first, then the matching public channels; the
+	// archived one can't be joined
+	if want := []string{"C9", "C1", "C2"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Expected channels %v, got %v", want, ids)
+	}
+	if len(joined) != 1 || joined[0] != "C2" {
+		t.Errorf("Expected only C2 joined, got %v", joined)
+	}
+	if listed.Get("types") != "public_channel" || listed.Get("exclude_archived") != "false" {
+		t.Errorf("Unexpected list parameters: %v", listed)
+	}
+
+	// A dry run leaves out the channels it would have to join
+	joined = nil
+	service.sink = dryRunSink{newMockSink()}
+	ids, err = service.channelIDs(context.Background(), ds, filters)
+	if err != nil {
+		t.Fatalf("channelIDs() error = %v", err)
+	}
+	if want := []string{"C9", "C1"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Expected channels %v in a dry run, got %v", want, ids)
+	}
+	if len(joined) != 0 {
+		t.Errorf("Expected no channels joined in a dry run, got %v", joined)
+	}
+
+	// Without auto_join, channels the app isn't in are left out too
+	service.sink = newMockSink()
+	delete(ds.Config.Filters, "auto_join")
+	if filters, err = parseSlackFilters(ds.Config.Filters); err != nil {
+		t.Fatalf("parseSlackFilters() error = %v", err)
+	}
+	ids, err = service.channelIDs(context.Background(), ds, filters)
+	if err != nil {
+		t.Fatalf("channelIDs() error = %v", err)
+	}
+	if want := []string{"C9", "C1"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("Expected channels %v without auto_join, got %v", want, ids)
+	}
+	if len(joined) != 0 {
+		t.Errorf("Expected no channels joined without auto_join, got %v", joined)
+	}
+}
+
+func TestSlackService_SyncChannelsDroppedChannel(t *testing.T) {
+	oldest := "unset"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.Contains(r.URL.Path, "/conversations.info"):
+			w.Write([]byte(`{"ok": true, "channel": {"id": "C1", "name": "general"}}`))
+		case strings.Contains(r.URL.Path, "/conversations.history"):
+			oldest = r.URL.Query().Get("oldest")
+			w.Write([]byte(`{"ok": true, "messages": [
+				{"type": "message", "text": "Hello", "ts": "1700000200.000000"}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	sink := newMockSink()
+	service := NewSlackService("test-token", sink)
+	service.baseURL = server.URL
+	service.limiter = rate.NewLimiter(rate.Inf, 1)
+
+	// C2 was synced before, but is no longer selected
+	ds := &models.DataSource{ID: uuid.New(), Config: models.Config{Channels: []string{"C1"}}}
+	checkpoint := Checkpoint{
+		slackFullSyncKey:       time.Now().Add(-time.Hour).UTC().Format(time.RFC3339Nano),
+		"C1":                   "1700000200.000000",
+		"C2":                   "1700000100.000000",
+		"C2/1700000000.000000": "1700000050.000000",
+	}
+
+	next, err := service.SyncChannels(context.Background(), ds, checkpoint)
+	if err != nil {
+		t.Fatalf("SyncChannels() error = %v", err)
+	}
+
+	// A full pass runs, so the dropped channel's documents are deleted
+	if oldest != "" {
+		t.Errorf("Expected a full pass, got oldest %q", oldest)
+	}
+	if next[slackFullSyncKey] == checkpoint[slackFullSyncKey] {
+		t.Error("Expected the full pass to be recorded")
+	}
+	if kept := sink.keptIDs(); len(kept) != 1 || kept[0] != "C1:1700000200.000000" {
+		t.Errorf("Expected only C1's message kept, got %v", kept)
+	}
+	for key := range next {
+		if strings.HasPrefix(key, "C2") {
+			t.Errorf("Expected C2 dropped from the checkpoint, got %v", next)
+		}
+	}
+}
+
+func TestSlackFilters_SelectsChannel(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters map[string]interface{}
+		channel SlackChannel
+		want    bool
+	}{
+		{"no discovery", nil, SlackChannel{Name: "general", IsMember: true}, false},
+		{"member", map[string]interface{}{"include": "help-*"}, SlackChannel{Name: "help-api", IsMember: true}, true},
+		{"not a member", map[string]interface{}{"include": "help-*"}, SlackChannel{Name: "help-api"}, false},
+		{"name not included", map[string]interface{}{"include": "help-*"}, SlackChannel{Name: "general", IsMember: true}, false},
+		{"name excluded", map[string]interface{}{"exclude": "random"}, SlackChannel{Name: "random", IsMember: true}, false},
+		{"archived", map[string]interface{}{"membership": "member"}, SlackChannel{Name: "old", IsMember: true, IsArchived: true}, false},
+		{"archived included", map[string]interface{}{"membership": "member", "include_archived": true}, SlackChannel{Name: "old", IsMember: true, IsArchived: true}, true},
+		{"public", map[string]interface{}{"membership": "public"}, SlackChannel{Name: "general"}, true},
+		{"private when public", map[string]interface{}{"membership": "public"}, SlackChannel{Name: "secret", IsMember: true, IsPrivate: true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parseSlackFilters(tt.filters)
+			if err != nil {
+				t.Fatalf("parseSlackFilters() error = %v", err)
+			}
+			if got := f.selectsChannel(tt.channel); got != tt.want {
+				t.Errorf("selectsChannel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSlackFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters map[string]interface{}
+		window  string
+		wantErr bool
+	}{
+		{"none", nil, "", false},
+		{"window", map[string]interface{}{"conversation_window": "30m"}, "1699999200-1700001000", false},
+		{"too short", map[string]interface{}{"conversation_window": "10s"}, "", true},
+		{"not a duration", map[string]interface{}{"conversation_window": "hourly"}, "", true},
+		{"file types", map[string]interface{}{"file_types": []interface{}{"pdf", "canvas"}, "max_file_size": float64(1 << 20)}, "", false},
+		{"unknown file type", map[string]interface{}{"file_types": []interface{}{"docx"}}, "", true},
+		{"bad size", map[string]interface{}{"max_file_size": "1MB"}, "", true},
+		{"unknown membership", map[string]interface{}{"membership": "all"}, "", true},
+		{"auto_join not a bool", map[string]interface{}{"membership": "public", "auto_join": "yes"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parseSlackFilters(tt.filters)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSlackFilters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil {
+				if got := f.windowKey("1700000123.000100"); got != tt.window {
+					t.Errorf("windowKey() = %q, want %q", got, tt.window)
+				}
+			}
+		})
+	}
+
+	f, err := parseSlackFilters(map[string]interface{}{"file_types": []interface{}{}})
+	if err != nil {
+		t.Fatalf("parseSlackFilters() error = %v", err)
+	}
+	if len(f.fileTypes) != 0 {
+		t.Errorf("Expected an empty list to sync no files, got %v", f.fileTypes)
+	}
+	if f, _ := parseSlackFilters(nil); len(f.fileTypes) != len(slackFileTypes) || f.maxFileSize != slackDefaultMaxFileSize {
+		t.Errorf("Expected all file types up to the default size, got %v and %d", f.fileTypes, f.maxFileSize)
+	}
+}
+
+func TestSlackTSAfter(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"1700000001.000000", "1700000000.999999", true},
+		{"1700000000.000002", "1700000000.000001", true},
+		{"1700000000.000001", "1700000000.000001", false},
+		{"999999999.000000", "1000000000.000000", false},
+	}
+
+	for _, tt := range tests {
+		if got := slackTSAfter(tt.a, tt.b); got != tt.want {
+			t.Errorf("slackTSAfter(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sync"
+
+	"github.com/Abraham12611/veritas/internal/logger"
+)
+
+// slackReferencePattern matches the references Slack embeds in message text:
+// <@U123> user mentions, <#C123|name> channel references and <!here> or
+// <!subteam^S123|@team> special mentions, with an optional label
+var slackReferencePattern = regexp.MustCompile(`<([@#!])([^>|]+)(?:\|([^>]*))?>`)
+
+// SlackUser represents a Slack user
+type SlackUser struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	RealName string `json:"real_name"`
+	Deleted  bool   `json:"deleted"`
+	IsBot    bool   `json:"is_bot"`
+	Profile  struct {
+		RealName    string `json:"real_name"`
+		DisplayName string `json:"display_name"`
+	} `json:"profile"`
+}
+
+// displayName returns the name the user goes by, preferring their full name
+func (u SlackUser) displayName() string {
+	switch {
+	case u.Profile.RealName != "":
+		return u.Profile.RealName
+	case u.RealName != "":
+		return u.RealName
+	case u.Profile.DisplayName != "":
+		return u.Profile.DisplayName
+	case u.Name != "":
+		return u.Name
+	default:
+		return u.ID
+	}
+}
+
+// slackDirectory caches the names of the users and channels messages refer
+// to, so each is looked up once per service
+type slackDirectory struct {
+	mu       sync.Mutex
+	users    map[string]*slackName
+	channels map[string]*slackName
+}
+
+// slackName is a directory entry, looked up by whoever asks for it first
+type slackName struct {
+	once sync.Once
+	name string
+}
+
+func newSlackDirectory() *slackDirectory {
+	return &slackDirectory{
+		users:    make(map[string]*slackName),
+		channels: make(map[string]*slackName),
+	}
+}
+
+// entry returns the entry for id in names, adding it if it's new
+func (d *slackDirectory) entry(names map[string]*slackName, id string) *slackName {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok := names[id]
+	if !ok {
+		entry = &slackName{}
+		names[id] = entry
+	}
+	return entry
+}
+
+// userName returns the display name of a user, or their ID if they can't be
+// looked up
+func (s *SlackService) userName(ctx context.Context, userID string) string {
+	entry := s.directory.entry(s.directory.users, userID)
+	entry.once.Do(func() {
+		entry.name = userID
+
+		user, err := s.getUserInfo(ctx, userID)
+		if err != nil {
+			var apiErr *SlackAPIError
+			if ctx.Err() == nil && !(errors.As(err, &apiErr) && apiErr.Code == "user_not_found") {
+				logger.Error("Failed to look up Slack user", err, logger.Fields{
+					"userId": userID,
+				})
+			}
+			return
+		}
+		entry.name = user.displayName()
+	})
+	return entry.name
+}
+
+// channelName returns the name of a channel, or its ID if it can't be looked
+// up
+func (s *SlackService) channelName(ctx context.Context, channelID string) string {
+	entry := s.directory.entry(s.directory.channels, channelID)
+	entry.once.Do(func() {
+		entry.name = channelID
+
+		channel, err := s.getChannelInfo(ctx, channelID)
+		if err != nil {
+			if ctx.Err() == nil {
+				logger.Error("Failed to look up Slack channel", err, logger.Fields{
+					"channelId": channelID,
+				})
+			}
+			return
+		}
+		entry.name = channel.Name
+	})
+	return entry.name
+}
+
+// speaker returns the name of who posted a message
+func (s *SlackService) speaker(ctx context.Context, msg SlackMessage) string {
+	switch {
+	case msg.User != "":
+		return s.userName(ctx, msg.User)
+	case msg.Username != "":
+		return msg.Username
+	default:
+		return "unknown"
+	}
+}
+
+// resolveReferences replaces the user mentions and channel references in a
+// message's text with readable names, e.g. "<@U123>" with "@Jane Doe"
+func (s *SlackService) resolveReferences(ctx context.Context, text string) string {
+	return slackReferencePattern.ReplaceAllStringFunc(text, func(ref string) string {
+		match := slackReferencePattern.FindStringSubmatch(ref)
+		kind, id, label := match[1], match[2], match[3]
+
+		switch {
+		case kind == "@":
+			return "@" + s.userName(ctx, id)
+		case kind == "#" && label != "":
+			return "#" + label
+		case kind == "#":
+			return "#" + s.channelName(ctx, id)
+		case label != "":
+			// Group mentions and dates carry their own text
+			return label
+		default:
+			return "@" + id // @here, @channel, @everyone
+		}
+	})
+}
+
+// getUserInfo retrieves a user's profile
+func (s *SlackService) getUserInfo(ctx context.Context, userID string) (*SlackUser, error) {
+	params := url.Values{}
+	params.Set("user", userID)
+
+	var response struct {
+		OK    bool      `json:"ok"`
+		Error string    `json:"error,omitempty"`
+		User  SlackUser `json:"user"`
+	}
+	if err := s.getJSON(ctx, "users.info", params, &response); err != nil {
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+
+	if !response.OK {
+		return nil, &SlackAPIError{Code: response.Error}
+	}
+
+	return &response.User, nil
+}
@@ -0,0 +1,236 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Abraham12611/veritas/internal/logger"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// slackMaxRequestAge is how old a signed Slack request may be, to stop
+// replays of captured requests
+const slackMaxRequestAge = 5 * time.Minute
+
+// slackEventCallback is a delivery from the Slack Events API
+type slackEventCallback struct {
+	Type      string `json:"type"`      // url_verification or event_callback
+	Challenge string `json:"challenge"` // url_verification only
+	Event     struct {
+		Type      string `json:"type"`
+		Subtype   string `json:"subtype"`
+		Channel   string `json:"channel"`
+		Timestamp string `json:"ts"`
+		ThreadTS  string `json:"thread_ts"`
+		DeletedTS string `json:"deleted_ts"` // message_deleted
+		Message   struct {
+			Timestamp string `json:"ts"`
+			ThreadTS  string `json:"thread_ts"`
+		} `json:"message"` // message_changed and message_replied
+		PreviousMessage struct {
+			ThreadTS string `json:"thread_ts"`
+		} `json:"previous_message"` // message_deleted
+	} `json:"event"`
+}
+
+// ParseWebhook maps Slack Events API deliveries to changes. Requests are
+// signed with the app's signing secret, which is the data source's webhook
+// secret.
+func (slackConnector) ParseWebhook(ds *models.DataSource, req WebhookRequest) (*WebhookEvent, error) {
+	timestamp := req.Header.Get("X-Slack-Request-Timestamp")
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing request timestamp", ErrInvalidSignature)
+	}
+	if age := time.Since(time.Unix(sent, 0)); age > slackMaxRequestAge || age < -slackMaxRequestAge {
+		return nil, fmt.Errorf("%w: request timestamp too old", ErrInvalidSignature)
+	}
+
+	message := append([]byte("v0:"+timestamp+":"), req.Body...)
+	if err := verifyHMAC(ds.Config.WebhookSecret, message, req.Header.Get("X-Slack-Signature"), "v0="); err != nil {
+		return nil, err
+	}
+
+	var callback slackEventCallback
+	if err := json.Unmarshal(req.Body, &callback); err != nil {
+		return nil, fmt.Errorf("failed to parse event: %w", err)
+	}
+
+	if callback.Type == "url_verification" {
+		return &WebhookEvent{Reply: map[string]string{"challenge": callback.Challenge}}, nil
+	}
+
+	filters, err := parseSlackFilters(ds.Config.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	// Whether a discovered channel is synced is only known once its details
+	// are looked up, when the item is synced
+	event := callback.Event
+	if callback.Type != "event_callback" || event.Type != "message" ||
+		!filters.discover && !containsString(ds.Config.Channels, event.Channel) {
+		return &WebhookEvent{}, nil
+	}
+
+	// documentID returns the external ID of the document with the message
+	// posted at ts, in thread threadTS if it's a reply
+	documentID := func(ts, threadTS string) string {
+		if threadTS != "" {
+			return slackExternalID(event.Channel, threadTS)
+		}
+		if key := filters.windowKey(ts); key != "" {
+			return slackExternalID(event.Channel, key)
+		}
+		return slackExternalID(event.Channel, ts)
+	}
+
+	switch event.Subtype {
+	case "message_deleted":
+		id := documentID(event.DeletedTS, event.PreviousMessage.ThreadTS)
+
+		// Only a document of a single message goes with it
+		if event.PreviousMessage.ThreadTS == "" && filters.conversationWindow == 0 {
+			return &WebhookEvent{Deleted: []string{id}}, nil
+		}
+		return &WebhookEvent{Changed: []string{id}}, nil
+	case "message_changed", "message_replied":
+		return &WebhookEvent{Changed: []string{documentID(event.Message.Timestamp, event.Message.ThreadTS)}}, nil
+	default:
+		return &WebhookEvent{Changed: []string{documentID(event.Timestamp, event.ThreadTS)}}, nil
+	}
+}
+
+func (slackConnector) SyncItems(ctx context.Context, ds *models.DataSource, sink DocumentSink, externalIDs []string) ([]string, error) {
+	return NewSlackService(ds.Config.APIToken, sink).SyncMessages(ctx, ds, externalIDs)
+}
+
+// slackExternalID returns the external ID of a message. Timestamps are only
+// unique per channel.
+func slackExternalID(channelID, ts string) string {
+	return channelID + ":" + ts
+}
+
+// SyncMessages syncs the conversations with the given external IDs: threads,
+// single messages and conversation windows. It returns the IDs of those that
+// no longer have messages, or are in a channel no longer configured or
+// discovered.
+func (s *SlackService) SyncMessages(ctx context.Context, ds *models.DataSource, externalIDs []string) ([]string, error) {
+	filters, err := parseSlackFilters(ds.Config.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+
+	channels := make(map[string]*SlackChannel)
+
+	var deleted []string
+	for _, externalID := range externalIDs {
+		channelID, key, ok := strings.Cut(externalID, ":")
+		if !ok {
+			continue
+		}
+		listed := containsString(ds.Config.Channels, channelID)
+		if !listed && !filters.discover {
+			deleted = append(deleted, externalID)
+			continue
+		}
+
+		channel, ok := channels[channelID]
+		if !ok {
+			var err error
+			if channel, err = s.getChannelInfo(ctx, channelID); err != nil {
+				return nil, fmt.Errorf("failed to get channel info: %w", err)
+			}
+			channels[channelID] = channel
+		}
+		if !listed && !filters.selectsChannel(*channel) {
+			deleted = append(deleted, externalID)
+			continue
+		}
+
+		var messages []SlackMessage
+		if start, end, isWindow := strings.Cut(key, "-"); isWindow {
+			messages, err = s.getWindow(ctx, channelID, start, end)
+		} else {
+			messages, err = s.getThread(ctx, channelID, key)
+
+			// Replies are part of their thread, and top-level messages part
+			// of their window if messages are grouped
+			if len(messages) > 0 && (messages[0].Timestamp != key ||
+				!messages[0].isThreadParent() && (messages[0].ThreadTimestamp != "" || filters.windowKey(key) != "")) {
+				messages = nil
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get messages of %s: %w", externalID, err)
+		}
+		if len(messages) == 0 {
+			deleted = append(deleted, externalID)
+			continue
+		}
+
+		conv := slackConversation{externalID: externalID, messages: messages}
+		if err := s.processConversation(ctx, ds, channel, conv, filters); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			logger.Error("Failed to process conversation", err, logger.Fields{
+				"channelId":  channelID,
+				"externalId": externalID,
+			})
+		}
+	}
+
+	return deleted, nil
+}
+
+// getWindow retrieves the top-level messages of a channel posted between the
+// Unix times start and end, oldest first. Threads aren't included; they are
+// documents of their own.
+func (s *SlackService) getWindow(ctx context.Context, channelID, start, end string) ([]SlackMessage, error) {
+	endSeconds, err := strconv.ParseInt(end, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid window end %q", end)
+	}
+	oldest := start + ".000000"
+	latest := fmt.Sprintf("%d.999999", endSeconds-1)
+
+	var window []SlackMessage
+	var cursor string
+	for {
+		messages, nextCursor, err := s.getMessages(ctx, channelID, oldest, latest, cursor)
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range messages {
+			if msg.ThreadTimestamp == "" {
+				window = append(window, msg)
+			}
+		}
+
+		if nextCursor == "" {
+			break
+		}
+		cursor = nextCursor
+	}
+
+	// Messages come newest first
+	for i, j := 0, len(window)-1; i < j; i, j = i+1, j-1 {
+		window[i], window[j] = window[j], window[i]
+	}
+	return window, nil
+}
+
+// containsString reports whether values contains s
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package sync
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// ErrInvalidSignature is returned for a webhook delivery that isn't signed
+// with the data source's webhook secret
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// WebhookRequest is a webhook delivery from a source
+type WebhookRequest struct {
+	Header http.Header
+	Body   []byte
+}
+
+// WebhookEvent describes what a webhook delivery says changed in the source
+type WebhookEvent struct {
+	// Changed are the external IDs of items that were created or updated
+	Changed []string
+
+	// Deleted are the external IDs of items that were deleted
+	Deleted []string
+
+	// Resync is true if the change can't be mapped to items, e.g. a push to
+	// a repository, so the data source needs a sync. Connectors whose sync
+	// only fetches what changed use this for cheap catch-up syncs.
+	Resync bool
+
+	// VerificationToken is a token sent unsigned by the source while
+	// setting up the webhook. Anyone could send one, so it's only stored as
+	// pending, for an admin to confirm and set as the webhook secret.
+	VerificationToken string
+
+	// Reply is sent back as JSON instead of an empty response, e.g. the
+	// challenge of Slack's URL verification
+	Reply interface{}
+}
+
+// WebhookReceiver is implemented by connectors whose source can push
+// changes, so they show up without waiting for the next sync
+type WebhookReceiver interface {
+	// ParseWebhook verifies a delivery against the data source's webhook
+	// secret and returns the changes it describes. Deliveries for other
+	// repositories, spaces, databases or channels describe no changes.
+	// Verification failures wrap ErrInvalidSignature.
+	ParseWebhook(ds *models.DataSource, req WebhookRequest) (*WebhookEvent, error)
+
+	// SyncItems fetches the items with the given external IDs and sends
+	// them to sink. It returns the IDs of the items that no longer exist or
+	// no longer match the data source's config, whose documents should be
+	// deleted. Failures to ingest single items are logged, not returned.
+	SyncItems(ctx context.Context, ds *models.DataSource, sink DocumentSink, externalIDs []string) ([]string, error)
+}
+
+// verifyHMAC checks that signature is the hex HMAC-SHA256 of message with
+// secret, after removing prefix
+func verifyHMAC(secret string, message []byte, signature, prefix string) error {
+	if secret == "" {
+		return fmt.Errorf("%w: no webhook secret configured", ErrInvalidSignature)
+	}
+	if !strings.HasPrefix(signature, prefix) {
+		return ErrInvalidSignature
+	}
+
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, prefix))
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(message)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
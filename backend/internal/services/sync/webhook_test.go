@@ -0,0 +1,375 @@
+package sync
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// sign returns the hex HMAC-SHA256 of message with secret
+func sign(secret, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyHMAC(t *testing.T) {
+	body := []byte(`{"action":"opened"}`)
+
+	tests := []struct {
+		name      string
+		secret    string
+		signature string
+		wantErr   bool
+	}{
+		{"valid", "secret", "sha256=" + sign("secret", string(body)), false},
+		{"wrong secret", "secret", "sha256=" + sign("other", string(body)), true},
+		{"missing prefix", "secret", sign("secret", string(body)), true},
+		{"not hex", "secret", "sha256=zz", true},
+		{"no secret configured", "", "sha256=" + sign("", string(body)), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyHMAC(tt.secret, body, tt.signature, "sha256=")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("verifyHMAC() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("Expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGitHubConnector_ParseWebhook(t *testing.T) {
+	ds := &models.DataSource{Config: models.Config{
+		Repository:    "org/repo",
+		WebhookSecret: "secret",
+		Filters:       map[string]interface{}{"issues": true, "wiki": true},
+	}}
+
+	const repo = `"repository": {"full_name": "org/repo", "default_branch": "main"}`
+	tests := []struct {
+		name  string
+		event string
+		body  string
+		want  WebhookEvent
+	}{
+		{"push to default branch", "push", `{"ref": "refs/heads/main", ` + repo + `}`, WebhookEvent{Resync: true}},
+		{"push to other branch", "push", `{"ref": "refs/heads/dev", ` + repo + `}`, WebhookEvent{}},
+		{"wiki edit", "gollum", `{` + repo + `}`, WebhookEvent{Resync: true}},
+		{"issue opened", "issues", `{"action": "opened", "issue": {"number": 5}, ` + repo + `}`, WebhookEvent{Changed: []string{"#5"}}},
+		{"issue deleted", "issues", `{"action": "deleted", "issue": {"number": 5}, ` + repo + `}`, WebhookEvent{Deleted: []string{"#5"}}},
+		{"issue comment", "issue_comment", `{"action": "created", "issue": {"number": 6}, ` + repo + `}`, WebhookEvent{Changed: []string{"#6"}}},
+		{"pull requests not synced", "pull_request", `{"action": "opened", "pull_request": {"number": 7}, ` + repo + `}`, WebhookEvent{}},
+		{"other repository", "issues", `{"action": "opened", "issue": {"number": 5}, "repository": {"full_name": "org/other"}}`, WebhookEvent{}},
+		{"unknown event", "star", `{}`, WebhookEvent{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			header.Set("X-GitHub-Event", tt.event)
+			header.Set("X-Hub-Signature-256", "sha256="+sign("secret", tt.body))
+
+			event, err := githubConnector{}.ParseWebhook(ds, WebhookRequest{Header: header, Body: []byte(tt.body)})
+			if err != nil {
+				t.Fatalf("ParseWebhook() error = %v", err)
+			}
+			if !reflect.DeepEqual(*event, tt.want) {
+				t.Errorf("ParseWebhook() = %+v, want %+v", *event, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid signature", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("X-GitHub-Event", "push")
+		header.Set("X-Hub-Signature-256", "sha256="+sign("other", "{}"))
+
+		_, err := githubConnector{}.ParseWebhook(ds, WebhookRequest{Header: header, Body: []byte("{}")})
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Expected ErrInvalidSignature, got %v", err)
+		}
+	})
+}
+
+func TestSlackConnector_ParseWebhook(t *testing.T) {
+	ds := &models.DataSource{Config: models.Config{
+		Channels:      []string{"C1"},
+		WebhookSecret: "secret",
+	}}
+
+	request := func(body string, sent time.Time) WebhookRequest {
+		timestamp := strconv.FormatInt(sent.Unix(), 10)
+		header := http.Header{}
+		header.Set("X-Slack-Request-Timestamp", timestamp)
+		header.Set("X-Slack-Signature", "v0="+sign("secret", "v0:"+timestamp+":"+body))
+		return WebhookRequest{Header: header, Body: []byte(body)}
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		want     WebhookEvent
+		windowed bool
+	}{
+		{"url verification", `{"type": "url_verification", "challenge": "abc"}`,
+			WebhookEvent{Reply: map[string]string{"challenge": "abc"}}, false},
+		{"new message", `{"type": "event_callback", "event": {"type": "message", "channel": "C1", "ts": "1.1"}}`,
+			WebhookEvent{Changed: []string{"C1:1.1"}}, false},
+		{"edited message", `{"type": "event_callback", "event": {"type": "message", "subtype": "message_changed", "channel": "C1", "ts": "2.2", "message": {"ts": "1.1"}}}`,
+			WebhookEvent{Changed: []string{"C1:1.1"}}, false},
+		{"deleted message", `{"type": "event_callback", "event": {"type": "message", "subtype": "message_deleted", "channel": "C1", "ts": "2.2", "deleted_ts": "1.1"}}`,
+			WebhookEvent{Deleted: []string{"C1:1.1"}}, false},
+		{"other channel", `{"type": "event_callback", "event": {"type": "message", "channel": "C2", "ts": "1.1"}}`,
+			WebhookEvent{}, false},
+		{"new reply", `{"type": "event_callback", "event": {"type": "message", "channel": "C1", "ts": "2.2", "thread_ts": "1.1"}}`,
+			WebhookEvent{Changed: []string{"C1:1.1"}}, false},
+		{"deleted reply", `{"type": "event_callback", "event": {"type": "message", "subtype": "message_deleted", "channel": "C1", "deleted_ts": "2.2", "previous_message": {"thread_ts": "1.1"}}}`,
+			WebhookEvent{Changed: []string{"C1:1.1"}}, false},
+		{"message in window", `{"type": "event_callback", "event": {"type": "message", "channel": "C1", "ts": "1700000123.000100"}}`,
+			WebhookEvent{Changed: []string{"C1:1699999200-1700002800"}}, true},
+		{"deleted message in window", `{"type": "event_callback", "event": {"type": "message", "subtype": "message_deleted", "channel": "C1", "deleted_ts": "1700000123.000100"}}`,
+			WebhookEvent{Changed: []string{"C1:1699999200-1700002800"}}, true},
+	}
+
+	windowed := &models.DataSource{Config: ds.Config}
+	windowed.Config.Filters = map[string]interface{}{"conversation_window": "1h"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := ds
+			if tt.windowed {
+				source = windowed
+			}
+			event, err := slackConnector{}.ParseWebhook(source, request(tt.body, time.Now()))
+			if err != nil {
+				t.Fatalf("ParseWebhook() error = %v", err)
+			}
+			if !reflect.DeepEqual(*event, tt.want) {
+				t.Errorf("ParseWebhook() = %+v, want %+v", *event, tt.want)
+			}
+		})
+	}
+
+	t.Run("discovered channel", func(t *testing.T) {
+		discovering := &models.DataSource{Config: ds.Config}
+		discovering.Config.Filters = map[string]interface{}{"include": "help-*"}
+
+		body := `{"type": "event_callback", "event": {"type": "message", "channel": "C2", "ts": "1.1"}}`
+		event, err := slackConnector{}.ParseWebhook(discovering, request(body, time.Now()))
+		if err != nil {
+			t.Fatalf("ParseWebhook() error = %v", err)
+		}
+		if len(event.Changed) != 1 || event.Changed[0] != "C2:1.1" {
+			t.Errorf("Expected the message of an unlisted channel queued, got %+v", *event)
+		}
+	})
+
+	t.Run("replayed request", func(t *testing.T) {
+		_, err := slackConnector{}.ParseWebhook(ds, request(`{"type": "event_callback"}`, time.Now().Add(-time.Hour)))
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Expected ErrInvalidSignature, got %v", err)
+		}
+	})
+}
+
+func TestNotionConnector_ParseWebhook(t *testing.T) {
+	ds := &models.DataSource{Config: models.Config{
+		DatabaseID:    "0a1b2c3d-0000-0000-0000-000000000001",
+		WebhookSecret: "secret",
+	}}
+
+	request := func(body string) WebhookRequest {
+		header := http.Header{}
+		header.Set("X-Notion-Signature", "sha256="+sign("secret", body))
+		return WebhookRequest{Header: header, Body: []byte(body)}
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want WebhookEvent
+	}{
+		{"page updated", `{"type": "page.content_updated", "entity": {"id": "p1", "type": "page"}, "data": {"parent": {"id": "0a1b2c3d000000000000000000000001", "type": "database"}}}`,
+			WebhookEvent{Changed: []string{"p1"}}},
+		{"page deleted", `{"type": "page.deleted", "entity": {"id": "p1", "type": "page"}, "data": {"parent": {"id": "0a1b2c3d-0000-0000-0000-000000000001", "type": "database"}}}`,
+			WebhookEvent{Deleted: []string{"p1"}}},
+		{"other database", `{"type": "page.created", "entity": {"id": "p1", "type": "page"}, "data": {"parent": {"id": "other", "type": "database"}}}`,
+			WebhookEvent{}},
+		{"database event", `{"type": "database.schema_updated", "entity": {"id": "d1", "type": "database"}}`,
+			WebhookEvent{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := notionConnector{}.ParseWebhook(ds, request(tt.body))
+			if err != nil {
+				t.Fatalf("ParseWebhook() error = %v", err)
+			}
+			if !reflect.DeepEqual(*event, tt.want) {
+				t.Errorf("ParseWebhook() = %+v, want %+v", *event, tt.want)
+			}
+		})
+	}
+
+	t.Run("verification token", func(t *testing.T) {
+		event, err := notionConnector{}.ParseWebhook(&models.DataSource{}, WebhookRequest{
+			Header: http.Header{},
+			Body:   []byte(`{"verification_token": "secret_abc"}`),
+		})
+		if err != nil {
+			t.Fatalf("ParseWebhook() error = %v", err)
+		}
+		if event.VerificationToken != "secret_abc" {
+			t.Errorf("Expected verification token, got %q", event.VerificationToken)
+		}
+	})
+
+	t.Run("no secret configured", func(t *testing.T) {
+		body := `{"type": "page.deleted", "entity": {"id": "p1", "type": "page"}}`
+		header := http.Header{}
+		header.Set("X-Notion-Signature", "sha256="+sign("secret_abc", body))
+
+		_, err := notionConnector{}.ParseWebhook(&models.DataSource{}, WebhookRequest{Header: header, Body: []byte(body)})
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Expected ErrInvalidSignature, got %v", err)
+		}
+	})
+
+	t.Run("unsigned event", func(t *testing.T) {
+		_, err := notionConnector{}.ParseWebhook(ds, WebhookRequest{
+			Header: http.Header{},
+			Body:   []byte(`{"type": "page.deleted", "entity": {"id": "p1", "type": "page"}}`),
+		})
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("Expected ErrInvalidSignature, got %v", err)
+		}
+	})
+}
+
+func TestConfluenceConnector_ParseWebhook(t *testing.T) {
+	ds := &models.DataSource{Config: models.Config{
+		SpaceKey:      "ENG",
+		WebhookSecret: "secret",
+	}}
+
+	tests := []struct {
+		name string
+		body string
+		want WebhookEvent
+	}{
+		{"page updated", `{"webhookEvent": "page_updated", "page": {"id": 123, "spaceKey": "ENG"}}`,
+			WebhookEvent{Changed: []string{"123"}}},
+		{"page trashed", `{"webhookEvent": "page_trashed", "page": {"id": "123", "spaceKey": "ENG"}}`,
+			WebhookEvent{Deleted: []string{"123"}}},
+		{"other space", `{"webhookEvent": "page_created", "page": {"id": 123, "spaceKey": "OPS"}}`,
+			WebhookEvent{}},
+		{"not a page", `{"webhookEvent": "space_updated"}`,
+			WebhookEvent{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			header.Set("X-Hub-Signature", "sha256="+sign("secret", tt.body))
+
+			event, err := confluenceConnector{}.ParseWebhook(ds, WebhookRequest{Header: header, Body: []byte(tt.body)})
+			if err != nil {
+				t.Fatalf("ParseWebhook() error = %v", err)
+			}
+			if !reflect.DeepEqual(*event, tt.want) {
+				t.Errorf("ParseWebhook() = %+v, want %+v", *event, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHubService_SyncIssueItems(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/org/repo/issues/1":
+			w.Write([]byte(`{"number": 1, "title": "Crash on start", "state": "open", "updated_at": "2024-01-01T00:00:00Z"}`))
+		case "/repos/org/repo/issues/1/comments":
+			w.Write([]byte(`[]`))
+		case "/repos/org/repo/issues/2":
+			w.WriteHeader(http.StatusGone)
+			w.Write([]byte(`{"message": "This issue was deleted"}`))
+		case "/repos/org/repo/issues/3":
+			w.Write([]byte(`{"number": 3, "title": "Closed", "state": "closed", "updated_at": "2024-01-01T00:00:00Z"}`))
+		default:
+			t.Errorf("Unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+
+	sink := newMockSink()
+	service := newTestGitHubService(t, handler, sink)
+
+	ds := &models.DataSource{ID: uuid.New(), Config: models.Config{
+		Repository: "org/repo",
+		Filters:    map[string]interface{}{"issues": true, "state": "open"},
+	}}
+
+	deleted, err := service.SyncIssueItems(context.Background(), ds, []string{"#1", "#2", "#3", "README.md"})
+	if err != nil {
+		t.Fatalf("SyncIssueItems() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(deleted, []string{"#2", "#3"}) {
+		t.Errorf("Expected #2 and #3 deleted, got %v", deleted)
+	}
+	if docs := sink.ingested(); len(docs) != 1 || docs[0].Metadata.ExternalID != "#1" {
+		t.Errorf("Expected only #1 ingested, got %v", docs)
+	}
+}
+
+func TestNotionService_SyncPages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pages/p1":
+			w.Write([]byte(`{"id": "p1", "url": "https://notion.so/p1", "parent": {"type": "database_id", "database_id": "db-1"}, "properties": {}}`))
+		case "/blocks/p1/children":
+			w.Write([]byte(`{"results": [], "has_more": false}`))
+		case "/pages/p2":
+			w.Write([]byte(`{"id": "p2", "in_trash": true, "parent": {"type": "database_id", "database_id": "db-1"}}`))
+		case "/pages/p3":
+			w.Write([]byte(`{"id": "p3", "parent": {"type": "database_id", "database_id": "db-2"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"object": "error", "status": 404}`))
+		}
+	}))
+	defer server.Close()
+
+	sink := newMockSink()
+	service := NewNotionService("test-token", sink)
+	service.baseURL = server.URL
+
+	ds := &models.DataSource{ID: uuid.New(), Config: models.Config{DatabaseID: "db1"}}
+
+	deleted, err := service.SyncPages(context.Background(), ds, []string{"p1", "p2", "p3", "p4"})
+	if err != nil {
+		t.Fatalf("SyncPages() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(deleted, []string{"p2", "p3", "p4"}) {
+		t.Errorf("Expected p2, p3 and p4 deleted, got %v", deleted)
+	}
+	if docs := sink.ingested(); len(docs) != 1 || docs[0].Metadata.ExternalID != "p1" {
+		t.Errorf("Expected only p1 ingested, got %v", docs)
+	}
+}
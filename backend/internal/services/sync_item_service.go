@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/Abraham12611/veritas/config"
+	"github.com/Abraham12611/veritas/internal/logger"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+const (
+	syncItemBatchSize   = 100              // Items claimed at once
+	syncItemMaxAttempts = 5                // Attempts before an item is dropped
+	syncItemRetryDelay  = 30 * time.Second // Delay before the first retry
+)
+
+// SyncItemService is the durable queue of single items waiting to be
+// re-ingested or deleted. Items are queued by webhooks, so changes show up
+// without waiting for the next sync.
+type SyncItemService struct{}
+
+// NewSyncItemService creates a new sync item service
+func NewSyncItemService() *SyncItemService {
+	return &SyncItemService{}
+}
+
+// EnqueueSyncItems queues the changed and deleted items of a data source. An
+// item that is already queued is updated and made ready to run.
+func (s *SyncItemService) EnqueueSyncItems(ctx context.Context, dataSourceID uuid.UUID, changed, deleted []string) error {
+	// An item may only appear once per insert, and the latest state wins
+	state := make(map[string]bool)
+	var externalIDs []string
+	add := func(id string, isDeleted bool) {
+		if _, ok := state[id]; !ok {
+			externalIDs = append(externalIDs, id)
+		}
+		state[id] = isDeleted
+	}
+	for _, id := range changed {
+		add(id, false)
+	}
+	for _, id := range deleted {
+		add(id, true)
+	}
+	if len(externalIDs) == 0 {
+		return nil
+	}
+
+	flags := make([]bool, len(externalIDs))
+	for i, id := range externalIDs {
+		flags[i] = state[id]
+	}
+
+	query := `
+		INSERT INTO sync_items (data_source_id, external_id, deleted, run_after)
+		SELECT $1, external_id, deleted, $4
+		FROM unnest($2::text[], $3::boolean[]) AS t(external_id, deleted)
+		ON CONFLICT (data_source_id, external_id)
+		DO UPDATE SET deleted = EXCLUDED.deleted, attempts = 0, run_after = EXCLUDED.run_after
+	`
+
+	_, err := config.DB.Exec(ctx, query, dataSourceID, externalIDs, flags, time.Now())
+	return err
+}
+
+// ClaimSyncItems removes up to limit ready items of a single data source from
+// the queue and returns them. Items that fail must be put back with
+// RetrySyncItems. Items claimed by a process that dies are lost; the next
+// sync of the data source catches up on them.
+func (s *SyncItemService) ClaimSyncItems(ctx context.Context, limit int) ([]models.SyncItem, error) {
+	query := `
+		DELETE FROM sync_items
+		WHERE (data_source_id, external_id) IN (
+			SELECT data_source_id, external_id
+			FROM sync_items
+			WHERE run_after <= $1 AND data_source_id = (
+				SELECT data_source_id
+				FROM sync_items
+				WHERE run_after <= $1
+				ORDER BY run_after
+				LIMIT 1
+			)
+			ORDER BY run_after
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING data_source_id, external_id, deleted, attempts
+	`
+
+	rows, err := config.DB.Query(ctx, query, time.Now(), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.SyncItem, error) {
+		var item models.SyncItem
+		err := row.Scan(&item.DataSourceID, &item.ExternalID, &item.Deleted, &item.Attempts)
+		return item, err
+	})
+}
+
+// RetrySyncItems puts failed items back in the queue with backoff. Items that
+// have used up their attempts are dropped, and items queued again since they
+// were claimed keep their newer state.
+func (s *SyncItemService) RetrySyncItems(ctx context.Context, items []models.SyncItem) error {
+	query := `
+		INSERT INTO sync_items (data_source_id, external_id, deleted, attempts, run_after)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (data_source_id, external_id) DO NOTHING
+	`
+
+	for _, item := range items {
+		attempts := item.Attempts + 1
+		if attempts >= syncItemMaxAttempts {
+			logger.Warn("Dropping sync item after too many attempts", logger.Fields{
+				"dataSourceId": item.DataSourceID,
+				"externalId":   item.ExternalID,
+				"attempts":     attempts,
+			})
+			continue
+		}
+
+		delay := syncItemRetryDelay << (attempts - 1)
+		_, err := config.DB.Exec(ctx, query, item.DataSourceID, item.ExternalID, item.Deleted, attempts, time.Now().Add(delay))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
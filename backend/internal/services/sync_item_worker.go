@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Abraham12611/veritas/internal/logger"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// syncItemQueue is the part of SyncItemService used by the worker
+type syncItemQueue interface {
+	ClaimSyncItems(ctx context.Context, limit int) ([]models.SyncItem, error)
+}
+
+// syncItemRunner is the part of DataSourceService used by the worker
+type syncItemRunner interface {
+	RunSyncItems(ctx context.Context, dataSourceID uuid.UUID, items []models.SyncItem) error
+}
+
+// SyncItemWorker re-ingests and deletes the single items queued by webhooks.
+// It runs beside the sync workers, so a long sync doesn't hold up changes.
+type SyncItemWorker struct {
+	queue        syncItemQueue
+	runner       syncItemRunner
+	batchSize    int
+	pollInterval time.Duration
+}
+
+// NewSyncItemWorker creates a sync item worker
+func NewSyncItemWorker(service *DataSourceService) *SyncItemWorker {
+	return &SyncItemWorker{
+		queue:        service.syncItemService,
+		runner:       service,
+		batchSize:    syncItemBatchSize,
+		pollInterval: syncQueuePollInterval,
+	}
+}
+
+// Run processes queued items until ctx is cancelled
+func (w *SyncItemWorker) Run(ctx context.Context) {
+	logger.Info("Starting sync item worker")
+
+	for ctx.Err() == nil {
+		items, err := w.queue.ClaimSyncItems(ctx, w.batchSize)
+		if err != nil && ctx.Err() == nil {
+			logger.Error("Failed to claim sync items", err)
+		}
+		if len(items) == 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(w.pollInterval):
+			}
+			continue
+		}
+
+		// Items of a batch all belong to the same data source
+		dataSourceID := items[0].DataSourceID
+		if err := w.runner.RunSyncItems(ctx, dataSourceID, items); err != nil {
+			logger.Error("Failed to sync items", err, logger.Fields{
+				"dataSourceId": dataSourceID,
+				"items":        len(items),
+			})
+		}
+	}
+
+	logger.Info("Sync item worker stopped")
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// mockItemQueue implements syncItemQueue and syncItemRunner for testing
+type mockItemQueue struct {
+	mu      sync.Mutex
+	batches [][]models.SyncItem
+	limits  []int
+	ran     map[uuid.UUID][]string
+}
+
+func (m *mockItemQueue) ClaimSyncItems(ctx context.Context, limit int) ([]models.SyncItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.limits = append(m.limits, limit)
+	if len(m.batches) == 0 {
+		return nil, nil
+	}
+	batch := m.batches[0]
+	m.batches = m.batches[1:]
+	return batch, nil
+}
+
+func (m *mockItemQueue) RunSyncItems(ctx context.Context, dataSourceID uuid.UUID, items []models.SyncItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, item := range items {
+		m.ran[dataSourceID] = append(m.ran[dataSourceID], item.ExternalID)
+	}
+	return nil
+}
+
+func TestSyncItemWorker_Run(t *testing.T) {
+	first, second := uuid.New(), uuid.New()
+	queue := &mockItemQueue{
+		batches: [][]models.SyncItem{
+			{{DataSourceID: first, ExternalID: "#1"}, {DataSourceID: first, ExternalID: "#2", Deleted: true}},
+			{{DataSourceID: second, ExternalID: "page"}},
+		},
+		ran: make(map[uuid.UUID][]string),
+	}
+
+	worker := &SyncItemWorker{
+		queue:        queue,
+		runner:       queue,
+		batchSize:    10,
+		pollInterval: 10 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		worker.Run(ctx)
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Worker did not stop after cancellation")
+	}
+
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	if got := queue.ran[first]; len(got) != 2 || got[0] != "#1" || got[1] != "#2" {
+		t.Errorf("Expected both items of the first data source run together, got %v", got)
+	}
+	if got := queue.ran[second]; len(got) != 1 || got[0] != "page" {
+		t.Errorf("Expected the second data source's item run, got %v", got)
+	}
+	if queue.limits[0] != 10 {
+		t.Errorf("Expected batches of 10, got %d", queue.limits[0])
+	}
+}
@@ -0,0 +1,428 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/Abraham12611/veritas/config"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+const (
+	defaultSyncJobLimit = 50 // Sync jobs returned when listing history
+
+	// syncJobVisibilityTimeout is how long a claimed job stays invisible to
+	// other workers without a heartbeat. A job whose worker died becomes
+	// claimable again once it expires.
+	syncJobVisibilityTimeout = 2 * time.Minute
+
+	syncRetryBaseDelay = time.Minute // Delay before the first retry
+	syncRetryMaxDelay  = time.Hour   // Upper bound on the retry delay
+)
+
+// syncJobColumns are the columns scanned by scanSyncJob
+const syncJobColumns = `
+	id, data_source_id, trigger, status,
+	documents_added, documents_updated, documents_unchanged, documents_deleted, documents_failed,
+	COALESCE(error, ''), attempts, max_attempts, run_after,
+	created_at, started_at, finished_at, updated_at, cancel_requested_at
+`
+
+// ErrNoActiveSync is returned when cancelling a data source that has no queued
+// or running sync
+var ErrNoActiveSync = errors.New("no sync in progress")
+
+// ErrSyncJobLost is returned when updating a job attempt that is no longer
+// running, because its visibility timeout expired and another worker claimed
+// the job again
+var ErrSyncJobLost = errors.New("sync job was claimed by another worker")
+
+// SyncJobService records the history and progress of data source syncs and
+// acts as the durable queue the sync workers pull from
+type SyncJobService struct{}
+
+// NewSyncJobService creates a new sync job service
+func NewSyncJobService() *SyncJobService {
+	return &SyncJobService{}
+}
+
+// ListSyncJobs returns the most recent sync jobs of a data source, newest first
+func (s *SyncJobService) ListSyncJobs(ctx context.Context, dataSourceID uuid.UUID, limit int) ([]models.SyncJob, error) {
+	if limit <= 0 {
+		limit = defaultSyncJobLimit
+	}
+
+	query := `
+		SELECT ` + syncJobColumns + `
+		FROM sync_jobs
+		WHERE data_source_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`
+
+	rows, err := config.DB.Query(ctx, query, dataSourceID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return collectSyncJobs(rows)
+}
+
+// ListActiveSyncJobs returns the queued and running jobs across all data
+// sources, in the order the workers will pick them up
+func (s *SyncJobService) ListActiveSyncJobs(ctx context.Context, limit int) ([]models.SyncJob, error) {
+	if limit <= 0 {
+		limit = defaultSyncJobLimit
+	}
+
+	query := `
+		SELECT ` + syncJobColumns + `
+		FROM sync_jobs
+		WHERE status IN ($1, $2)
+		ORDER BY status DESC, run_after ASC
+		LIMIT $3
+	`
+
+	rows, err := config.DB.Query(ctx, query, models.SyncJobRunning, models.SyncJobQueued, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return collectSyncJobs(rows)
+}
+
+// GetSyncJob returns a specific sync job
+func (s *SyncJobService) GetSyncJob(ctx context.Context, id uuid.UUID) (*models.SyncJob, error) {
+	query := `
+		SELECT ` + syncJobColumns + `
+		FROM sync_jobs
+		WHERE id = $1
+	`
+
+	var job models.SyncJob
+	if err := scanSyncJob(config.DB.QueryRow(ctx, query, id), &job); err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// EnqueueSyncJob queues a sync of a data source. If the data source already
+// has a queued or running job, that job is returned instead, so a source is
+// never synced twice at once.
+func (s *SyncJobService) EnqueueSyncJob(ctx context.Context, dataSourceID uuid.UUID, trigger string) (*models.SyncJob, error) {
+	insert := `
+		INSERT INTO sync_jobs (id, data_source_id, trigger, status, run_after, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5, $5)
+		ON CONFLICT (data_source_id) WHERE status IN ('queued', 'running') DO NOTHING
+		RETURNING ` + syncJobColumns
+
+	existing := `
+		SELECT ` + syncJobColumns + `
+		FROM sync_jobs
+		WHERE data_source_id = $1 AND status IN ('queued', 'running')
+	`
+
+	// The active job can finish between the insert and the lookup, so try twice
+	for attempt := 0; attempt < 2; attempt++ {
+		var job models.SyncJob
+		err := scanSyncJob(config.DB.QueryRow(ctx, insert,
+			uuid.New(),
+			dataSourceID,
+			trigger,
+			models.SyncJobQueued,
+			time.Now(),
+		), &job)
+		if err == nil {
+			return &job, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+
+		err = scanSyncJob(config.DB.QueryRow(ctx, existing, dataSourceID), &job)
+		if err == nil {
+			return &job, nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+	}
+
+	return nil, errors.New("failed to enqueue sync job")
+}
+
+// ClaimSyncJob takes the next job that is ready to run and marks it running
+// for syncJobVisibilityTimeout. Running jobs whose visibility expired, because
+// their worker stopped heartbeating, are claimed again. It returns nil if no
+// job is ready.
+func (s *SyncJobService) ClaimSyncJob(ctx context.Context) (*models.SyncJob, error) {
+	query := `
+		UPDATE sync_jobs
+		SET status = $1, attempts = attempts + 1, started_at = COALESCE(started_at, $2),
+			locked_until = $3
+		WHERE id = (
+			SELECT id
+			FROM sync_jobs
+			WHERE (status = $4 AND run_after <= $2)
+			   OR (status = $1 AND locked_until < $2)
+			ORDER BY run_after
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + syncJobColumns
+
+	now := time.Now()
+
+	var job models.SyncJob
+	err := scanSyncJob(config.DB.QueryRow(ctx, query,
+		models.SyncJobRunning,
+		now,
+		now.Add(syncJobVisibilityTimeout),
+		models.SyncJobQueued,
+	), &job)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// UpdateSyncJobProgress records the document counts of a running sync and
+// extends its visibility timeout. It reports whether cancellation of the job
+// has been requested, or returns ErrSyncJobLost if the attempt was superseded.
+func (s *SyncJobService) UpdateSyncJobProgress(ctx context.Context, job *models.SyncJob, counts models.SyncJobCounts) (bool, error) {
+	query := `
+		UPDATE sync_jobs
+		SET documents_added = $1, documents_updated = $2, documents_unchanged = $3,
+			documents_deleted = $4, documents_failed = $5, locked_until = $6
+		WHERE id = $7 AND status = $8 AND attempts = $9
+		RETURNING cancel_requested_at IS NOT NULL
+	`
+
+	var cancelRequested bool
+	err := config.DB.QueryRow(ctx, query,
+		counts.Added,
+		counts.Updated,
+		counts.Unchanged,
+		counts.Deleted,
+		counts.Failed,
+		time.Now().Add(syncJobVisibilityTimeout),
+		job.ID,
+		models.SyncJobRunning,
+		job.Attempts,
+	).Scan(&cancelRequested)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, ErrSyncJobLost
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return cancelRequested, nil
+}
+
+// CancelSyncJob cancels the active sync job of a data source. A queued job is
+// cancelled immediately; a running job is flagged and stopped by its worker
+// on the next heartbeat. It returns ErrNoActiveSync if there is no active job.
+func (s *SyncJobService) CancelSyncJob(ctx context.Context, dataSourceID uuid.UUID) (*models.SyncJob, error) {
+	// SET expressions see the old row, so status still holds the prior value
+	query := `
+		UPDATE sync_jobs
+		SET status = CASE WHEN status = $2 THEN $3 ELSE status END,
+			finished_at = CASE WHEN status = $2 THEN $4 ELSE finished_at END,
+			cancel_requested_at = COALESCE(cancel_requested_at, $4)
+		WHERE data_source_id = $1 AND status IN ($2, $5)
+		RETURNING ` + syncJobColumns
+
+	var job models.SyncJob
+	err := scanSyncJob(config.DB.QueryRow(ctx, query,
+		dataSourceID,
+		models.SyncJobQueued,
+		models.SyncJobCancelled,
+		time.Now(),
+		models.SyncJobRunning,
+	), &job)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNoActiveSync
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// FinishCancelledSyncJob records that a running job stopped after a
+// cancellation request. It returns ErrSyncJobLost if the attempt was
+// superseded.
+func (s *SyncJobService) FinishCancelledSyncJob(ctx context.Context, job *models.SyncJob, counts models.SyncJobCounts) error {
+	query := `
+		UPDATE sync_jobs
+		SET status = $1, finished_at = $2, locked_until = NULL,
+			documents_added = $3, documents_updated = $4, documents_unchanged = $5,
+			documents_deleted = $6, documents_failed = $7
+		WHERE id = $8 AND status = $9 AND attempts = $10
+	`
+
+	result, err := config.DB.Exec(ctx, query,
+		models.SyncJobCancelled,
+		time.Now(),
+		counts.Added,
+		counts.Updated,
+		counts.Unchanged,
+		counts.Deleted,
+		counts.Failed,
+		job.ID,
+		models.SyncJobRunning,
+		job.Attempts,
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrSyncJobLost
+	}
+
+	return nil
+}
+
+// FinishSyncJob records the outcome of an attempt and returns the job's new
+// status. A nil syncErr marks the job as succeeded. A failed attempt is
+// queued again with backoff unless it was the last one or syncErr is a
+// *backoff.PermanentError. It returns ErrSyncJobLost if the attempt was
+// superseded.
+func (s *SyncJobService) FinishSyncJob(ctx context.Context, job *models.SyncJob, counts models.SyncJobCounts, syncErr error) (string, error) {
+	now := time.Now()
+	status := models.SyncJobSucceeded
+	runAfter := job.RunAfter
+	finishedAt := &now
+	var errMsg *string
+
+	if syncErr != nil {
+		msg := syncErr.Error()
+		errMsg = &msg
+
+		var permanent *backoff.PermanentError
+		if job.Attempts < job.MaxAttempts && !errors.As(syncErr, &permanent) {
+			status = models.SyncJobQueued
+			runAfter = now.Add(syncRetryDelay(job.Attempts))
+			finishedAt = nil
+		} else {
+			status = models.SyncJobFailed
+		}
+	}
+
+	query := `
+		UPDATE sync_jobs
+		SET status = $1, error = $2, run_after = $3, finished_at = $4, locked_until = NULL,
+			documents_added = $5, documents_updated = $6, documents_unchanged = $7,
+			documents_deleted = $8, documents_failed = $9
+		WHERE id = $10 AND status = $11 AND attempts = $12
+	`
+
+	result, err := config.DB.Exec(ctx, query,
+		status,
+		errMsg,
+		runAfter,
+		finishedAt,
+		counts.Added,
+		counts.Updated,
+		counts.Unchanged,
+		counts.Deleted,
+		counts.Failed,
+		job.ID,
+		models.SyncJobRunning,
+		job.Attempts,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	if result.RowsAffected() == 0 {
+		return "", ErrSyncJobLost
+	}
+
+	return status, nil
+}
+
+// RequeueSyncJob puts a job that was interrupted, e.g. by a shutdown, back in
+// the queue without counting the attempt. An attempt that was superseded is
+// left alone.
+func (s *SyncJobService) RequeueSyncJob(ctx context.Context, job *models.SyncJob) error {
+	query := `
+		UPDATE sync_jobs
+		SET status = $1, attempts = GREATEST(attempts - 1, 0), run_after = $2, locked_until = NULL
+		WHERE id = $3 AND status = $4 AND attempts = $5
+	`
+
+	_, err := config.DB.Exec(ctx, query, models.SyncJobQueued, time.Now(), job.ID, models.SyncJobRunning, job.Attempts)
+	return err
+}
+
+// syncRetryDelay returns how long to wait before retrying a job that failed
+// its attempt'th attempt. The delay doubles with each attempt.
+func syncRetryDelay(attempt int) time.Duration {
+	delay := syncRetryBaseDelay
+	for i := 1; i < attempt && delay < syncRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > syncRetryMaxDelay {
+		delay = syncRetryMaxDelay
+	}
+	return delay
+}
+
+// rowScanner is satisfied by pgx.Row and pgx.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// collectSyncJobs scans and closes rows selected with syncJobColumns
+func collectSyncJobs(rows pgx.Rows) ([]models.SyncJob, error) {
+	defer rows.Close()
+
+	jobs := []models.SyncJob{}
+	for rows.Next() {
+		var job models.SyncJob
+		if err := scanSyncJob(rows, &job); err != nil {
+			return nil, fmt.Errorf("failed to scan sync job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// scanSyncJob scans a row selected with syncJobColumns
+func scanSyncJob(row rowScanner, job *models.SyncJob) error {
+	return row.Scan(
+		&job.ID,
+		&job.DataSourceID,
+		&job.Trigger,
+		&job.Status,
+		&job.Added,
+		&job.Updated,
+		&job.Unchanged,
+		&job.Deleted,
+		&job.Failed,
+		&job.Error,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.RunAfter,
+		&job.CreatedAt,
+		&job.StartedAt,
+		&job.FinishedAt,
+		&job.UpdatedAt,
+		&job.CancelRequestedAt,
+	)
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	gosync "sync"
+
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+const (
+	defaultPreviewLimit = 20 // Documents listed in a sync preview
+	maxPreviewLimit     = 100
+)
+
+// previewSink is the DocumentSink for a sync preview. It summarises the
+// documents a connector produces instead of ingesting them.
+type previewSink struct {
+	ingestion *IngestionService
+	limit     int
+	stop      context.CancelFunc
+
+	mu      gosync.Mutex
+	preview models.SyncPreview
+	stopped bool
+}
+
+// newPreviewSink creates a sink that lists up to limit documents. If stop is
+// set, it's called once a document past the limit arrives, to end the sync
+// early; otherwise every document is counted in the totals.
+func newPreviewSink(ingestion *IngestionService, limit int, stop context.CancelFunc) *previewSink {
+	if limit <= 0 {
+		limit = defaultPreviewLimit
+	}
+	if limit > maxPreviewLimit {
+		limit = maxPreviewLimit
+	}
+
+	return &previewSink{
+		ingestion: ingestion,
+		limit:     limit,
+		stop:      stop,
+		preview: models.SyncPreview{
+			Documents:    []models.PreviewDocument{},
+			TotalsByType: make(map[string]int),
+		},
+	}
+}
+
+// IngestDocument records the document in the preview. The returned document
+// is never stored.
+func (p *previewSink) IngestDocument(ctx context.Context, input models.CreateDocumentInput) (*models.Document, error) {
+	if p.stop != nil && p.full() {
+		p.mu.Lock()
+		p.stopped = true
+		p.mu.Unlock()
+		p.stop()
+		return nil, context.Canceled
+	}
+
+	// Chunk the same way ingestion would, without embedding
+	chunks := len(p.ingestion.splitIntoChunks(input.Content))
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.preview.Total++
+	p.preview.TotalsByType[input.Type]++
+	p.preview.TotalSize += len(input.Content)
+	p.preview.TotalChunks += chunks
+
+	if len(p.preview.Documents) < p.limit {
+		p.preview.Documents = append(p.preview.Documents, models.PreviewDocument{
+			Title:      input.Title,
+			URL:        input.URL,
+			Type:       input.Type,
+			ExternalID: input.Metadata.ExternalID,
+			Size:       len(input.Content),
+			Chunks:     chunks,
+		})
+	}
+
+	return &models.Document{
+		InstanceID:   input.InstanceID,
+		DataSourceID: input.DataSourceID,
+		Title:        input.Title,
+		Content:      input.Content,
+		URL:          input.URL,
+		Type:         input.Type,
+		Metadata:     input.Metadata,
+	}, nil
+}
+
+// full reports whether the preview lists as many documents as it can
+func (p *previewSink) full() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.preview.Documents) >= p.limit
+}
+
+// DryRun reports that the preview must not change the source
+func (p *previewSink) DryRun() bool {
+	return true
+}
+
+// KeepDocument does nothing. Previews run without a checkpoint, so every
+// document is produced.
+func (p *previewSink) KeepDocument(externalID string) {}
+
+// result returns the preview collected so far
+func (p *previewSink) result() *models.SyncPreview {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	preview := p.preview
+	return &preview
+}
+
+// hasStopped reports whether the sink ended the sync at the limit
+func (p *previewSink) hasStopped() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stopped
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+func TestPreviewSink(t *testing.T) {
+	sink := newPreviewSink(&IngestionService{}, 2, nil)
+
+	inputs := []models.CreateDocumentInput{
+		{Title: "README.md", Type: "markdown", Content: "Short", Metadata: models.Metadata{ExternalID: "README.md"}},
+		{Title: "guide.md", Type: "markdown", Content: strings.Repeat("A long sentence. ", 200), Metadata: models.Metadata{ExternalID: "guide.md"}},
+		{Title: "notes.txt", Type: "text", Content: "Notes", Metadata: models.Metadata{ExternalID: "notes.txt"}},
+	}
+	for _, input := range inputs {
+		if _, err := sink.IngestDocument(context.Background(), input); err != nil {
+			t.Fatalf("IngestDocument() error = %v", err)
+		}
+	}
+
+	preview := sink.result()
+
+	if len(preview.Documents) != 2 {
+		t.Fatalf("Expected 2 listed documents, got %d", len(preview.Documents))
+	}
+	if preview.Documents[0].ExternalID != "README.md" || preview.Documents[0].Chunks != 1 {
+		t.Errorf("Unexpected first document: %+v", preview.Documents[0])
+	}
+	if preview.Documents[1].Chunks < 2 {
+		t.Errorf("Expected long document to be split into chunks, got %d", preview.Documents[1].Chunks)
+	}
+
+	if preview.Total != 3 {
+		t.Errorf("Expected 3 documents in total, got %d", preview.Total)
+	}
+	if preview.TotalsByType["markdown"] != 2 || preview.TotalsByType["text"] != 1 {
+		t.Errorf("Unexpected totals by type: %v", preview.TotalsByType)
+	}
+	if want := len("Short") + len(inputs[1].Content) + len("Notes"); preview.TotalSize != want {
+		t.Errorf("Expected total size %d, got %d", want, preview.TotalSize)
+	}
+	if preview.TotalChunks != preview.Documents[1].Chunks+2 {
+		t.Errorf("Unexpected total chunks %d", preview.TotalChunks)
+	}
+}
+
+func TestPreviewSink_Stop(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sink := newPreviewSink(&IngestionService{}, 2, cancel)
+
+	for i, title := range []string{"a.md", "b.md", "c.md"} {
+		_, err := sink.IngestDocument(ctx, models.CreateDocumentInput{Title: title, Type: "markdown", Content: "Text"})
+		if i < 2 && err != nil {
+			t.Fatalf("IngestDocument() error = %v", err)
+		}
+		if i == 2 && err == nil {
+			t.Error("Expected the document past the limit to be refused")
+		}
+	}
+
+	// The sync is stopped at the first document past the limit
+	if ctx.Err() == nil || !sink.hasStopped() {
+		t.Error("Expected the sync to be stopped")
+	}
+	if preview := sink.result(); len(preview.Documents) != 2 || preview.Total != 2 {
+		t.Errorf("Expected 2 documents, got %d listed of %d", len(preview.Documents), preview.Total)
+	}
+	if !sink.DryRun() {
+		t.Error("Expected the preview to be a dry run")
+	}
+}
+
+func TestPreviewSink_Limit(t *testing.T) {
+	if got := newPreviewSink(&IngestionService{}, 0, nil).limit; got != defaultPreviewLimit {
+		t.Errorf("Expected default limit %d, got %d", defaultPreviewLimit, got)
+	}
+	if got := newPreviewSink(&IngestionService{}, 1000, nil).limit; got != maxPreviewLimit {
+		t.Errorf("Expected limit capped at %d, got %d", maxPreviewLimit, got)
+	}
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	gosync "sync"
+
+	"github.com/google/uuid"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// syncRun is the DocumentSink for a single sync of a data source. It records
+// the external IDs the connector produced so that documents which no longer
+// exist in the source can be removed once the sync has completed, and counts
+// what happened to each document for the sync job.
+type syncRun struct {
+	ingestion    *IngestionService
+	dataSourceID uuid.UUID
+
+	mu        gosync.Mutex
+	seen      map[string]struct{}
+	totals    models.SyncJobCounts
+	cancelled bool
+}
+
+// newSyncRun creates a sync run for a data source
+func newSyncRun(ingestion *IngestionService, dataSourceID uuid.UUID) *syncRun {
+	return &syncRun{
+		ingestion:    ingestion,
+		dataSourceID: dataSourceID,
+		seen:         make(map[string]struct{}),
+	}
+}
+
+// IngestDocument records the document as seen and ingests it
+func (r *syncRun) IngestDocument(ctx context.Context, input models.CreateDocumentInput) (*models.Document, error) {
+	// Mark the document before ingesting, so a document that still exists in
+	// the source but fails to re-ingest keeps its previous version
+	r.markSeen(input.Metadata.ExternalID)
+
+	doc, outcome, err := r.ingestion.Ingest(ctx, input)
+	r.record(outcome, err)
+	return doc, err
+}
+
+// KeepDocument records a document the connector found unchanged as seen
+func (r *syncRun) KeepDocument(externalID string) {
+	r.markSeen(externalID)
+	r.record(IngestUnchanged, nil)
+}
+
+// record counts the outcome of a single document
+func (r *syncRun) record(outcome IngestOutcome, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err != nil {
+		r.totals.Failed++
+		return
+	}
+
+	switch outcome {
+	case IngestCreated:
+		r.totals.Added++
+	case IngestUpdated:
+		r.totals.Updated++
+	case IngestUnchanged:
+		r.totals.Unchanged++
+	}
+}
+
+// counts returns the document counts so far
+func (r *syncRun) counts() models.SyncJobCounts {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.totals
+}
+
+// markCancelled records that the run was stopped by a cancellation request
+func (r *syncRun) markCancelled() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cancelled = true
+}
+
+// wasCancelled reports whether the run was stopped by a cancellation request
+func (r *syncRun) wasCancelled() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cancelled
+}
+
+// markSeen records an external ID as present in the source
+func (r *syncRun) markSeen(externalID string) {
+	if externalID == "" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seen[externalID] = struct{}{}
+}
+
+// seenIDs returns the external IDs seen so far
+func (r *syncRun) seenIDs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, 0, len(r.seen))
+	for id := range r.seen {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// deleteMissing soft deletes the documents that weren't seen during the run.
+// It must only be called after the connector finished without error.
+func (r *syncRun) deleteMissing(ctx context.Context) (int64, error) {
+	deleted, err := r.ingestion.DeleteMissingDocuments(ctx, r.dataSourceID, r.seenIDs())
+	if err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	r.totals.Deleted += int(deleted)
+	r.mu.Unlock()
+
+	return deleted, nil
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+func TestSyncRun_MarkSeen(t *testing.T) {
+	run := newSyncRun(NewIngestionService(&mockEmbedder{}), uuid.New())
+
+	// Mark concurrently, as connector workers do
+	var wg sync.WaitGroup
+	for _, id := range []string{"page1", "page2", "page1", ""} {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			run.markSeen(id)
+		}(id)
+	}
+	wg.Wait()
+
+	ids := run.seenIDs()
+	sort.Strings(ids)
+	if len(ids) != 2 || ids[0] != "page1" || ids[1] != "page2" {
+		t.Errorf("seenIDs() = %v, want [page1 page2]", ids)
+	}
+}
+
+func TestSyncRun_Record(t *testing.T) {
+	run := newSyncRun(NewIngestionService(&mockEmbedder{}), uuid.New())
+
+	run.record(IngestCreated, nil)
+	run.record(IngestCreated, nil)
+	run.record(IngestUpdated, nil)
+	run.record(IngestUnchanged, nil)
+	run.record("", errors.New("embedding failed"))
+
+	want := models.SyncJobCounts{Added: 2, Updated: 1, Unchanged: 1, Failed: 1}
+	if got := run.counts(); got != want {
+		t.Errorf("counts() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSyncRun_KeepDocument(t *testing.T) {
+	run := newSyncRun(NewIngestionService(&mockEmbedder{}), uuid.New())
+
+	run.KeepDocument("docs/README.md")
+
+	if ids := run.seenIDs(); len(ids) != 1 || ids[0] != "docs/README.md" {
+		t.Errorf("Expected kept document to be seen, got %v", ids)
+	}
+	if got := run.counts(); got != (models.SyncJobCounts{Unchanged: 1}) {
+		t.Errorf("counts() = %+v, want 1 unchanged", got)
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Abraham12611/veritas/internal/logger"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// syncFrequencies maps Config.SyncFrequency values to sync intervals
+var syncFrequencies = map[string]time.Duration{
+	"hourly": time.Hour,
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+// scheduledSyncer is the part of DataSourceService used by the scheduler
+type scheduledSyncer interface {
+	ListScheduledDataSources(ctx context.Context) ([]models.DataSource, error)
+	TriggerSync(ctx context.Context, id uuid.UUID, trigger string) (*models.SyncJob, error)
+}
+
+// SyncScheduler periodically queues syncs for the data sources that are due
+// according to their sync frequency. The syncs themselves are run by the
+// sync workers; the queue never holds two active jobs for the same source.
+type SyncScheduler struct {
+	syncer   scheduledSyncer
+	interval time.Duration
+}
+
+// NewSyncScheduler creates a scheduler that checks for due data sources every
+// interval
+func NewSyncScheduler(service *DataSourceService, interval time.Duration) *SyncScheduler {
+	return &SyncScheduler{
+		syncer:   service,
+		interval: interval,
+	}
+}
+
+// Run queues due syncs until ctx is cancelled
+func (s *SyncScheduler) Run(ctx context.Context) {
+	logger.Info("Starting sync scheduler", logger.Fields{
+		"interval": s.interval.String(),
+	})
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.enqueueDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			logger.Info("Sync scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// enqueueDue queues a sync for each due data source
+func (s *SyncScheduler) enqueueDue(ctx context.Context) {
+	dataSources, err := s.syncer.ListScheduledDataSources(ctx)
+	if err != nil {
+		logger.Error("Failed to list scheduled data sources", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range dataSources {
+		ds := &dataSources[i]
+		if !isSyncDue(ds, now) {
+			continue
+		}
+
+		job, err := s.syncer.TriggerSync(ctx, ds.ID, models.SyncTriggerScheduled)
+		if err != nil {
+			logger.Error("Failed to queue scheduled sync", err, logger.Fields{
+				"dataSourceId": ds.ID,
+			})
+			continue
+		}
+
+		logger.Info("Queued scheduled sync", logger.Fields{
+			"dataSourceId": ds.ID,
+			"jobId":        job.ID,
+			"frequency":    ds.Config.SyncFrequency,
+		})
+	}
+}
+
+// isSyncDue reports whether a data source should be synced at now. Sources
+// that have never synced are inactive and due immediately, including those
+// whose first sync was cancelled or couldn't be queued. Paused sources are
+// never due. Failed and
+// cancelled syncs count as attempts, which updated_at reflects, so they are
+// retried one interval later rather than on every tick.
+func isSyncDue(ds *models.DataSource, now time.Time) bool {
+	interval, ok := syncFrequencies[ds.Config.SyncFrequency]
+	if !ok {
+		return false
+	}
+
+	if ds.Status != "active" && ds.Status != "error" && ds.Status != "inactive" {
+		return false
+	}
+
+	var lastAttempt time.Time
+	if ds.LastSync != nil {
+		lastAttempt = *ds.LastSync
+	}
+	if ds.UpdatedAt.After(lastAttempt) {
+		lastAttempt = ds.UpdatedAt
+	}
+
+	return !now.Before(lastAttempt.Add(interval))
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// mockSyncer implements scheduledSyncer for testing
+type mockSyncer struct {
+	dataSources []models.DataSource
+	triggered   map[uuid.UUID]string
+}
+
+func (m *mockSyncer) ListScheduledDataSources(ctx context.Context) ([]models.DataSource, error) {
+	return m.dataSources, nil
+}
+
+func (m *mockSyncer) TriggerSync(ctx context.Context, id uuid.UUID, trigger string) (*models.SyncJob, error) {
+	m.triggered[id] = trigger
+	return &models.SyncJob{ID: uuid.New(), DataSourceID: id, Trigger: trigger, Status: models.SyncJobQueued}, nil
+}
+
+func TestIsSyncDue(t *testing.T) {
+	now := time.Now()
+	hourAgo := now.Add(-61 * time.Minute)
+	recently := now.Add(-10 * time.Minute)
+
+	tests := []struct {
+		name string
+		ds   models.DataSource
+		want bool
+	}{
+		{
+			name: "Never synced",
+			ds:   models.DataSource{Status: "active", Config: models.Config{SyncFrequency: "daily"}},
+			want: true,
+		},
+		{
+			name: "Hourly and overdue",
+			ds:   models.DataSource{Status: "active", LastSync: &hourAgo, Config: models.Config{SyncFrequency: "hourly"}},
+			want: true,
+		},
+		{
+			name: "Daily and recently synced",
+			ds:   models.DataSource{Status: "active", LastSync: &hourAgo, Config: models.Config{SyncFrequency: "daily"}},
+			want: false,
+		},
+		{
+			name: "No frequency",
+			ds:   models.DataSource{Status: "active"},
+			want: false,
+		},
+		{
+			name: "Unknown frequency",
+			ds:   models.DataSource{Status: "active", Config: models.Config{SyncFrequency: "monthly"}},
+			want: false,
+		},
+		{
+			name: "Paused",
+			ds:   models.DataSource{Status: "paused", LastSync: &hourAgo, Config: models.Config{SyncFrequency: "hourly"}},
+			want: false,
+		},
+		{
+			name: "Paused and never synced",
+			ds:   models.DataSource{Status: "paused", UpdatedAt: hourAgo, Config: models.Config{SyncFrequency: "hourly"}},
+			want: false,
+		},
+		{
+			name: "Inactive and never synced",
+			ds:   models.DataSource{Status: "inactive", UpdatedAt: hourAgo, Config: models.Config{SyncFrequency: "hourly"}},
+			want: true,
+		},
+		{
+			name: "Already syncing",
+			ds:   models.DataSource{Status: "syncing", Config: models.Config{SyncFrequency: "hourly"}},
+			want: false,
+		},
+		{
+			name: "Recently failed",
+			ds:   models.DataSource{Status: "error", LastSync: &hourAgo, UpdatedAt: recently, Config: models.Config{SyncFrequency: "hourly"}},
+			want: false,
+		},
+		{
+			name: "Recently cancelled",
+			ds:   models.DataSource{Status: "active", LastSync: &hourAgo, UpdatedAt: recently, Config: models.Config{SyncFrequency: "hourly"}},
+			want: false,
+		},
+		{
+			name: "Failed an interval ago",
+			ds:   models.DataSource{Status: "error", UpdatedAt: hourAgo, Config: models.Config{SyncFrequency: "hourly"}},
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isSyncDue(&tt.ds, now); got != tt.want {
+				t.Errorf("isSyncDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyncScheduler_EnqueueDue(t *testing.T) {
+	recently := time.Now().Add(-time.Minute)
+	due := models.DataSource{ID: uuid.New(), Status: "active", Config: models.Config{SyncFrequency: "hourly"}}
+	notDue := models.DataSource{ID: uuid.New(), Status: "active", LastSync: &recently, Config: models.Config{SyncFrequency: "hourly"}}
+
+	syncer := &mockSyncer{
+		dataSources: []models.DataSource{due, notDue},
+		triggered:   make(map[uuid.UUID]string),
+	}
+	scheduler := &SyncScheduler{syncer: syncer, interval: time.Minute}
+
+	scheduler.enqueueDue(context.Background())
+
+	if len(syncer.triggered) != 1 {
+		t.Fatalf("Expected 1 queued sync, got %d", len(syncer.triggered))
+	}
+	if trigger := syncer.triggered[due.ID]; trigger != models.SyncTriggerScheduled {
+		t.Errorf("Expected due data source queued as %q, got %q", models.SyncTriggerScheduled, trigger)
+	}
+}
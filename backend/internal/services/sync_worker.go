@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	gosync "sync"
+	"time"
+
+	"github.com/Abraham12611/veritas/internal/logger"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// syncQueuePollInterval is how long an idle worker waits before checking the
+// queue again
+const syncQueuePollInterval = 2 * time.Second
+
+// syncJobQueue is the part of SyncJobService used by the workers
+type syncJobQueue interface {
+	ClaimSyncJob(ctx context.Context) (*models.SyncJob, error)
+}
+
+// syncJobRunner is the part of DataSourceService used by the workers
+type syncJobRunner interface {
+	RunSyncJob(ctx context.Context, job *models.SyncJob) error
+}
+
+// SyncWorkerPool runs queued sync jobs with a fixed number of workers. Jobs
+// are claimed from Postgres, so several API processes can share the queue.
+type SyncWorkerPool struct {
+	queue        syncJobQueue
+	runner       syncJobRunner
+	concurrency  int
+	pollInterval time.Duration
+}
+
+// NewSyncWorkerPool creates a pool of concurrency sync workers
+func NewSyncWorkerPool(service *DataSourceService, concurrency int) *SyncWorkerPool {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &SyncWorkerPool{
+		queue:        service.syncJobService,
+		runner:       service,
+		concurrency:  concurrency,
+		pollInterval: syncQueuePollInterval,
+	}
+}
+
+// Run processes jobs until ctx is cancelled, then waits for the workers to
+// requeue the jobs they were running
+func (p *SyncWorkerPool) Run(ctx context.Context) {
+	logger.Info("Starting sync workers", logger.Fields{
+		"concurrency": p.concurrency,
+	})
+
+	var wg gosync.WaitGroup
+	for i := 0; i < p.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+	wg.Wait()
+
+	logger.Info("Sync workers stopped")
+}
+
+// work claims and runs jobs one at a time, polling while the queue is empty
+func (p *SyncWorkerPool) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := p.queue.ClaimSyncJob(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.Error("Failed to claim sync job", err)
+		}
+		if job == nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(p.pollInterval):
+			}
+			continue
+		}
+
+		// Failures are recorded on the job by RunSyncJob
+		if err := p.runner.RunSyncJob(ctx, job); err != nil {
+			logger.Error("Sync job failed", err, logger.Fields{
+				"jobId":        job.ID,
+				"dataSourceId": job.DataSourceID,
+			})
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/Abraham12611/veritas/internal/models"
+)
+
+// mockQueue implements syncJobQueue and syncJobRunner for testing
+type mockQueue struct {
+	mu   sync.Mutex
+	jobs []*models.SyncJob
+
+	running int32
+	peak    int32
+	ran     int32
+	release chan struct{}
+}
+
+func (m *mockQueue) ClaimSyncJob(ctx context.Context) (*models.SyncJob, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.jobs) == 0 {
+		return nil, nil
+	}
+	job := m.jobs[0]
+	m.jobs = m.jobs[1:]
+	return job, nil
+}
+
+func (m *mockQueue) RunSyncJob(ctx context.Context, job *models.SyncJob) error {
+	running := atomic.AddInt32(&m.running, 1)
+	defer atomic.AddInt32(&m.running, -1)
+	for {
+		peak := atomic.LoadInt32(&m.peak)
+		if running <= peak || atomic.CompareAndSwapInt32(&m.peak, peak, running) {
+			break
+		}
+	}
+
+	select {
+	case <-m.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	atomic.AddInt32(&m.ran, 1)
+	return nil
+}
+
+func TestSyncWorkerPool_Run(t *testing.T) {
+	queue := &mockQueue{release: make(chan struct{})}
+	for i := 0; i < 5; i++ {
+		queue.jobs = append(queue.jobs, &models.SyncJob{ID: uuid.New(), DataSourceID: uuid.New()})
+	}
+
+	pool := &SyncWorkerPool{
+		queue:        queue,
+		runner:       queue,
+		concurrency:  2,
+		pollInterval: 10 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(done)
+	}()
+
+	// Let the workers pick up jobs, then let every job finish
+	time.Sleep(50 * time.Millisecond)
+	close(queue.release)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Worker pool did not stop after cancellation")
+	}
+
+	if peak := atomic.LoadInt32(&queue.peak); peak != 2 {
+		t.Errorf("Expected 2 concurrent jobs, got %d", peak)
+	}
+	if ran := atomic.LoadInt32(&queue.ran); ran != 5 {
+		t.Errorf("Expected 5 jobs run, got %d", ran)
+	}
+}
+
+func TestSyncRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{10, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := syncRetryDelay(tt.attempt); got != tt.want {
+			t.Errorf("syncRetryDelay(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
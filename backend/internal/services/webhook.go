@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/Abraham12611/veritas/config"
+	"github.com/Abraham12611/veritas/internal/logger"
+	"github.com/Abraham12611/veritas/internal/models"
+	"github.com/Abraham12611/veritas/internal/services/sync"
+)
+
+// ErrInvalidSignature is returned for a webhook delivery that isn't signed
+// with the data source's webhook secret
+var ErrInvalidSignature = sync.ErrInvalidSignature
+
+// ErrWebhookNotSupported is returned for a webhook delivered to a data source
+// of another type, or of a type that can't receive webhooks
+var ErrWebhookNotSupported = errors.New("data source does not receive webhooks")
+
+// ReceiveWebhook verifies a webhook delivery for a data source of type dsType
+// and queues the changes it describes. It returns the reply to send back to
+// the source, or nil for an empty response.
+func (s *DataSourceService) ReceiveWebhook(ctx context.Context, dsType string, id uuid.UUID, req sync.WebhookRequest) (interface{}, error) {
+	ds, err := s.GetDataSource(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	receiver, ok := webhookReceiver(ds.Type)
+	if !ok || ds.Type != dsType {
+		return nil, ErrWebhookNotSupported
+	}
+
+	event, err := receiver.ParseWebhook(ds, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first delivery of some sources carries the secret for the rest.
+	// It's unsigned, so it's only stored as pending for an admin to read from
+	// the data source API and confirm by setting it as the webhook secret.
+	if event.VerificationToken != "" {
+		if err := s.setWebhookVerificationToken(ctx, ds.ID, event.VerificationToken); err != nil {
+			return nil, fmt.Errorf("failed to store webhook verification token: %w", err)
+		}
+		logger.Info("Received webhook verification token", logger.Fields{
+			"dataSourceId": ds.ID,
+		})
+	}
+
+	if event.Resync {
+		if _, err := s.syncJobService.EnqueueSyncJob(ctx, ds.ID, models.SyncTriggerWebhook); err != nil {
+			return nil, fmt.Errorf("failed to enqueue sync job: %w", err)
+		}
+	}
+
+	if err := s.syncItemService.EnqueueSyncItems(ctx, ds.ID, event.Changed, event.Deleted); err != nil {
+		return nil, fmt.Errorf("failed to enqueue sync items: %w", err)
+	}
+
+	return event.Reply, nil
+}
+
+// RunSyncItems re-ingests or deletes items of a data source claimed by the
+// sync item worker. Items that can't be synced are queued again.
+func (s *DataSourceService) RunSyncItems(ctx context.Context, dataSourceID uuid.UUID, items []models.SyncItem) error {
+	// Requeueing must happen even if ctx was cancelled
+	statusCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := s.runSyncItems(ctx, dataSourceID, items)
+	if err != nil {
+		if retryErr := s.syncItemService.RetrySyncItems(statusCtx, items); retryErr != nil {
+			logger.Error("Failed to requeue sync items", retryErr, logger.Fields{
+				"dataSourceId": dataSourceID,
+			})
+		}
+	}
+	return err
+}
+
+// runSyncItems does the work of RunSyncItems
+func (s *DataSourceService) runSyncItems(ctx context.Context, dataSourceID uuid.UUID, items []models.SyncItem) error {
+	ds, err := s.GetDataSource(ctx, dataSourceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Deleted since the items were queued
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get data source: %w", err)
+	}
+
+	receiver, ok := webhookReceiver(ds.Type)
+	if !ok {
+		return nil
+	}
+
+	var changed, deleted []string
+	for _, item := range items {
+		if item.Deleted {
+			deleted = append(deleted, item.ExternalID)
+		} else {
+			changed = append(changed, item.ExternalID)
+		}
+	}
+
+	run := newSyncRun(s.ingestionService, ds.ID)
+	if len(changed) > 0 {
+		gone, err := receiver.SyncItems(ctx, ds, run, changed)
+		if err != nil {
+			return fmt.Errorf("failed to sync items: %w", err)
+		}
+		deleted = append(deleted, gone...)
+	}
+
+	var deletedCount int64
+	if len(deleted) > 0 {
+		if deletedCount, err = s.ingestionService.DeleteDocuments(ctx, ds.ID, deleted); err != nil {
+			return fmt.Errorf("failed to delete documents: %w", err)
+		}
+	}
+
+	counts := run.counts()
+	logger.Info("Synced items", logger.Fields{
+		"dataSourceId": ds.ID,
+		"items":        len(items),
+		"added":        counts.Added,
+		"updated":      counts.Updated,
+		"unchanged":    counts.Unchanged,
+		"deleted":      deletedCount,
+		"failed":       counts.Failed,
+	})
+
+	return nil
+}
+
+// setWebhookVerificationToken stores a webhook verification token in the data
+// source's config without touching the rest of it, the webhook secret
+// included
+func (s *DataSourceService) setWebhookVerificationToken(ctx context.Context, id uuid.UUID, token string) error {
+	query := `
+		UPDATE data_sources
+		SET config = jsonb_set(config, '{webhook_verification_token}', to_jsonb($1::text))
+		WHERE id = $2 AND deleted_at IS NULL
+	`
+
+	_, err := config.DB.Exec(ctx, query, token, id)
+	return err
+}
+
+// webhookReceiver returns the connector of a data source type if it can
+// receive webhooks
+func webhookReceiver(dsType string) (sync.WebhookReceiver, bool) {
+	connector, ok := sync.Lookup(dsType)
+	if !ok {
+		return nil, false
+	}
+	receiver, ok := connector.(sync.WebhookReceiver)
+	return receiver, ok
+}
@@ -0,0 +1,7 @@
+-- Store a hash of each document's content so re-syncs can skip unchanged items
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS content_hash VARCHAR(64);
+
+-- Identify documents by their ID in the source system. Documents without an
+-- external ID have a NULL key and never conflict.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_documents_external_id
+    ON documents (data_source_id, (metadata->>'external_id'));
@@ -0,0 +1,23 @@
+-- Create sync_jobs table recording each sync of a data source
+CREATE TABLE IF NOT EXISTS sync_jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    data_source_id UUID NOT NULL REFERENCES data_sources(id),
+    trigger VARCHAR(50) NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'running',
+    documents_added INTEGER NOT NULL DEFAULT 0,
+    documents_updated INTEGER NOT NULL DEFAULT 0,
+    documents_unchanged INTEGER NOT NULL DEFAULT 0,
+    documents_deleted INTEGER NOT NULL DEFAULT 0,
+    documents_failed INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_jobs_data_source_id ON sync_jobs(data_source_id, started_at DESC);
+
+CREATE TRIGGER update_sync_jobs_updated_at
+    BEFORE UPDATE ON sync_jobs
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,24 @@
+-- Turn sync_jobs into a durable queue processed by the sync workers
+ALTER TABLE sync_jobs
+    ADD COLUMN IF NOT EXISTS attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS max_attempts INTEGER NOT NULL DEFAULT 3,
+    ADD COLUMN IF NOT EXISTS run_after TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP;
+
+UPDATE sync_jobs SET created_at = started_at;
+
+-- Jobs start out queued; started_at is set when a worker picks them up
+ALTER TABLE sync_jobs ALTER COLUMN status SET DEFAULT 'queued';
+ALTER TABLE sync_jobs ALTER COLUMN started_at DROP DEFAULT;
+
+-- At most one queued or running job per data source
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sync_jobs_active
+    ON sync_jobs(data_source_id) WHERE status IN ('queued', 'running');
+
+-- Workers look for jobs that are ready to run
+CREATE INDEX IF NOT EXISTS idx_sync_jobs_queue ON sync_jobs(status, run_after);
+
+-- History is listed by enqueue time
+DROP INDEX IF EXISTS idx_sync_jobs_data_source_id;
+CREATE INDEX IF NOT EXISTS idx_sync_jobs_data_source_id ON sync_jobs(data_source_id, created_at DESC);
@@ -0,0 +1,3 @@
+-- Record cancellation requests; the worker running the job picks them up
+-- on its next heartbeat
+ALTER TABLE sync_jobs ADD COLUMN IF NOT EXISTS cancel_requested_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,3 @@
+-- Connector state carried between syncs, e.g. where an incremental sync
+-- should resume
+ALTER TABLE data_sources ADD COLUMN IF NOT EXISTS sync_checkpoint JSONB;
@@ -0,0 +1,13 @@
+-- Single items queued for re-ingestion or deletion, e.g. by webhooks
+CREATE TABLE IF NOT EXISTS sync_items (
+    data_source_id UUID NOT NULL REFERENCES data_sources(id) ON DELETE CASCADE,
+    external_id TEXT NOT NULL,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    run_after TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (data_source_id, external_id)
+);
+
+-- Workers look for items that are ready to run
+CREATE INDEX IF NOT EXISTS idx_sync_items_run_after ON sync_items(run_after);
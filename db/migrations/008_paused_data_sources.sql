@@ -0,0 +1,4 @@
+-- Sources switched off by a user are now paused. Until now they shared the
+-- inactive status with sources that have never synced; those that have
+-- synced can only have been switched off.
+UPDATE data_sources SET status = 'paused' WHERE status = 'inactive' AND last_sync IS NOT NULL;